@@ -14,6 +14,11 @@ func init() {
 	rootCmd.AddCommand(debugCmd)
 	debugCmd.AddCommand(dev.DevStartCmd)
 	debugCmd.AddCommand(dev.DevEndCmd)
+	debugCmd.AddCommand(dev.DevRequestCmd)
+	debugCmd.AddCommand(dev.DevHandoffCmd)
+	debugCmd.AddCommand(dev.DevContinueCmd)
+	debugCmd.AddCommand(dev.DevRunCmd)
+	debugCmd.AddCommand(dev.DevSyncExecCmd)
 }
 
 var debugCmd = &cobra.Command{
@@ -0,0 +1,45 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/coloredoutput"
+)
+
+func init() {
+	DevContinueCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "", "k8s deployment which your developing service exists",
+	)
+	DevContinueCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+}
+
+var DevContinueCmd = &cobra.Command{
+	Use:   "continue [NAME]",
+	Short: "Let the pod in DevMode of init container proceed to its main containers",
+	Long:  `Let the pod in DevMode of init container proceed to its main containers`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(args[0], common.WorkloadName, common.ServiceType)
+		must(err)
+		if !nocalhostSvc.IsInDevMode() {
+			must(errors.New(fmt.Sprintf("Service %s is not in DevMode", nocalhostSvc.Name)))
+		}
+		must(nocalhostSvc.ContinueInitDevContainer())
+		coloredoutput.Success("Init container has exited, main containers are starting")
+	},
+}
@@ -11,11 +11,8 @@ import (
 	"github.com/spf13/cobra"
 	"nocalhost/cmd/nhctl/cmds/common"
 	"nocalhost/internal/nhctl/coloredoutput"
-	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/utils"
-	"nocalhost/pkg/nhctl/log"
-	"strconv"
 )
 
 func init() {
@@ -43,55 +40,22 @@ var DevEndCmd = &cobra.Command{
 }
 
 func EndDevMode(nocalhostSvc *controller.Controller) error {
-	if !nocalhostSvc.IsInReplaceDevMode() && !nocalhostSvc.IsInDuplicateDevMode() {
+	if !nocalhostSvc.IsInDevMode() {
 		return errors.New(fmt.Sprintf("Service %s is not in DevMode", common.WorkloadName))
 	}
 
-	var needToRecoverHPA bool
-	if !nocalhostSvc.IsInDuplicateDevMode() {
-		needToRecoverHPA = true
-	}
+	common.Must(endDevMode(nocalhostSvc))
+	return nil
+}
 
-	common.Must(nocalhostSvc.DevEnd(false))
-	utils.Should(nocalhostSvc.DecreaseDevModeCount())
+func endDevMode(nocalhostSvc *controller.Controller) error {
+	ephemeral := nocalhostSvc.DevModeType.IsEphemeralDevMode()
 
-	// Recover hpa
-	if needToRecoverHPA {
-		log.Info("Recovering HPA...")
-		hl, err := nocalhostSvc.ListHPA()
-		if err != nil {
-			log.WarnE(err, "Failed to find HPA")
-		}
-		if len(hl) == 0 {
-			log.Info("No HPA found")
-		}
-		for _, h := range hl {
-			if len(h.Annotations) == 0 {
-				continue
-			}
-			if max, ok := h.Annotations[_const.HPAOriginalMaxReplicasKey]; ok {
-				maxInt, err := strconv.ParseInt(max, 0, 0)
-				if err != nil {
-					log.WarnE(err, "")
-					continue
-				}
-				h.Spec.MaxReplicas = int32(maxInt)
-			}
-			if min, ok := h.Annotations[_const.HPAOriginalMinReplicasKey]; ok {
-				minInt, err := strconv.ParseInt(min, 0, 0)
-				if err != nil {
-					log.WarnE(err, "")
-					continue
-				}
-				minInt32 := int32(minInt)
-				h.Spec.MinReplicas = &minInt32
-			}
-			if _, err = nocalhostSvc.Client.UpdateHPA(&h); err != nil {
-				log.WarnE(err, fmt.Sprintf("Failed to update hpa %s", h.Name))
-			} else {
-				log.Infof("HPA %s has been recovered", h.Name)
-			}
-		}
+	if err := nocalhostSvc.DevEnd(false); err != nil {
+		return err
+	}
+	if !ephemeral {
+		utils.Should(nocalhostSvc.DecreaseDevModeCount())
 	}
 	coloredoutput.Success("DevMode has been ended")
 	return nil
@@ -0,0 +1,78 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/utils"
+)
+
+func init() {
+	DevHandoffCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "", "k8s deployment which your developing service exists",
+	)
+	DevHandoffCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+}
+
+var DevHandoffCmd = &cobra.Command{
+	Use:   "handoff [NAME]",
+	Short: "End DevMode and hand off the service to the first one in queue",
+	Long:  `End DevMode and hand off the service to the first one queued by 'nhctl dev request'`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		nocalhostApp, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(
+			args[0], common.WorkloadName, common.ServiceType,
+		)
+		must(err)
+		must(HandoffDevMode(nocalhostApp, nocalhostSvc))
+	},
+}
+
+// HandoffDevMode grants the service to the first requester before ending DevMode,
+// so that no one else can take it in between, requester's daemon starts DevMode on receiving DEV_END.
+// The grant is revoked if DevMode fails to be ended
+func HandoffDevMode(nocalhostApp *app.Application, nocalhostSvc *controller.Controller) error {
+	if !nocalhostSvc.AppMeta.SvcDevModePossessor(
+		nocalhostSvc.Name, nocalhostSvc.Type, nocalhostApp.Identifier, profile.ReplaceDevMode,
+	) {
+		return errors.New(fmt.Sprintf("%s is not in replace DevMode by yourself", nocalhostSvc.Name))
+	}
+
+	granted, err := nocalhostSvc.AppMeta.SvcDevHandoff(nocalhostSvc.Name, nocalhostSvc.Type)
+	if err != nil {
+		if errors.Is(err, appmeta.ErrNoDevRequest) {
+			return errors.New(fmt.Sprintf("No one is waiting for %s", nocalhostSvc.Name))
+		}
+		return err
+	}
+
+	if err = endDevMode(nocalhostSvc); err != nil {
+		// still in DevMode, take the grant back, or the requester holds a grant of a service in use
+		utils.ShouldI(
+			nocalhostSvc.AppMeta.SvcDevHandoffRevoke(nocalhostSvc.Name, nocalhostSvc.Type, granted.Identifier),
+			fmt.Sprintf("Failed to revoke the grant of %s", granted.Identifier),
+		)
+		return err
+	}
+	coloredoutput.Success(fmt.Sprintf("%s has been handed off to %s", nocalhostSvc.Name, granted.Identifier))
+	return nil
+}
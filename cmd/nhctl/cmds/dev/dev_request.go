@@ -0,0 +1,123 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/profile"
+	"path/filepath"
+	"time"
+)
+
+var (
+	devRequestOps    = &appmeta.DevRequest{}
+	devRequestCancel bool
+	devRequestList   bool
+)
+
+func init() {
+	DevRequestCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "", "k8s deployment which your developing service exists",
+	)
+	DevRequestCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+	DevRequestCmd.Flags().StringVarP(&devRequestOps.Container, "container", "c", "", "container to develop")
+	DevRequestCmd.Flags().StringVarP(&devRequestOps.LocalSync, "local-sync", "s", "", "local directory to sync")
+	DevRequestCmd.Flags().StringVarP(&devRequestOps.DevImage, "image", "i", "", "image of DevContainer")
+	DevRequestCmd.Flags().BoolVar(&devRequestCancel, "cancel", false, "leave the queue of the service")
+	DevRequestCmd.Flags().BoolVar(&devRequestList, "list", false, "list the queue of the service")
+}
+
+var DevRequestCmd = &cobra.Command{
+	Use:   "request [NAME]",
+	Short: "Queue for a service in replace DevMode by others",
+	Long: `Queue for a service in replace DevMode by others,
+DevMode will be started automatically by daemon once the holder hands it off by 'nhctl dev handoff'`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		nocalhostApp, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(
+			args[0], common.WorkloadName, common.ServiceType,
+		)
+		must(err)
+
+		switch {
+		case devRequestList:
+			listDevRequests(nocalhostSvc)
+		case devRequestCancel:
+			must(nocalhostSvc.AppMeta.SvcDevRequestCancel(nocalhostSvc.Name, nocalhostSvc.Type, nocalhostApp.Identifier))
+			coloredoutput.Success(fmt.Sprintf("Left the queue of %s", nocalhostSvc.Name))
+		default:
+			must(RequestDevMode(nocalhostApp, nocalhostSvc, devRequestOps))
+		}
+	},
+}
+
+func RequestDevMode(nocalhostApp *app.Application, nocalhostSvc *controller.Controller, request *appmeta.DevRequest) error {
+	if !nocalhostSvc.IsInReplaceDevMode() {
+		return errors.New(
+			fmt.Sprintf("%s is not in replace DevMode, start DevMode directly", nocalhostSvc.Name),
+		)
+	}
+	if nocalhostSvc.AppMeta.SvcDevModePossessor(
+		nocalhostSvc.Name, nocalhostSvc.Type, nocalhostApp.Identifier, profile.ReplaceDevMode,
+	) {
+		return errors.New(fmt.Sprintf("%s is already in DevMode by yourself", nocalhostSvc.Name))
+	}
+
+	if request.LocalSync != "" {
+		localSync, err := filepath.Abs(request.LocalSync)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		request.LocalSync = localSync
+	}
+	request.Identifier = nocalhostApp.Identifier
+
+	if err := nocalhostSvc.AppMeta.SvcDevRequest(nocalhostSvc.Name, nocalhostSvc.Type, request); err != nil {
+		return err
+	}
+
+	queue := nocalhostSvc.AppMeta.GetSvcDevRequests(nocalhostSvc.Name, nocalhostSvc.Type)
+	coloredoutput.Success(
+		fmt.Sprintf(
+			"Queued for %s, %d request(s) in queue, DevMode will be started once it is handed off to you",
+			nocalhostSvc.Name, len(queue),
+		),
+	)
+	return nil
+}
+
+func listDevRequests(nocalhostSvc *controller.Controller) {
+	queue := nocalhostSvc.AppMeta.GetSvcDevRequests(nocalhostSvc.Name, nocalhostSvc.Type)
+	if len(queue) == 0 {
+		coloredoutput.Hint(fmt.Sprintf("No one is waiting for %s", nocalhostSvc.Name))
+		return
+	}
+	for i, request := range queue {
+		state := "pending"
+		if request.Granted() {
+			state = "granted"
+		}
+		fmt.Printf(
+			"%d\t%s\t%s\t%s\n", i+1, request.Identifier, state,
+			time.Unix(request.RequestedAt, 0).Format(time.RFC3339),
+		)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var devRunOps = struct {
+	container string
+	debug     bool
+	watch     bool
+	debounce  time.Duration
+	patterns  []string
+	signal    string
+}{}
+
+func init() {
+	DevRunCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "", "k8s deployment which your developing service exists",
+	)
+	DevRunCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+	DevRunCmd.Flags().StringVarP(&devRunOps.container, "container", "c", "", "container to run the command")
+	DevRunCmd.Flags().BoolVar(&devRunOps.debug, "debug", false, "run the debug command instead of run command")
+	DevRunCmd.Flags().BoolVar(
+		&devRunOps.watch, "watch", false, "restart the command after changed files are synced to dev container",
+	)
+	DevRunCmd.Flags().DurationVar(
+		&devRunOps.debounce, "debounce", 0, "merge sync batches in the duration into one restart, default is 1s",
+	)
+	DevRunCmd.Flags().StringSliceVar(
+		&devRunOps.patterns, "pattern", nil, "only changes of files matching the patterns trigger restart",
+	)
+	DevRunCmd.Flags().StringVar(
+		&devRunOps.signal, "signal", "", "send the signal to the command instead of restarting it, such as HUP",
+	)
+}
+
+var DevRunCmd = &cobra.Command{
+	Use:   "run [NAME]",
+	Short: "Run the run command in dev container, and restart it after files synced",
+	Long: `Run the run command in dev container, with --watch it's restarted or signaled
+after each completed sync batch, without any IDE`,
+	Example: `  nhctl dev run bookinfo -d productpage --watch
+  nhctl dev run bookinfo -d productpage --watch --pattern '*.py' --pattern '!*_test.py'`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(args[0], common.WorkloadName, common.ServiceType)
+		must(err)
+		if !nocalhostSvc.IsInDevMode() {
+			must(errors.New(fmt.Sprintf("Service %s is not in DevMode", nocalhostSvc.Name)))
+		}
+
+		runner, err := nocalhostSvc.NewHotReloadRunner(devRunOps.container, devRunOps.debug, os.Stdout, os.Stderr)
+		must(err)
+		if devRunOps.debounce > 0 {
+			runner.Debounce = devRunOps.debounce
+		}
+		if len(devRunOps.patterns) > 0 {
+			runner.Patterns = devRunOps.patterns
+		}
+		if devRunOps.signal != "" {
+			runner.Signal = devRunOps.signal
+		}
+		runner.Log = func(format string, a ...interface{}) {
+			line := fmt.Sprintf(format, a...)
+			log.Infof("[hot-reload] %s", line)
+			if err := nocalhostSvc.AppendHotReloadLog(line); err != nil {
+				log.LogE(err)
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			cancel()
+		}()
+
+		if !devRunOps.watch {
+			must(runner.RunOnce(ctx))
+			return
+		}
+
+		events, err := nocalhostSvc.WatchSyncEvents(ctx)
+		must(err)
+		log.Infof("Restart log is written to %s", nocalhostSvc.HotReloadLogPath())
+		runner.Run(ctx, events)
+	},
+}
@@ -14,7 +14,6 @@ import (
 	"nocalhost/cmd/nhctl/cmds/common"
 	"nocalhost/internal/nhctl/app"
 	"nocalhost/internal/nhctl/coloredoutput"
-	"nocalhost/internal/nhctl/common/base"
 	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/dev_dir"
@@ -26,19 +25,27 @@ import (
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
 	utils2 "nocalhost/pkg/nhctl/utils"
-	"strconv"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
 var (
-	pod   string
-	shell string
+	pod          string
+	shell        string
+	devGroup     string
+	dryRun       bool
+	dryRunOutput string
+	ordinal      int
+	mirror       bool
 )
 
 type DevStartOps struct {
 	*model.DevStartOptions
 	NocalhostSvc *controller.Controller
 	NocalhostApp *app.Application
+
+	pfListBeforeDevStart []*profile.DevPortForward
 }
 
 var devStartOps = &model.DevStartOptions{}
@@ -90,12 +97,44 @@ func init() {
 	)
 	DevStartCmd.Flags().StringVarP(
 		&devStartOps.DevModeType, "dev-mode", "m", "",
-		"specify which DevMode you want to enter, such as: replace,duplicate. Default: replace",
+		"specify which DevMode you want to enter, such as: replace,duplicate,ephemeral,local. Default: replace. "+
+			"The sidecar of ephemeral DevMode runs as root with SYS_PTRACE, namespaces enforcing baseline or "+
+			"restricted PodSecurity reject it, use replace DevMode there",
 	)
 	DevStartCmd.Flags().StringToStringVar(
 		&devStartOps.MeshHeader, "header", map[string]string{},
 		"mesh header while use duplicate devMode, traffic which have those headers will route to current workload",
 	)
+	DevStartCmd.Flags().BoolVar(
+		&mirror, "mirror", false,
+		"duplicate DevMode only, a shadow copy of requests to the workload is sent to the duplicate workload, "+
+			"while responses still come from the workload",
+	)
+	DevStartCmd.Flags().IntVar(
+		&devStartOps.MirrorPercent, "mirror-percent", 100,
+		"percentage of requests mirrored while using --mirror, 1-100",
+	)
+	DevStartCmd.Flags().StringToStringVar(
+		&devStartOps.ExtraContainers, "extra-container", map[string]string{},
+		"extra containers enter DevMode along with --container, such as: sidecar=/local/sidecar/dir",
+	)
+	DevStartCmd.Flags().StringVar(
+		&devGroup, "group", "",
+		"start DevMode of all services declared in the dev group of application config, "+
+			"all of them will be rolled back if any fails",
+	)
+	DevStartCmd.Flags().BoolVar(
+		&dryRun, "dry-run", false,
+		"only print the objects which would be created or modified, without sending them to cluster",
+	)
+	DevStartCmd.Flags().StringVarP(
+		&dryRunOutput, "output", "o", "yaml",
+		"output format of --dry-run, json or yaml",
+	)
+	DevStartCmd.Flags().IntVar(
+		&ordinal, "ordinal", 0,
+		"only the pod of the ordinal of StatefulSet enters replace DevMode, other pods are untouched",
+	)
 }
 
 var DevStartCmd = &cobra.Command{
@@ -109,7 +148,26 @@ var DevStartCmd = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if dryRun {
+			// keep stdout clean for the rendered objects
+			log.RedirectionDefaultLogger(os.Stderr)
+		}
+		if cmd.Flags().Changed("ordinal") {
+			devStartOps.Ordinal = &ordinal
+		}
+		if !mirror {
+			devStartOps.MirrorPercent = 0
+		} else if devStartOps.MirrorPercent < 1 || devStartOps.MirrorPercent > 100 {
+			log.Fatalf("'mirror-percent' %d out of range 1-100", devStartOps.MirrorPercent)
+		}
 		d := DevStartOps{DevStartOptions: devStartOps}
+		if devGroup != "" {
+			if dryRun {
+				log.Fatal("'dry-run' is not supported by dev group")
+			}
+			must(d.StartDevGroup(args[0], devGroup))
+			return
+		}
 		must(d.StartDevMode(args[0]))
 	},
 }
@@ -117,16 +175,52 @@ var DevStartCmd = &cobra.Command{
 func (d *DevStartOps) StartDevMode(applicationName string) error {
 
 	dt := profile.DevModeType(d.DevModeType)
-	if !dt.IsDuplicateDevMode() && !dt.IsReplaceDevMode() {
+	if !dt.IsDuplicateDevMode() && !dt.IsReplaceDevMode() && !dt.IsEphemeralDevMode() && !dt.IsLocalDevMode() {
 		return errors.New(fmt.Sprintf("Unsupported DevModeType %s", dt))
 	}
 
+	if dt.IsLocalDevMode() && dryRun {
+		log.Fatal("'dry-run' is not supported by local DevMode")
+	}
+
+	if d.Ordinal != nil && !dt.IsReplaceDevMode() {
+		log.Fatal("'ordinal' is only supported by replace DevMode")
+	}
+
+	if d.MirrorPercent != 0 {
+		if !dt.IsDuplicateDevMode() {
+			log.Fatal("'mirror' is only supported by duplicate DevMode")
+		}
+		if len(d.MeshHeader) != 0 {
+			log.Fatal("'mirror' and 'header' can not be used at the same time")
+		}
+	}
+
 	if len(d.LocalSyncDir) > 1 {
 		log.Fatal("Can not define multi 'local-sync(-s)'")
 	} else if len(d.LocalSyncDir) == 0 {
 		log.Fatal("'local-sync(-s)' must be specified")
 	}
 
+	if len(d.ExtraContainers) > 0 {
+		if d.Container == "" {
+			log.Fatal("'container(-c)' must be specified while using 'extra-container'")
+		}
+		for container, dir := range d.ExtraContainers {
+			if container == d.Container {
+				log.Fatalf("Container %s can not be specified as an extra container", container)
+			}
+			if dir == "" {
+				log.Fatalf("Local sync dir of extra container %s must be specified", container)
+			}
+			absDir, err := filepath.Abs(dir)
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			d.ExtraContainers[container] = absDir
+		}
+	}
+
 	nocalhostApp, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(applicationName, common.WorkloadName, common.ServiceType)
 	if err != nil {
 		return err
@@ -138,6 +232,14 @@ func (d *DevStartOps) StartDevMode(applicationName string) error {
 		log.Fatal(nocalhostApp.GetAppMeta().NotInstallTips())
 	}
 
+	if dryRun {
+		return d.dryRunDevMode(dt)
+	}
+
+	if dt.IsLocalDevMode() {
+		return d.startLocalDevMode()
+	}
+
 	if d.NocalhostSvc.IsInDevMode() {
 		coloredoutput.Hint(fmt.Sprintf("Already in %s DevMode...", d.NocalhostSvc.DevModeType.ToString()))
 
@@ -162,7 +264,16 @@ func (d *DevStartOps) StartDevMode(applicationName string) error {
 	// not support enter replace and mesh mode at the same time
 	// because of if already in replace devMode, enter mesh mode will inject sidecar to origin workloads
 	// pods will recreate, effect other in replace
-	if len(d.MeshHeader) == 0 && d.NocalhostSvc.IsInReplaceDevMode() {
+	if len(d.MeshHeader) == 0 && d.MirrorPercent == 0 && d.NocalhostSvc.IsInReplaceDevMode() {
+		if dt.IsReplaceDevMode() {
+			coloredoutput.Fail(
+				fmt.Sprintf(
+					"%s is in replace DevMode by others, use 'nhctl dev request' to queue for it",
+					d.NocalhostSvc.Name,
+				),
+			)
+			return nil
+		}
 		coloredoutput.Fail("Not support enter replace and mesh devMode at the same time")
 		return nil
 	}
@@ -180,13 +291,7 @@ func (d *DevStartOps) StartDevMode(applicationName string) error {
 
 	coloredoutput.Hint(fmt.Sprintf("Starting %s DevMode...", dt.ToString()))
 
-	d.NocalhostSvc.DevModeType = dt
-	d.loadLocalOrCmConfigIfValid()
-	d.stopPreviousSyncthing()
-	d.recordLocalSyncDirToProfile()
-	d.prepareSyncThing()
-	d.stopPreviousPortForward()
-	if err := d.enterDevMode(dt); err != nil {
+	if err := d.prepareAndEnterDevMode(dt); err != nil {
 		log.FatalE(err, "")
 	}
 
@@ -207,36 +312,61 @@ func (d *DevStartOps) StartDevMode(applicationName string) error {
 	return nil
 }
 
-var pfListBeforeDevStart []*profile.DevPortForward
+// prepareAndEnterDevMode step 1) to 7) of starting DevMode
+func (d *DevStartOps) prepareAndEnterDevMode(dt profile.DevModeType) error {
+	d.NocalhostSvc.DevModeType = dt
+	if err := d.loadLocalOrCmConfigIfValid(); err != nil {
+		return err
+	}
+	if err := d.stopPreviousSyncthing(); err != nil {
+		return err
+	}
+	if err := d.recordLocalSyncDirToProfile(); err != nil {
+		return err
+	}
+	if err := d.prepareSyncThing(); err != nil {
+		return err
+	}
+	d.stopPreviousPortForward()
+	return d.enterDevMode(dt)
+}
 
 func (d *DevStartOps) stopPreviousPortForward() {
 	appProfile, _ := d.NocalhostApp.GetProfile()
-	pfListBeforeDevStart = appProfile.SvcProfileV2(common.WorkloadName, string(d.NocalhostSvc.Type)).DevPortForwardList
-	for _, pf := range pfListBeforeDevStart {
+	for _, pf := range appProfile.SvcProfileV2(d.NocalhostSvc.Name, string(d.NocalhostSvc.Type)).DevPortForwardList {
+		// port-forward to service follows its endpoints, no need to restart
+		if pf.TargetService != "" {
+			continue
+		}
+		d.pfListBeforeDevStart = append(d.pfListBeforeDevStart, pf)
+	}
+	for _, pf := range d.pfListBeforeDevStart {
 		log.Infof("Stopping %d:%d", pf.LocalPort, pf.RemotePort)
-		utils.Should(d.NocalhostSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort))
+		utils.Should(d.NocalhostSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort, pf.GetProtocol(), pf.Role))
 	}
 }
 
-func (d *DevStartOps) prepareSyncThing() {
+func (d *DevStartOps) prepareSyncThing() error {
+	if d.NocalhostSvc.GetSyncEngine(d.Container) == _const.ExecSyncEngine {
+		return nil
+	}
 	dt := profile.DevModeType(d.DevModeType)
-	must(d.NocalhostSvc.CreateSyncThingSecret(d.Container, d.LocalSyncDir, dt.IsDuplicateDevMode()))
+	return d.NocalhostSvc.CreateSyncThingSecret(d.Container, d.LocalSyncDir, dt.IsDuplicateDevMode())
 }
 
-func (d *DevStartOps) recordLocalSyncDirToProfile() {
-	must(
-		d.NocalhostSvc.UpdateSvcProfile(
-			func(svcProfile *profile.SvcProfileV2) error {
-				svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin = d.LocalSyncDir
-				return nil
-			},
-		),
+func (d *DevStartOps) recordLocalSyncDirToProfile() error {
+	return d.NocalhostSvc.UpdateSvcProfile(
+		func(svcProfile *profile.SvcProfileV2) error {
+			svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin = d.LocalSyncDir
+			svcProfile.ExtraDevContainers = d.ExtraContainers
+			return nil
+		},
 	)
 }
 
 // when re enter dev mode, nocalhost will check the associate dir
 // nocalhost will load svc config from associate dir if needed
-func (d *DevStartOps) loadLocalOrCmConfigIfValid() {
+func (d *DevStartOps) loadLocalOrCmConfigIfValid() error {
 
 	svcPack := dev_dir.NewSvcPack(
 		d.NocalhostSvc.NameSpace,
@@ -251,37 +381,43 @@ func (d *DevStartOps) loadLocalOrCmConfigIfValid() {
 	case 0:
 		associatePath := svcPack.GetAssociatePath()
 		if associatePath == "" {
-			must(errors.New("'local-sync(-s)' should specify while svc is not associate with local dir"))
+			return errors.New("'local-sync(-s)' should specify while svc is not associate with local dir")
 		}
 		d.LocalSyncDir = append(d.LocalSyncDir, string(associatePath))
 
-		must(associatePath.Associate(svcPack, common.KubeConfig, true))
+		if err := associatePath.Associate(svcPack, common.KubeConfig, true); err != nil {
+			return err
+		}
 
-		_ = d.NocalhostApp.ReloadSvcCfg(common.WorkloadName, base.SvcType(common.ServiceType), false, false)
+		_ = d.NocalhostApp.ReloadSvcCfg(d.NocalhostSvc.Name, d.NocalhostSvc.Type, false, false)
 	case 1:
 
-		must(dev_dir.DevPath(d.LocalSyncDir[0]).Associate(svcPack, common.KubeConfig, true))
+		if err := dev_dir.DevPath(d.LocalSyncDir[0]).Associate(svcPack, common.KubeConfig, true); err != nil {
+			return err
+		}
 
-		_ = d.NocalhostApp.ReloadSvcCfg(common.WorkloadName, base.SvcType(common.ServiceType), false, false)
+		_ = d.NocalhostApp.ReloadSvcCfg(d.NocalhostSvc.Name, d.NocalhostSvc.Type, false, false)
 	default:
-		log.Fatal(errors.New("Can not define multi 'local-sync(-s)'"))
+		return errors.New("Can not define multi 'local-sync(-s)'")
 	}
+	return nil
 }
 
 // we should clean previous Syncthing
 // prevent previous syncthing hold the db lock
-func (d *DevStartOps) stopPreviousSyncthing() {
+func (d *DevStartOps) stopPreviousSyncthing() error {
 	// Clean up previous syncthing
-	must(
-		d.NocalhostSvc.FindOutSyncthingProcess(
-			func(pid int) error {
-				return syncthing.Stop(pid, true)
-			},
-		),
-	)
+	if err := d.NocalhostSvc.FindOutSyncthingProcess(
+		func(pid int) error {
+			return syncthing.Stop(pid, true)
+		},
+	); err != nil {
+		return err
+	}
 	// kill syncthing process by find find it with terminal
 	str := strings.ReplaceAll(d.NocalhostSvc.GetSyncDir(), nocalhost_path.GetNhctlHomeDir(), "")
 	utils2.KillSyncthingProcess(str)
+	return nil
 }
 
 func (d *DevStartOps) startSyncthing(podName string, resume bool) {
@@ -294,64 +430,42 @@ func (d *DevStartOps) startSyncthing(podName string, resume bool) {
 }
 
 func (d *DevStartOps) enterDevMode(devModeType profile.DevModeType) error {
-	must(
-		d.NocalhostSvc.AppMeta.SvcDevStarting(
-			d.NocalhostSvc.Name, d.NocalhostSvc.Type,
-			d.NocalhostApp.Identifier, devModeType,
-		),
-	)
-	must(
-		d.NocalhostSvc.UpdateSvcProfile(
-			func(v2 *profile.SvcProfileV2) error {
-				v2.OriginDevContainer = d.Container
-				return nil
-			},
-		),
-	)
+	if err := d.NocalhostSvc.AppMeta.SvcDevStarting(
+		d.NocalhostSvc.Name, d.NocalhostSvc.Type,
+		d.NocalhostApp.Identifier, devModeType,
+	); err != nil {
+		return err
+	}
 
 	// prevent dev status modified but not actually enter dev mode
 	var devStartSuccess = false
+	// workload has been modified, it needs to be reset as well
+	var imageReplaced = false
 	var err error
 	defer func() {
-		if !devStartSuccess {
-			log.Infof("Roll backing dev mode...")
-			_ = d.NocalhostSvc.AppMeta.SvcDevEnd(
-				d.NocalhostSvc.Name, d.NocalhostSvc.Identifier, d.NocalhostSvc.Type, devModeType,
-			)
+		if devStartSuccess {
+			return
 		}
+		if imageReplaced {
+			log.Info("Resetting workload...")
+			utils.ShouldI(d.NocalhostSvc.DevEnd(true), "Failed to reset workload")
+		}
+		log.Infof("Roll backing dev mode...")
+		_ = d.NocalhostSvc.AppMeta.SvcDevEnd(
+			d.NocalhostSvc.Name, d.NocalhostSvc.Identifier, d.NocalhostSvc.Type, devModeType,
+		)
 	}()
 
-	// Only `replace` DevMode needs to disable hpa
-	// replace and duplicate devMode also needs to disable HPA
-	//if devModeType.IsReplaceDevMode() {
-	log.Info("Disabling hpa...")
-	hl, err := d.NocalhostSvc.ListHPA()
-	if err != nil {
-		log.WarnE(err, "Failed to find hpa")
-	}
-	if len(hl) == 0 {
-		log.Info("No hpa found")
-	}
-	for _, h := range hl {
-		if len(h.Annotations) == 0 {
-			h.Annotations = make(map[string]string)
-		}
-		h.Annotations[_const.HPAOriginalMaxReplicasKey] = strconv.Itoa(int(h.Spec.MaxReplicas))
-		h.Spec.MaxReplicas = 1
-		if h.Spec.MinReplicas != nil {
-			h.Annotations[_const.HPAOriginalMinReplicasKey] = strconv.Itoa(int(*h.Spec.MinReplicas))
-			var i int32 = 1
-			h.Spec.MinReplicas = &i
-		}
-		if _, err = d.NocalhostSvc.Client.UpdateHPA(&h); err != nil {
-			log.WarnE(err, fmt.Sprintf("Failed to update hpa %s", h.Name))
-		} else {
-			log.Infof("HPA %s has been disabled", h.Name)
-		}
+	if err = d.NocalhostSvc.UpdateSvcProfile(
+		func(v2 *profile.SvcProfileV2) error {
+			v2.OriginDevContainer = d.Container
+			return nil
+		},
+	); err != nil {
+		return err
 	}
-	//}
 
-	//d.NocalhostSvc.DevModeType = devModeType
+	// hpa of the workload is suspended and restored by the pod controller
 	if err = d.NocalhostSvc.BuildPodController().ReplaceImage(context.TODO(), d.DevStartOptions); err != nil {
 		log.WarnE(err, "Failed to replace dev container")
 		log.Info("Resetting workload...")
@@ -361,6 +475,17 @@ func (d *DevStartOps) enterDevMode(devModeType profile.DevModeType) error {
 		}
 		return err
 	}
+	imageReplaced = true
+
+	// engine may fall back from the configured one while replacing image
+	if err = d.NocalhostSvc.UpdateSvcProfile(
+		func(v2 *profile.SvcProfileV2) error {
+			v2.SyncEngine = d.NocalhostSvc.GetSyncEngine(d.Container)
+			return nil
+		},
+	); err != nil {
+		return err
+	}
 
 	if err = d.NocalhostSvc.AppMeta.SvcDevStartComplete(
 		d.NocalhostSvc.Name, d.NocalhostSvc.Type, d.NocalhostSvc.Identifier, devModeType,
@@ -368,7 +493,11 @@ func (d *DevStartOps) enterDevMode(devModeType profile.DevModeType) error {
 		return err
 	}
 
-	utils.Should(d.NocalhostSvc.IncreaseDevModeCount())
+	if !devModeType.IsEphemeralDevMode() {
+		utils.Should(d.NocalhostSvc.IncreaseDevModeCount())
+	}
+
+	utils.ShouldI(d.NocalhostSvc.StartDevLease(d.Container), "Failed to start lease of DevMode")
 
 	// mark dev start as true
 	devStartSuccess = true
@@ -377,10 +506,21 @@ func (d *DevStartOps) enterDevMode(devModeType profile.DevModeType) error {
 }
 
 func (d *DevStartOps) startPortForwardAfterDevStart(devPodName string) {
-	for _, pf := range pfListBeforeDevStart {
-		utils.Should(d.NocalhostSvc.PortForward(devPodName, pf.LocalPort, pf.RemotePort, pf.Role))
+	for _, pf := range d.pfListBeforeDevStart {
+		// reverse port-forward is started from config by PortForwardAfterDevStart
+		if pf.Role == _const.ReversePortForwardRole {
+			continue
+		}
+		utils.Should(
+			d.NocalhostSvc.PortForwardWithIdleTimeout(
+				devPodName, pf.Protocol, pf.LocalPort, pf.RemotePort, pf.Role, pf.IdleTimeout,
+			),
+		)
 	}
 	must(d.NocalhostSvc.PortForwardAfterDevStart(devPodName, d.Container))
+	for container := range d.ExtraContainers {
+		must(d.NocalhostSvc.PortForwardAfterDevStart(devPodName, container))
+	}
 }
 
 func must(err error) {
@@ -0,0 +1,67 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"nocalhost/internal/nhctl/profile"
+	"strings"
+)
+
+// dryRunDevMode prints the workload, PVCs and syncthing secret which entering DevMode would apply
+func (d *DevStartOps) dryRunDevMode(dt profile.DevModeType) error {
+	if dryRunOutput != "yaml" && dryRunOutput != "json" {
+		return errors.New(fmt.Sprintf("Unsupported output format %s, json or yaml expected", dryRunOutput))
+	}
+
+	d.NocalhostSvc.DevModeType = dt
+	_ = d.NocalhostApp.ReloadSvcCfg(d.NocalhostSvc.Name, d.NocalhostSvc.Type, false, true)
+
+	result, err := d.NocalhostSvc.DryRunDevMode(d.DevStartOptions)
+	if err != nil {
+		return err
+	}
+
+	objs := []interface{}{result.Workload.Object}
+	for _, pvc := range result.PVCs {
+		objs = append(objs, pvc)
+	}
+	objs = append(objs, result.Secret)
+
+	if dryRunOutput == "json" {
+		items := make([]runtime.RawExtension, 0, len(objs))
+		for _, obj := range objs {
+			bys, err := json.Marshal(obj)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			items = append(items, runtime.RawExtension{Raw: bys})
+		}
+		bys, err := json.MarshalIndent(
+			map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items}, "", "  ",
+		)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		fmt.Println(string(bys))
+		return nil
+	}
+
+	docs := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		bys, err := yaml.Marshal(obj)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		docs = append(docs, string(bys))
+	}
+	fmt.Print(strings.Join(docs, "---\n"))
+	return nil
+}
@@ -0,0 +1,216 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"path/filepath"
+	"time"
+)
+
+// StartDevGroup starts DevMode of all services declared in dev group in one transaction,
+// once any of them fails, services already entered DevMode will be rolled back
+func (d *DevStartOps) StartDevGroup(applicationName, groupName string) error {
+
+	dt := profile.DevModeType(d.DevModeType)
+	if !dt.IsDuplicateDevMode() && !dt.IsReplaceDevMode() && !dt.IsEphemeralDevMode() {
+		return errors.New(fmt.Sprintf("Unsupported DevModeType %s", dt))
+	}
+
+	nocalhostApp, err := common.InitApp(applicationName)
+	if err != nil {
+		return err
+	}
+
+	if !nocalhostApp.GetAppMeta().IsInstalled() {
+		return errors.New(nocalhostApp.GetAppMeta().NotInstallTips())
+	}
+
+	group := nocalhostApp.GetApplicationConfigV2().GetDevGroup(groupName)
+	if group == nil {
+		return errors.New(fmt.Sprintf("Dev group %s not found in application %s", groupName, applicationName))
+	}
+	if len(group.Services) == 0 {
+		return errors.New(fmt.Sprintf("No service declared in dev group %s", groupName))
+	}
+
+	members := make([]*DevStartOps, 0, len(group.Services))
+	for _, svc := range group.Services {
+		svcType := svc.Type
+		if svcType == "" {
+			svcType = base.Deployment.String()
+		}
+		nocalhostSvc, err := nocalhostApp.InitAndCheckIfSvcExist(svc.Name, svcType)
+		if err != nil {
+			return err
+		}
+
+		ops := *d.DevStartOptions
+		ops.Container = svc.Container
+		ops.ExtraContainers = nil
+		ops.Ordinal = nil
+		ops.LocalSyncDir = nil
+		if svc.LocalSync != "" {
+			localSync, err := filepath.Abs(svc.LocalSync)
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			ops.LocalSyncDir = []string{localSync}
+		}
+		members = append(
+			members, &DevStartOps{DevStartOptions: &ops, NocalhostApp: nocalhostApp, NocalhostSvc: nocalhostSvc},
+		)
+	}
+
+	coloredoutput.Hint(fmt.Sprintf("Starting %s DevMode of dev group %s...", dt.ToString(), groupName))
+
+	groupMembers := make([]devGroupMember, 0, len(members))
+	for _, member := range members {
+		groupMembers = append(groupMembers, member)
+	}
+	if err = enterDevGroup(groupName, groupMembers, dt); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to start dev group %s", groupName))
+	}
+
+	for _, member := range members {
+		devPodName, err := member.NocalhostSvc.GetDevModePodName()
+		if err != nil {
+			return err
+		}
+
+		member.startPortForwardAfterDevStart(devPodName)
+
+		if !member.NoSyncthing {
+			member.startSyncthing(devPodName, false)
+		}
+	}
+
+	if d.NoSyncthing {
+		coloredoutput.Success("File sync is not started caused by --without-sync flag..")
+	}
+	coloredoutput.Success(fmt.Sprintf("Dev group %s has entered DevMode", groupName))
+	return nil
+}
+
+// devGroupMember is a service of dev group
+type devGroupMember interface {
+	checkDevGroupMember(groupName string) error
+	enterDevGroupMember(dt profile.DevModeType) error
+	rollbackDevMode()
+	restorePortForward()
+}
+
+// enterDevGroup checks all members before modifying any of them, then enters DevMode of them one by one.
+// Once any of them fails, members already entered DevMode are rolled back in reverse order
+func enterDevGroup(groupName string, members []devGroupMember, dt profile.DevModeType) error {
+	for _, member := range members {
+		if err := member.checkDevGroupMember(groupName); err != nil {
+			return err
+		}
+	}
+
+	entered := make([]devGroupMember, 0, len(members))
+	for _, member := range members {
+		if err := member.enterDevGroupMember(dt); err != nil {
+			// the failed one has been reset by itself, but its port-forwards are still stopped
+			coloredoutput.Fail(fmt.Sprintf("Rolling back dev group %s...", groupName))
+			member.restorePortForward()
+			for i := len(entered) - 1; i >= 0; i-- {
+				entered[i].rollbackDevMode()
+			}
+			return err
+		}
+		entered = append(entered, member)
+	}
+	return nil
+}
+
+func (d *DevStartOps) checkDevGroupMember(groupName string) error {
+	if d.NocalhostSvc.IsInDevMode() {
+		return errors.New(
+			fmt.Sprintf(
+				"%s %s is already in %s DevMode, please end it before starting dev group %s",
+				d.NocalhostSvc.Type, d.NocalhostSvc.Name, d.NocalhostSvc.DevModeType.ToString(), groupName,
+			),
+		)
+	}
+	return nil
+}
+
+func (d *DevStartOps) enterDevGroupMember(dt profile.DevModeType) error {
+	log.Infof("Starting DevMode of %s %s...", d.NocalhostSvc.Type, d.NocalhostSvc.Name)
+	if err := d.prepareAndEnterDevMode(dt); err != nil {
+		log.WarnE(err, fmt.Sprintf("Failed to start DevMode of %s", d.NocalhostSvc.Name))
+		return err
+	}
+	return nil
+}
+
+// rollbackDevMode resets the workload which has entered DevMode in a failed dev group
+func (d *DevStartOps) rollbackDevMode() {
+	svc := d.NocalhostSvc
+	log.Infof("Rolling back %s %s...", svc.Type, svc.Name)
+
+	utils.ShouldI(svc.DevEnd(true), fmt.Sprintf("Failed to roll back %s", svc.Name))
+	if !svc.DevModeType.IsEphemeralDevMode() {
+		utils.Should(svc.DecreaseDevModeCount())
+	}
+	d.restorePortForward()
+}
+
+// restorePortForward restarts port-forwards stopped before entering DevMode against the original pod
+func (d *DevStartOps) restorePortForward() {
+	if len(d.pfListBeforeDevStart) == 0 {
+		return
+	}
+	svc := d.NocalhostSvc
+
+	// list pods of the workload itself instead of the ones of DevMode
+	dt := svc.DevModeType
+	svc.DevModeType = profile.ReplaceDevMode
+	defer func() { svc.DevModeType = dt }()
+
+	var podName string
+	for i := 0; i < 60 && podName == ""; i++ {
+		<-time.After(time.Second)
+		pods, err := svc.GetPodList()
+		if err != nil {
+			log.WarnE(err, "")
+			continue
+		}
+		for _, pod := range pods {
+			if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+				podName = pod.Name
+				break
+			}
+		}
+	}
+	if podName == "" {
+		log.Warnf("No running pod of %s, port-forwards are not restored", svc.Name)
+		return
+	}
+
+	for _, pf := range d.pfListBeforeDevStart {
+		// reverse port-forward needs the sidecar of DevMode
+		if pf.Role == _const.ReversePortForwardRole {
+			continue
+		}
+		log.Infof("Restoring port-forward %d:%d of %s", pf.LocalPort, pf.RemotePort, svc.Name)
+		utils.Should(
+			svc.PortForwardWithIdleTimeout(podName, pf.Protocol, pf.LocalPort, pf.RemotePort, pf.Role, pf.IdleTimeout),
+		)
+	}
+	d.pfListBeforeDevStart = nil
+}
@@ -0,0 +1,88 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/profile"
+	"strings"
+	"testing"
+)
+
+type fakeDevGroupMember struct {
+	name     string
+	checkErr error
+	enterErr error
+	calls    *[]string
+}
+
+func (f *fakeDevGroupMember) checkDevGroupMember(string) error {
+	*f.calls = append(*f.calls, "check "+f.name)
+	return f.checkErr
+}
+
+func (f *fakeDevGroupMember) enterDevGroupMember(profile.DevModeType) error {
+	*f.calls = append(*f.calls, "enter "+f.name)
+	return f.enterErr
+}
+
+func (f *fakeDevGroupMember) rollbackDevMode() {
+	*f.calls = append(*f.calls, "rollback "+f.name)
+}
+
+func (f *fakeDevGroupMember) restorePortForward() {
+	*f.calls = append(*f.calls, "restore "+f.name)
+}
+
+func TestEnterDevGroup(t *testing.T) {
+	cases := []struct {
+		name     string
+		checkErr map[string]error
+		enterErr map[string]error
+		calls    []string
+	}{
+		{
+			name:  "all entered",
+			calls: []string{"check a", "check b", "check c", "enter a", "enter b", "enter c"},
+		},
+		{
+			name:     "check fails before modifying any",
+			checkErr: map[string]error{"c": errors.New("c is already in DevMode")},
+			calls:    []string{"check a", "check b", "check c"},
+		},
+		{
+			name:     "rolled back in reverse order",
+			enterErr: map[string]error{"c": errors.New("failed to enter DevMode")},
+			calls: []string{
+				"check a", "check b", "check c", "enter a", "enter b", "enter c",
+				"restore c", "rollback b", "rollback a",
+			},
+		},
+		{
+			name:     "first fails",
+			enterErr: map[string]error{"a": errors.New("failed to enter DevMode")},
+			calls:    []string{"check a", "check b", "check c", "enter a", "restore a"},
+		},
+	}
+	for _, c := range cases {
+		calls := make([]string, 0)
+		members := make([]devGroupMember, 0)
+		for _, name := range []string{"a", "b", "c"} {
+			members = append(
+				members, &fakeDevGroupMember{
+					name: name, checkErr: c.checkErr[name], enterErr: c.enterErr[name], calls: &calls,
+				},
+			)
+		}
+		err := enterDevGroup("group", members, profile.ReplaceDevMode)
+		if (err != nil) != (len(c.checkErr)+len(c.enterErr) > 0) {
+			t.Fatalf("%s: unexpected error %v", c.name, err)
+		}
+		if strings.Join(calls, ",") != strings.Join(c.calls, ",") {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.calls, calls)
+		}
+	}
+}
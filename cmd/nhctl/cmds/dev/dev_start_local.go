@@ -0,0 +1,54 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/profile"
+)
+
+// startLocalDevMode runs the workload on local machine, instead of replacing its container:
+// 1) materialize env, ConfigMap/Secret volumes and service account token of the container to local
+// 2) reverse in-cluster traffic of the workload to local through vpn
+// 3) run `command.run` of the container locally under local sync dir
+func (d *DevStartOps) startLocalDevMode() error {
+	if d.NocalhostSvc.IsInDevMode() {
+		if !d.NocalhostSvc.DevModeType.IsLocalDevMode() {
+			return errors.New(
+				fmt.Sprintf(
+					"Already in %s DevMode, end it before entering local DevMode",
+					d.NocalhostSvc.DevModeType.ToString(),
+				),
+			)
+		}
+		coloredoutput.Hint("Already in local DevMode...")
+	} else {
+		coloredoutput.Hint("Starting local DevMode...")
+		d.NocalhostSvc.DevModeType = profile.LocalDevMode
+		if err := d.loadLocalOrCmConfigIfValid(); err != nil {
+			return err
+		}
+		if err := d.recordLocalSyncDirToProfile(); err != nil {
+			return err
+		}
+		if err := d.enterDevMode(profile.LocalDevMode); err != nil {
+			return err
+		}
+	}
+
+	if d.NoTerminal {
+		coloredoutput.Success(
+			fmt.Sprintf(
+				"Traffic of %s has been reversed to local, env has been materialized to %s",
+				d.NocalhostSvc.Name, d.NocalhostSvc.GetLocalDevDir(),
+			),
+		)
+		return nil
+	}
+	return d.NocalhostSvc.RunLocalProcess(d.Container, d.LocalSyncDir[0])
+}
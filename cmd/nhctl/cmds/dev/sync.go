@@ -23,7 +23,7 @@ import (
 )
 
 func (d *DevStartOps) StartSyncthing(podName string, resume bool, stop bool, syncDouble *bool, override bool) {
-	if !d.NocalhostSvc.IsInReplaceDevMode() && !d.NocalhostSvc.IsInDuplicateDevMode() {
+	if !d.NocalhostSvc.IsInDevMode() {
 		log.Fatalf("Service \"%s\" is not in developing", common.WorkloadName)
 	}
 
@@ -34,6 +34,11 @@ func (d *DevStartOps) StartSyncthing(podName string, resume bool, stop bool, syn
 		)
 	}
 
+	if d.NocalhostSvc.GetDevSyncEngine() == _const.ExecSyncEngine {
+		d.startExecSyncEngine(podName, resume, stop)
+		return
+	}
+
 	// resume port-forward and syncthing
 	if resume || stop {
 		utils.ShouldI(d.NocalhostSvc.StopFileSyncOnly(), "Error occurs when stopping sync process")
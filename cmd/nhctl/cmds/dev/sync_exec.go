@@ -0,0 +1,104 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dev
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/syncengine"
+	"nocalhost/internal/nhctl/utils"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var (
+	syncExecContainer string
+	syncExecPod       string
+)
+
+func init() {
+	DevSyncExecCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "", "k8s deployment which your developing service exists",
+	)
+	DevSyncExecCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+	DevSyncExecCmd.Flags().StringVarP(&syncExecContainer, "container", "c", "", "container to sync files to")
+	DevSyncExecCmd.Flags().StringVar(&syncExecPod, "pod", "", "dev pod to sync files to")
+}
+
+// DevSyncExecCmd serves sync engine exec in foreground, it's started by `nhctl sync` in background
+var DevSyncExecCmd = &cobra.Command{
+	Use:    "sync-exec [NAME]",
+	Short:  "Sync files to dev container through exec api",
+	Long:   `Sync files to dev container through exec api`,
+	Hidden: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(args[0], common.WorkloadName, common.ServiceType)
+		must(err)
+		svcProfile, err := nocalhostSvc.GetProfile()
+		must(err)
+
+		engine, err := nocalhostSvc.NewExecSyncEngine(
+			syncExecContainer, syncExecPod, svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin, nil,
+		)
+		must(err)
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		must(engine.Serve(ctx))
+	},
+}
+
+// startExecSyncEngine files are pushed by nhctl itself, no port-forward or syncthing needed
+func (d *DevStartOps) startExecSyncEngine(podName string, resume, stop bool) {
+	if resume || stop {
+		utils.ShouldI(d.NocalhostSvc.StopFileSyncOnly(), "Error occurs when stopping sync process")
+		if stop {
+			return
+		}
+	} else if syncengine.FindProcess(d.NocalhostSvc.GetSyncDir()) != 0 {
+		coloredoutput.Hint("Sync engine exec has been started")
+		return
+	}
+
+	if podName == "" {
+		var err error
+		if podName, err = d.NocalhostSvc.GetDevModePodName(); err != nil {
+			must(err)
+		}
+	}
+	svcProfile, err := d.NocalhostSvc.GetProfile()
+	must(err)
+
+	args := []string{
+		"dev", "sync-exec", d.NocalhostApp.Name,
+		"-d", d.NocalhostSvc.Name,
+		"-t", d.NocalhostSvc.Type.String(),
+		"-n", d.NocalhostSvc.NameSpace,
+		"--kubeconfig", d.NocalhostSvc.Client.KubeConfigFilePath(),
+		"--container", d.Container,
+		"--pod", podName,
+	}
+	engine, err := d.NocalhostSvc.NewExecSyncEngine(
+		d.Container, podName, svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin, args,
+	)
+	must(err)
+	utils.ShouldI(engine.Run(context.TODO()), "Failed to run sync engine exec")
+
+	must(d.NocalhostSvc.SetSyncingStatus(true))
+}
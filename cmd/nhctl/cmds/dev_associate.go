@@ -140,7 +140,8 @@ var devAssociateCmd = &cobra.Command{
 			f()
 		}
 
-		if (nocalhostSvc.IsInReplaceDevMode() && nocalhostSvc.IsProcessor()) || nocalhostSvc.IsInDuplicateDevMode() {
+		if (nocalhostSvc.IsInReplaceDevMode() && nocalhostSvc.IsProcessor()) ||
+			nocalhostSvc.IsInDuplicateDevMode() || nocalhostSvc.IsInEphemeralDevMode() || nocalhostSvc.IsInLocalDevMode() {
 			if !dev_dir.DevPath(workDir).AlreadyAssociate(svcPack) {
 				log.PWarn("Current svc is already in DevMode, so can not switch associate dir, please exit the DevMode and try again.")
 				os.Exit(1)
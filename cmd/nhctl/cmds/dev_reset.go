@@ -38,9 +38,12 @@ var devResetCmd = &cobra.Command{
 		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(applicationName, common.WorkloadName, common.ServiceType)
 		must(err)
 
+		ephemeral := nocalhostSvc.DevModeType.IsEphemeralDevMode()
 		_ = nocalhostSvc.DevEnd(true)
 
-		utils.Should(nocalhostSvc.DecreaseDevModeCount())
+		if !ephemeral {
+			utils.Should(nocalhostSvc.DecreaseDevModeCount())
+		}
 		log.Infof("Service %s has been reset.\n", common.WorkloadName)
 	},
 }
@@ -186,8 +186,9 @@ var installCmd = &cobra.Command{
 						log.WarnE(err, "")
 						continue
 					}
-					log.Infof("Port forward %d:%d", lPort, rPort)
-					utils.Should(nhSvc.PortForward(podName, lPort, rPort, ""))
+					protocol, _ := utils.GetPortForwardProtocol(pf)
+					log.Infof("Port forward %s %d:%d", protocol, lPort, rPort)
+					utils.Should(nhSvc.PortForwardByProtocol(podName, protocol, lPort, rPort, ""))
 				}
 			}
 		}
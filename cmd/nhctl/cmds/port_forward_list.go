@@ -12,11 +12,21 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 	"nocalhost/cmd/nhctl/cmds/common"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/daemon_client"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/pfstats"
+	"nocalhost/pkg/nhctl/log"
 )
 
+var showPortForwardStats bool
+
 func init() {
 	portForwardListCmd.Flags().BoolVar(&listFlags.Yaml, "yaml", false, "use yaml as out put")
 	portForwardListCmd.Flags().BoolVar(&listFlags.Json, "json", false, "use json as out put")
+	portForwardListCmd.Flags().BoolVar(
+		&showPortForwardStats, "stats", false, "show connections and traffic of port-forwards from daemon",
+	)
 	PortForwardCmd.AddCommand(portForwardListCmd)
 }
 
@@ -30,6 +40,11 @@ type PortForwardItem struct {
 	DaemonServerPid int    `json:"daemonserverpid" yaml:"daemonserverpid"`
 	Updated         string `json:"updated" yaml:"updated"`
 	Reason          string `json:"reason" yaml:"reason"`
+	// Target is svc/SERVICE for port-forward to service, Endpoints are its ready endpoints
+	Target      string         `json:"target,omitempty" yaml:"target,omitempty"`
+	Endpoints   []string       `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	IdleTimeout string         `json:"idleTimeout,omitempty" yaml:"idleTimeout,omitempty"`
+	Stats       *pfstats.Stats `json:"stats,omitempty" yaml:"stats,omitempty"`
 }
 
 var portForwardListCmd = &cobra.Command{
@@ -50,19 +65,58 @@ var portForwardListCmd = &cobra.Command{
 		p, err := nocalhostApp.GetProfile()
 		must(err)
 
+		// stats are queried from daemon lazily, sudo and non-sudo daemon are queried separately
+		statsOfDaemon := map[bool][]*daemon_common.PortForwardStats{}
+		getStats := func(sudo bool) []*daemon_common.PortForwardStats {
+			if stats, ok := statsOfDaemon[sudo]; ok {
+				return stats
+			}
+			client, err := daemon_client.GetDaemonClient(sudo)
+			if err == nil {
+				statsOfDaemon[sudo], err = client.SendGetPortForwardStatsCommand()
+			}
+			if err != nil {
+				log.WarnE(err, "Failed to get port-forward stats from daemon")
+				statsOfDaemon[sudo] = nil
+			}
+			return statsOfDaemon[sudo]
+		}
+
 		pfList := make([]PortForwardItem, 0)
 		for _, sp := range p.SvcProfile {
 			for _, pf := range sp.DevPortForwardList {
+				port := fmt.Sprintf("%d:%d", pf.LocalPort, pf.RemotePort)
+				if pf.Protocol == _const.PortForwardUDP {
+					port = pf.Protocol + ":" + port
+				}
+				var target string
+				if pf.TargetService != "" {
+					target = "svc/" + pf.TargetService
+				}
+				var stats *pfstats.Stats
+				if showPortForwardStats {
+					for _, s := range getStats(pf.Sudo) {
+						if s.NameSpace == nocalhostApp.NameSpace && s.AppName == applicationName &&
+							s.SvcName == sp.GetName() && pf.Is(s.LocalPort, s.RemotePort, s.Protocol, s.Role) {
+							stats = &s.Stats
+							break
+						}
+					}
+				}
 				pfList = append(pfList, PortForwardItem{
 					SvcName:         sp.GetName(),
 					ServiceType:     sp.GetType(),
-					Port:            fmt.Sprintf("%d:%d", pf.LocalPort, pf.RemotePort),
+					Port:            port,
 					Status:          pf.Status,
 					Role:            pf.Role,
 					Sudo:            pf.Sudo,
 					DaemonServerPid: pf.DaemonServerPid,
 					Updated:         pf.Updated,
 					Reason:          pf.Reason,
+					Target:          target,
+					Endpoints:       pf.Endpoints,
+					IdleTimeout:     pf.IdleTimeout,
+					Stats:           stats,
 				})
 			}
 		}
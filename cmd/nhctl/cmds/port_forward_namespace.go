@@ -0,0 +1,149 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/daemon_client"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/nsforward"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"strings"
+)
+
+var nsForwardFlags = struct {
+	Stop       bool
+	List       bool
+	Json       bool
+	NoHosts    bool
+	HostsFile  string
+	DNSAddress string
+}{}
+
+func init() {
+	portForwardNamespaceCmd.Flags().BoolVar(&nsForwardFlags.Stop, "stop", false, "stop forwarding the namespace")
+	portForwardNamespaceCmd.Flags().BoolVar(&nsForwardFlags.List, "list", false, "list forwarded namespaces")
+	portForwardNamespaceCmd.Flags().BoolVar(&nsForwardFlags.Json, "json", false, "use json as out put")
+	portForwardNamespaceCmd.Flags().StringVar(
+		&nsForwardFlags.HostsFile, "hosts-file", nsforward.DefaultHostsFile(),
+		"hosts file to write the managed block of hostnames to",
+	)
+	portForwardNamespaceCmd.Flags().BoolVar(
+		&nsForwardFlags.NoHosts, "no-hosts", false, "do not write hostnames to hosts file",
+	)
+	portForwardNamespaceCmd.Flags().StringVar(
+		&nsForwardFlags.DNSAddress, "dns-address", "",
+		"serve hostnames by an embedded dns server on the address, such as 127.0.0.1:5353",
+	)
+	PortForwardCmd.AddCommand(portForwardNamespaceCmd)
+}
+
+var portForwardNamespaceCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "Forward all services of namespace to local addresses",
+	Long: `Forward all services of namespace to local addresses without vpn, each service gets a loopback
+address (127.x.x.x) with its own ports, and hostnames such as orders.my-ns.svc.cluster.local are
+written to a managed block of hosts file, or served by an embedded dns server. Writing the system
+hosts file needs sudo.
+
+Ports are listened on by the daemon. A daemon not run by root can never listen on privileged ports
+(below 1024) such as 80 and 443, so http://orders.my-ns.svc.cluster.local works only if nhctl is run
+with sudo. If loopback aliases are unavailable (such as macOS without aliases of lo0 added), or a port
+can not be listened on, another local port is used, and hostnames of the service are not published,
+connect to the printed local address and port instead. Namespace forwards are recovered after the
+daemon restarted`,
+	Example: `  nhctl port-forward namespace -n my-ns
+  nhctl port-forward namespace -n my-ns --no-hosts --dns-address 127.0.0.1:5353
+  nhctl port-forward namespace -n my-ns --stop`,
+	Run: func(cmd *cobra.Command, args []string) {
+		must(common.Prepare())
+		client, err := daemon_client.GetDaemonClient(utils.IsSudoUser())
+		must(err)
+
+		action := command.NamespaceForwardStart
+		if nsForwardFlags.Stop {
+			action = command.NamespaceForwardStop
+		} else if nsForwardFlags.List {
+			action = command.NamespaceForwardList
+		}
+		hostsFile := nsForwardFlags.HostsFile
+		if nsForwardFlags.NoHosts {
+			hostsFile = ""
+		}
+
+		status, err := client.SendNamespaceForwardCommand(
+			common.KubeConfig, common.NameSpace, action, hostsFile, nsForwardFlags.DNSAddress,
+		)
+		must(err)
+
+		if nsForwardFlags.Json {
+			bys, err := json.Marshal(status)
+			must(err)
+			fmt.Println(string(bys))
+			return
+		}
+		if action == command.NamespaceForwardStop {
+			log.Infof("Namespace %s is not forwarded any more", common.NameSpace)
+			return
+		}
+		for _, s := range status {
+			printNamespaceForward(s)
+		}
+	},
+}
+
+func printNamespaceForward(s *daemon_common.NamespaceForwardStatus) {
+	fmt.Printf("Namespace %s: %d services forwarded\n", s.Namespace, len(s.Services))
+	for _, svc := range s.Services {
+		ports := make([]string, 0, len(svc.Ports))
+		for _, p := range svc.Ports {
+			if p.LocalPort == p.Port {
+				ports = append(ports, fmt.Sprintf("%d", p.Port))
+			} else {
+				ports = append(ports, fmt.Sprintf("%d->%d", p.LocalPort, p.Port))
+			}
+		}
+		hostname := "-"
+		if len(svc.Hostnames) > 0 {
+			hostname = svc.Hostnames[len(svc.Hostnames)-1]
+		}
+		fmt.Printf("  %-30s %-16s %-24s %s\n", svc.Name, svc.Address, strings.Join(ports, ","), hostname)
+	}
+
+	published := nsforward.PublishedServices(s.Services)
+	if len(published) < len(s.Services) {
+		log.Warn(
+			"Hostnames of services below are not published, as their address is shared or not all ports " +
+				"are listened on, connect to local addresses instead:",
+		)
+		for _, svc := range s.Services {
+			if len(svc.Hostnames) > 0 {
+				continue
+			}
+			for _, p := range svc.Ports {
+				fmt.Printf("  %s:%d -> svc/%s:%d\n", svc.Address, p.LocalPort, svc.Name, p.Port)
+			}
+		}
+	}
+
+	if s.DNSAddress != "" {
+		fmt.Printf("Hostnames are served by dns on %s\n", s.DNSAddress)
+	}
+	if s.HostsError != "" && len(published) > 0 {
+		log.Warnf(
+			"Hostnames are not written to %s: %s, run it with sudo, use --dns-address, or add lines below to hosts file:",
+			s.HostsFile, s.HostsError,
+		)
+		for _, svc := range published {
+			fmt.Println(svc.Address + " " + strings.Join(svc.Hostnames, " "))
+		}
+	}
+}
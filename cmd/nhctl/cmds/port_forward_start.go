@@ -6,13 +6,20 @@
 package cmds
 
 import (
+	"fmt"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"nocalhost/cmd/nhctl/cmds/common"
 	"nocalhost/internal/nhctl/app"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/daemon_client"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
+	"os"
+	"strings"
+	"time"
 )
 
 var portForwardOptions = &app.PortForwardOptions{}
@@ -23,7 +30,7 @@ func init() {
 	)
 	portForwardStartCmd.Flags().StringSliceVarP(
 		&portForwardOptions.DevPort, "dev-port", "p", []string{},
-		"port-forward between pod and local, such 8080:8080 or :8080(random localPort)",
+		"port-forward between pod and local, such 8080:8080, :8080(random localPort) or udp:8125:8125",
 	)
 	//portForwardStartCmd.Flags().BoolVarP(&portForwardOptions.RunAsDaemon,
 	// "daemon", "m", true, "if port-forward run as daemon")
@@ -51,13 +58,22 @@ func init() {
 		&portForwardOptions.Follow, "follow", "", false,
 		"stock here waiting for disconnect or return immediately",
 	)
+	portForwardStartCmd.Flags().StringVarP(
+		&portForwardOptions.IdleTimeout, "idle-timeout", "", "",
+		"stop the port-forward after no traffic for the duration, such as 30m, never by default",
+	)
 	PortForwardCmd.AddCommand(portForwardStartCmd)
 }
 
 var portForwardStartCmd = &cobra.Command{
-	Use:   "start [NAME]",
+	Use:   "start [NAME] [svc/SERVICE]",
 	Short: "Forward local port to remote pod's port",
-	Long:  `Forward local port to remote pod's port`,
+	Long: `Forward local port to remote pod's port, or to the port of svc/SERVICE, connections to a
+service are balanced across its ready endpoints and failed over when an endpoint disappears.
+The port-forward to a service is recorded in the workload specified by -d, which is the
+service name by default, and the workload needs not exist`,
+	Example: `  nhctl port-forward start bookinfo -d productpage -p 9080:9080
+  nhctl port-forward start bookinfo svc/productpage -p 9080:9080`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
@@ -67,8 +83,36 @@ var portForwardStartCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 
 		applicationName := args[0]
-		nocalhostApp, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(applicationName, common.WorkloadName, common.ServiceType)
-		must(err)
+		if portForwardOptions.IdleTimeout != "" {
+			if _, err := time.ParseDuration(portForwardOptions.IdleTimeout); err != nil {
+				must(errors.New(fmt.Sprintf("Invalid idle timeout %s", portForwardOptions.IdleTimeout)))
+			}
+		}
+		var targetService string
+		if len(args) > 1 {
+			if !strings.HasPrefix(args[1], "svc/") && !strings.HasPrefix(args[1], "service/") {
+				must(errors.New(fmt.Sprintf("Unsupported target %s, only svc/SERVICE is supported", args[1])))
+			}
+			targetService = args[1][strings.Index(args[1], "/")+1:]
+			if common.WorkloadName == "" {
+				common.WorkloadName = targetService
+			}
+		}
+		var nocalhostApp *app.Application
+		var nocalhostSvc *controller.Controller
+		var err error
+		if targetService != "" {
+			// port-forward to service is only recorded in profile of the workload, which needs not exist
+			nocalhostApp, err = common.InitApp(applicationName)
+			must(err)
+			nocalhostSvc, err = nocalhostApp.InitService(common.WorkloadName, common.ServiceType)
+			must(err)
+		} else {
+			nocalhostApp, nocalhostSvc, err = common.InitAppAndCheckIfSvcExist(
+				applicationName, common.WorkloadName, common.ServiceType,
+			)
+			must(err)
+		}
 
 		log.Info("Starting port-forwarding")
 
@@ -81,21 +125,51 @@ var portForwardStartCmd = &cobra.Command{
 		}
 
 		var localPorts, remotePorts []int
+		var protocols []string
 		for _, port := range portForwardOptions.DevPort {
 			localPort, remotePort, err := utils.GetPortForwardForString(port)
 			if err != nil {
 				log.WarnE(err, "")
 				continue
 			}
+			protocol, _ := utils.GetPortForwardProtocol(port)
 			localPorts = append(localPorts, localPort)
 			remotePorts = append(remotePorts, remotePort)
+			protocols = append(protocols, protocol)
 		}
 
 		for index, localPort := range localPorts {
-			if portForwardOptions.Follow {
+			if targetService != "" && protocols[index] == _const.PortForwardUDP {
+				must(errors.New("UDP port-forward to service is not supported"))
+			}
+			if portForwardOptions.Follow && targetService != "" {
+				must(
+					nocalhostApp.ServicePortForward(
+						targetService, localPort, remotePorts[index], nil, nil,
+						genericclioptions.IOStreams{Out: os.Stdout, ErrOut: os.Stderr}, nil,
+					),
+				)
+			} else if targetService != "" {
+				must(
+					nocalhostSvc.PortForwardToService(
+						targetService, localPort, remotePorts[index], portForwardOptions.IdleTimeout,
+					),
+				)
+			} else if portForwardOptions.Follow && protocols[index] == _const.PortForwardUDP {
+				must(
+					nocalhostApp.UDPPortForward(
+						podName, localPort, remotePorts[index], nil, nil,
+						genericclioptions.IOStreams{Out: os.Stdout, ErrOut: os.Stderr},
+					),
+				)
+			} else if portForwardOptions.Follow {
 				must(nocalhostApp.PortForwardFollow(podName, localPort, remotePorts[index], nil))
 			} else {
-				must(nocalhostSvc.PortForward(podName, localPort, remotePorts[index], ""))
+				must(
+					nocalhostSvc.PortForwardWithIdleTimeout(
+						podName, protocols[index], localPort, remotePorts[index], "", portForwardOptions.IdleTimeout,
+					),
+				)
 			}
 		}
 		// notify daemon to invalid cache before return
@@ -0,0 +1,156 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/moby/term"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/controller"
+	"os"
+	"strings"
+)
+
+var syncConflictsOps = struct {
+	container  string
+	resolve    string
+	path       string
+	outputType string
+}{}
+
+func init() {
+	syncConflictsCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "",
+		"k8s deployment which your developing service exists",
+	)
+	syncConflictsCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+	syncConflictsCmd.Flags().StringVar(&syncConflictsOps.container, "container", "", "container name of pod to sync")
+	syncConflictsCmd.Flags().StringVar(
+		&syncConflictsOps.resolve, "resolve", "",
+		"resolve conflicts without prompting, can be local, remote or merge",
+	)
+	syncConflictsCmd.Flags().StringVar(
+		&syncConflictsOps.path, "path", "", "only the conflict of the path relative to local sync dir",
+	)
+	syncConflictsCmd.Flags().StringVarP(
+		&syncConflictsOps.outputType, "output", "o", "", "output format, only json is supported",
+	)
+	fileSyncCmd.AddCommand(syncConflictsCmd)
+}
+
+var syncConflictsCmd = &cobra.Command{
+	Use:   "conflicts [NAME]",
+	Short: "List and resolve conflicts of file sync",
+	Long: `List files whose local and remote versions diverged, with their diff,
+and resolve them by keeping local version, keeping remote version or merging them`,
+	Example: `  nhctl sync conflicts bookinfo -d productpage
+  nhctl sync conflicts bookinfo -d productpage --path src/app.py --resolve local`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		switch syncConflictsOps.resolve {
+		case "", controller.ConflictKeepLocal, controller.ConflictKeepRemote, controller.ConflictMerge:
+		default:
+			must(errors.New(fmt.Sprintf("Unsupported resolution %s", syncConflictsOps.resolve)))
+		}
+
+		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(args[0], common.WorkloadName, common.ServiceType)
+		must(err)
+		if !nocalhostSvc.IsInDevMode() {
+			must(errors.New(fmt.Sprintf("Service %s is not in DevMode", nocalhostSvc.Name)))
+		}
+
+		conflicts, err := nocalhostSvc.ListSyncConflicts(syncConflictsOps.container)
+		must(err)
+		if syncConflictsOps.path != "" {
+			filtered := make([]*controller.SyncConflict, 0)
+			for _, sc := range conflicts {
+				if sc.Path == strings.TrimPrefix(syncConflictsOps.path, "./") {
+					filtered = append(filtered, sc)
+				}
+			}
+			conflicts = filtered
+		}
+
+		if syncConflictsOps.outputType == JSON {
+			if syncConflictsOps.resolve == "" {
+				displayLn(conflicts)
+				return
+			}
+		} else if len(conflicts) == 0 {
+			coloredoutput.Success("No conflict found")
+			return
+		}
+
+		interactive := syncConflictsOps.resolve == "" && term.IsTerminal(os.Stdin.Fd())
+		reader := bufio.NewReader(os.Stdin)
+		for _, sc := range conflicts {
+			if syncConflictsOps.outputType != JSON {
+				printSyncConflict(sc)
+			}
+
+			resolution := syncConflictsOps.resolve
+			if interactive {
+				resolution = promptResolution(reader, sc.Path)
+			}
+			if resolution == "" {
+				continue
+			}
+			must(nocalhostSvc.ResolveSyncConflict(syncConflictsOps.container, sc, resolution))
+			coloredoutput.Success("Conflict of %s resolved by %s", sc.Path, resolution)
+		}
+	},
+}
+
+func printSyncConflict(sc *controller.SyncConflict) {
+	fmt.Printf("%s\n", sc.Path)
+	if sc.ConflictCopy != "" {
+		fmt.Printf("  conflict copy: %s\n", sc.ConflictCopy)
+	}
+	printSyncConflictVersion(controller.ConflictKeepLocal, sc.Local)
+	printSyncConflictVersion(controller.ConflictKeepRemote, sc.Remote)
+	fmt.Println(sc.Diff)
+}
+
+func printSyncConflictVersion(name string, v controller.SyncConflictVersion) {
+	if v.Deleted {
+		fmt.Printf("  %-6s  deleted\n", name)
+		return
+	}
+	fmt.Printf("  %-6s  %s  %d bytes\n", name, v.ModTime.Format("2006-01-02 15:04:05"), v.Size)
+}
+
+// promptResolution returns empty if the conflict is skipped
+func promptResolution(reader *bufio.Reader, p string) string {
+	for {
+		fmt.Printf("Resolve %s, keep [l]ocal, keep [r]emote, [m]erge or [s]kip: ", p)
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return ""
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "l", controller.ConflictKeepLocal:
+			return controller.ConflictKeepLocal
+		case "r", controller.ConflictKeepRemote:
+			return controller.ConflictKeepRemote
+		case "m", controller.ConflictMerge:
+			return controller.ConflictMerge
+		case "s", "skip":
+			return ""
+		}
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"nocalhost/cmd/nhctl/cmds/common"
 	"nocalhost/internal/nhctl/app"
 	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/syncengine"
 	"nocalhost/internal/nhctl/syncthing/network/req"
 	"nocalhost/pkg/nhctl/log"
 	"time"
@@ -43,6 +45,10 @@ func init() {
 		&syncStatusOps.Watch, "watch", false,
 		"wait for sync process finished, default value is false",
 	)
+	syncStatusCmd.Flags().StringVarP(
+		&syncStatusOps.Output, "output", "o", "",
+		"output format, only json is supported, with --watch sync events are streamed as json lines",
+	)
 	syncStatusCmd.Flags().Int64Var(
 		&syncStatusOps.Timeout, "timeout", 120,
 		"wait for sync process finished timeout, default is 120 seconds, unit is seconds ",
@@ -106,6 +112,14 @@ func SyncStatus(opt *app.SyncStatusOptions, ns, app, svc, svcType, kubeconfig st
 	//	return req.NotSyncthingProcessFound
 	//}
 
+	if nhSvc.GetDevSyncEngine() == _const.ExecSyncEngine {
+		if opt != nil && opt.Watch && opt.Output == JSON {
+			watchExecSyncEvents(nhSvc.GetSyncDir())
+			return nil
+		}
+		return execSyncStatus(nhSvc.GetSyncDir())
+	}
+
 	client := nhSvc.NewSyncthingHttpClient(2)
 
 	if opt != nil {
@@ -120,13 +134,73 @@ func SyncStatus(opt *app.SyncStatusOptions, ns, app, svc, svcType, kubeconfig st
 			return nil
 		}
 
+		if opt.Watch && opt.Output == JSON {
+			client.WatchSyncEvents(context.TODO(), func(event req.SyncEvent) { displayLn(event) })
+			return nil
+		}
+
 		if opt.Watch {
 			watchSyncProcess(client)
 			return nil
 		}
 	}
 
-	return client.GetSyncthingStatus()
+	status := client.GetSyncthingStatus()
+	if status.Status != req.Disconnected {
+		if svcProfile, err := nhSvc.GetProfile(); err == nil {
+			status.Folders = nhSvc.GetSyncMappingStatus(svcProfile.OriginDevContainer)
+		}
+	}
+	return status
+}
+
+// execSyncStatus sync engine exec reports its status by status file instead of syncthing api
+func execSyncStatus(home string) *req.SyncthingStatus {
+	if syncengine.FindProcess(home) == 0 {
+		return req.NotSyncthingProcessFound
+	}
+	status, err := syncengine.ReadStatus(home)
+	if err != nil {
+		return &req.SyncthingStatus{Status: req.Scanning, Msg: "Scanning local files"}
+	}
+
+	switch status.Status {
+	case syncengine.ExecIdle:
+		return &req.SyncthingStatus{Status: req.Idle, Msg: status.Msg}
+	case syncengine.ExecSyncing:
+		return &req.SyncthingStatus{
+			Status: req.Syncing, Msg: fmt.Sprintf("%s, %d files pending", status.Msg, status.Pending),
+		}
+	default:
+		return &req.SyncthingStatus{Status: req.Error, Msg: status.Msg, Tips: req.Identifier + status.Msg}
+	}
+}
+
+// watchExecSyncEvents sync engine exec has no event api, events are generated by changes of its status file
+func watchExecSyncEvents(home string) {
+	var last req.SyncEventType
+	for {
+		event := req.SyncEvent{Time: time.Now().Format(time.RFC3339Nano)}
+		if syncengine.FindProcess(home) == 0 {
+			event.Type = req.SyncEventDisconnected
+		} else if status, err := syncengine.ReadStatus(home); err == nil {
+			event.Msg = status.Msg
+			switch status.Status {
+			case syncengine.ExecIdle:
+				event.Type = req.SyncEventIdle
+			case syncengine.ExecSyncing:
+				event.Type = req.SyncEventScanStarted
+				event.Msg = fmt.Sprintf("%s, %d files pending", status.Msg, status.Pending)
+			default:
+				event.Type = req.SyncEventError
+			}
+		}
+		if event.Type != "" && event.Type != last {
+			displayLn(event)
+			last = event.Type
+		}
+		time.Sleep(time.Second)
+	}
 }
 
 func display(v interface{}) {
@@ -0,0 +1,122 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/coloredoutput"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/profile"
+)
+
+var syncTuneOps = struct {
+	container string
+	profile.SyncTuning
+}{}
+
+func init() {
+	syncTuneCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "",
+		"k8s deployment which your developing service exists",
+	)
+	syncTuneCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+	syncTuneCmd.Flags().StringVar(&syncTuneOps.container, "container", "", "container name of pod to sync")
+	syncTuneCmd.Flags().IntVar(
+		&syncTuneOps.MaxSendKbps, "max-send-kbps", 0, "rate limit of sending to dev container, 0 is unlimited",
+	)
+	syncTuneCmd.Flags().IntVar(
+		&syncTuneOps.MaxRecvKbps, "max-recv-kbps", 0, "rate limit of receiving from dev container, 0 is unlimited",
+	)
+	syncTuneCmd.Flags().StringVar(
+		&syncTuneOps.Compression, "compression", "", "compression of sync, always, metadata or never",
+	)
+	syncTuneCmd.Flags().IntVar(&syncTuneOps.RescanIntervalS, "rescan-interval", 0, "seconds between full scans")
+	syncTuneCmd.Flags().IntVar(
+		&syncTuneOps.FsWatcherDelayS, "fs-watcher-delay", 0, "seconds to batch file changes before syncing",
+	)
+	syncTuneCmd.Flags().StringVar(
+		&syncTuneOps.MaxFileSize, "max-file-size", "", "files larger than it are not synced, such as 100Mi",
+	)
+	fileSyncCmd.AddCommand(syncTuneCmd)
+}
+
+var syncTuneCmd = &cobra.Command{
+	Use:   "tune [NAME]",
+	Short: "Tune rate limits, compression and batching of file sync",
+	Long: `Update sync tuning of the container config and apply it to the running sync without
+restarting DevMode, only the flags specified are changed`,
+	Example: `  nhctl sync tune bookinfo -d productpage --max-send-kbps 1024 --compression always
+  nhctl sync tune bookinfo -d productpage --max-file-size 50Mi`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(args[0], common.WorkloadName, common.ServiceType)
+		must(err)
+		if !nocalhostSvc.IsInDevMode() {
+			must(errors.New(fmt.Sprintf("Service %s is not in DevMode", nocalhostSvc.Name)))
+		}
+		if nocalhostSvc.GetDevSyncEngine() == _const.ExecSyncEngine {
+			must(errors.New("Sync engine exec does not support sync tuning"))
+		}
+
+		switch syncTuneOps.Compression {
+		case "", _const.SyncCompressionAlways, _const.SyncCompressionMetadata, _const.SyncCompressionNever:
+		default:
+			must(errors.New(fmt.Sprintf("Unsupported compression %s", syncTuneOps.Compression)))
+		}
+		if syncTuneOps.MaxFileSize != "" {
+			_, err = resource.ParseQuantity(syncTuneOps.MaxFileSize)
+			must(errors.Wrap(err, "Invalid max file size"))
+		}
+
+		config := nocalhostSvc.Config()
+		devConfig := config.GetContainerDevConfigOrDefault(syncTuneOps.container)
+		if devConfig == nil {
+			must(errors.New("Dev config of container not found"))
+		}
+		if devConfig.Sync == nil {
+			devConfig.Sync = &profile.SyncConfig{}
+		}
+		if devConfig.Sync.Tuning == nil {
+			devConfig.Sync.Tuning = &profile.SyncTuning{}
+		}
+		tuning := devConfig.Sync.Tuning
+		flags := cmd.Flags()
+		if flags.Changed("max-send-kbps") {
+			tuning.MaxSendKbps = syncTuneOps.MaxSendKbps
+		}
+		if flags.Changed("max-recv-kbps") {
+			tuning.MaxRecvKbps = syncTuneOps.MaxRecvKbps
+		}
+		if flags.Changed("compression") {
+			tuning.Compression = syncTuneOps.Compression
+		}
+		if flags.Changed("rescan-interval") {
+			tuning.RescanIntervalS = syncTuneOps.RescanIntervalS
+		}
+		if flags.Changed("fs-watcher-delay") {
+			tuning.FsWatcherDelayS = syncTuneOps.FsWatcherDelayS
+		}
+		if flags.Changed("max-file-size") {
+			tuning.MaxFileSize = syncTuneOps.MaxFileSize
+		}
+		must(nocalhostSvc.UpdateConfig(*config))
+
+		must(nocalhostSvc.ApplySyncTuning(syncTuneOps.container))
+		coloredoutput.Success("Sync tuning is applied, rate limits of dev container take effect after DevMode restarted")
+	},
+}
@@ -0,0 +1,113 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/cmd/nhctl/cmds/common"
+	"nocalhost/internal/nhctl/coloredoutput"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
+	"os"
+)
+
+var syncVerifyOps = struct {
+	container  string
+	fix        bool
+	outputType string
+}{}
+
+func init() {
+	syncVerifyCmd.Flags().StringVarP(
+		&common.WorkloadName, "deployment", "d", "",
+		"k8s deployment which your developing service exists",
+	)
+	syncVerifyCmd.Flags().StringVarP(
+		&common.ServiceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet",
+	)
+	syncVerifyCmd.Flags().StringVar(&syncVerifyOps.container, "container", "", "container name of pod to sync")
+	syncVerifyCmd.Flags().BoolVar(
+		&syncVerifyOps.fix, "fix", false, "rescan the missing and differing files, and override remote changes",
+	)
+	syncVerifyCmd.Flags().StringVarP(
+		&syncVerifyOps.outputType, "output", "o", "", "output format, only json is supported",
+	)
+	fileSyncCmd.AddCommand(syncVerifyCmd)
+}
+
+var syncVerifyCmd = &cobra.Command{
+	Use:   "verify [NAME]",
+	Short: "Verify local files are really synced to dev container",
+	Long: `Compare content hashes of local sync dirs with the ones of dev container, and report
+missing, extra and differing files, files ignored by sync are skipped.
+It exits with 1 if local files are not all synced`,
+	Example: `  nhctl sync verify bookinfo -d productpage
+  nhctl sync verify bookinfo -d productpage --fix`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		_, nocalhostSvc, err := common.InitAppAndCheckIfSvcExist(args[0], common.WorkloadName, common.ServiceType)
+		must(err)
+		if !nocalhostSvc.IsInDevMode() {
+			must(errors.New(fmt.Sprintf("Service %s is not in DevMode", nocalhostSvc.Name)))
+		}
+		if nocalhostSvc.GetDevSyncEngine() == _const.ExecSyncEngine {
+			must(errors.New("Sync engine exec verifies files by content hashes itself"))
+		}
+
+		results, err := nocalhostSvc.VerifySync(syncVerifyOps.container)
+		must(err)
+
+		converged := true
+		for _, r := range results {
+			converged = converged && r.Converged()
+		}
+		if syncVerifyOps.outputType == JSON {
+			displayLn(results)
+		} else {
+			for _, r := range results {
+				printSyncVerifyResult(r)
+			}
+		}
+
+		if converged {
+			return
+		}
+		if !syncVerifyOps.fix {
+			os.Exit(1)
+		}
+		must(nocalhostSvc.FixSync(syncVerifyOps.container, results))
+		coloredoutput.Success("Missing and differing files are rescanned, run verify again after sync finished")
+	},
+}
+
+func printSyncVerifyResult(r *controller.SyncVerifyResult) {
+	if r.Converged() && len(r.Extra) == 0 {
+		coloredoutput.Success("%s -> %s: in sync", r.Local, r.Remote)
+		return
+	}
+	if r.Converged() {
+		coloredoutput.Success("%s -> %s: in sync, with %d extra files in dev container", r.Local, r.Remote, len(r.Extra))
+	} else {
+		coloredoutput.Fail("%s -> %s: not in sync", r.Local, r.Remote)
+	}
+	printSyncVerifyFiles("missing", r.Missing)
+	printSyncVerifyFiles("differing", r.Differing)
+	printSyncVerifyFiles("extra", r.Extra)
+}
+
+func printSyncVerifyFiles(kind string, files []string) {
+	for _, f := range files {
+		fmt.Printf("  %-9s  %s\n", kind, f)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"nocalhost/internal/nhctl/udpforward"
+	"os"
+)
+
+var udpRelayPort int
+
+func init() {
+	udpRelayCmd.Flags().IntVar(&udpRelayPort, "port", 0, "udp port of container to relay datagrams to")
+	rootCmd.AddCommand(udpRelayCmd)
+}
+
+// udpRelayCmd runs in container as the relay of udp port-forward, framed datagrams are read from stdin,
+// and replies are written to stdout
+var udpRelayCmd = &cobra.Command{
+	Use:    "udp-relay",
+	Short:  "Relay framed datagrams of stdin to udp port",
+	Long:   `Relay framed datagrams of stdin to udp port`,
+	Hidden: true,
+	// nhctl is not initialized in container
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := udpforward.Relay(
+			context.Background(), fmt.Sprintf("127.0.0.1:%d", udpRelayPort),
+			struct {
+				io.Reader
+				io.Writer
+			}{os.Stdin, os.Stdout},
+		)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
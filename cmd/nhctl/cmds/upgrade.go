@@ -74,7 +74,7 @@ var upgradeCmd = &cobra.Command{
 				}
 				pfList = append(pfList, pf)
 				log.Infof("Stopping pf: %d:%d", pf.LocalPort, pf.RemotePort)
-				utils.Should(nhSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort))
+				utils.Should(nhSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort, pf.GetProtocol(), pf.Role))
 			}
 			if len(pfList) > 0 {
 				pfListMap[svcProfile.GetName()] = pfList
@@ -94,13 +94,22 @@ var upgradeCmd = &cobra.Command{
 				ctx, _ := context.WithTimeout(context.Background(), 5*time.Minute)
 				nhSvc, err := nocalhostApp.InitService(svcName, pf.ServiceType)
 				must(err)
+				if pf.TargetService != "" {
+					log.Infof("Starting pf %d:%d to svc/%s", pf.LocalPort, pf.RemotePort, pf.TargetService)
+					utils.Should(nhSvc.PortForwardToService(pf.TargetService, pf.LocalPort, pf.RemotePort, pf.IdleTimeout))
+					continue
+				}
 				podName, err := controller.GetDefaultPodName(ctx, nhSvc)
 				if err != nil {
 					log.WarnE(err, "")
 					continue
 				}
 				log.Infof("Starting pf %d:%d for %s", pf.LocalPort, pf.RemotePort, svcName)
-				utils.Should(nhSvc.PortForward(podName, pf.LocalPort, pf.RemotePort, pf.Role))
+				utils.Should(
+					nhSvc.PortForwardWithIdleTimeout(
+						podName, pf.Protocol, pf.LocalPort, pf.RemotePort, pf.Role, pf.IdleTimeout,
+					),
+				)
 			}
 		}
 	},
@@ -624,7 +624,8 @@ type PortForwardOptions struct {
 	Way         string // port-forward way, value is manual or devPorts
 	RunAsDaemon bool
 	Forward     bool
-	Follow      bool // will stock until send ctrl+c or occurs error
+	Follow      bool   // will stock until send ctrl+c or occurs error
+	IdleTimeout string // stop port-forward after no traffic for the duration
 }
 
 type PortForwardEndOptions struct {
@@ -681,6 +682,16 @@ func (a *Application) PortForward(pod string, localPort, remotePort int, readyCh
 	return a.client.ForwardPortForwardByPod(pod, localPort, remotePort, readyChan, stopChan, g)
 }
 
+// UDPPortForward forwards udp localPort to remotePort of pod through an in-pod relay
+func (a *Application) UDPPortForward(pod string, localPort, remotePort int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams) error {
+	return a.client.ForwardUDPByPod(pod, localPort, remotePort, readyChan, stopChan, g)
+}
+
+// ServicePortForward forwards localPort to port of service, balanced across its ready endpoints
+func (a *Application) ServicePortForward(service string, localPort, port int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams, onChange func([]clientgoutils.ServiceEndpoint)) error {
+	return a.client.ForwardService(service, localPort, port, readyChan, stopChan, g, onChange)
+}
+
 func (a *Application) CleanUpTmpResources() error {
 	if !a.shouldClean {
 		return nil
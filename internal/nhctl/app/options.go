@@ -21,6 +21,7 @@ type SyncStatusOptions struct {
 	WaitForSync bool
 	Watch       bool
 	Timeout     int64
+	Output      string
 }
 
 type SyncStatusDirOptions struct {
@@ -0,0 +1,121 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package appmeta
+
+import (
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/common/base"
+	profile2 "nocalhost/internal/nhctl/profile"
+	"time"
+)
+
+// ApplicationDevLease leases of DevMode, keyed the same as ApplicationDevMeta,
+// DevMode with an expired lease will be ended by daemon
+type ApplicationDevLease map[base.SvcType]map[ /* resource name */ string]*DevLease
+
+type DevLease struct {
+	Name        string               `json:"name" yaml:"name"`
+	Identifier  string               `json:"identifier" yaml:"identifier"`
+	DevModeType profile2.DevModeType `json:"devModeType" yaml:"devModeType"`
+	// idle timeout in seconds
+	TTLSeconds int64 `json:"ttl" yaml:"ttl"`
+	// unix timestamp of the last activity, such as file syncing or terminal session
+	LastActivity int64 `json:"lastActivity" yaml:"lastActivity"`
+}
+
+func (l *DevLease) TTL() time.Duration {
+	return time.Duration(l.TTLSeconds) * time.Second
+}
+
+// Expired return true if no activity happened within ttl plus grace
+func (l *DevLease) Expired(now time.Time, grace time.Duration) bool {
+	if l.TTLSeconds <= 0 {
+		return false
+	}
+	return now.After(time.Unix(l.LastActivity, 0).Add(l.TTL() + grace))
+}
+
+// NeedRenew lease is not renewed on every activity, which causes too many updates of secret
+func (l *DevLease) NeedRenew(now time.Time) bool {
+	return now.Sub(time.Unix(l.LastActivity, 0)) >= l.TTL()/10
+}
+
+func (a *ApplicationMeta) devLeaseOf(svcType base.SvcType) map[string]*DevLease {
+	if a.DevLease == nil {
+		a.DevLease = ApplicationDevLease{}
+	}
+	if _, ok := a.DevLease[svcType.Alias()]; !ok {
+		a.DevLease[svcType.Alias()] = map[string]*DevLease{}
+	}
+	return a.DevLease[svcType.Alias()]
+}
+
+// SvcDevLeaseStart records a lease for svc in DevMode, ttl <= 0 means never expire
+func (a *ApplicationMeta) SvcDevLeaseStart(name string, svcType base.SvcType, identifier string,
+	modeType profile2.DevModeType, ttl time.Duration) error {
+	m := a.devLeaseOf(svcType)
+	key := devModeName(name, identifier, modeType)
+	if ttl <= 0 {
+		if _, ok := m[key]; !ok {
+			return nil
+		}
+		delete(m, key)
+		return a.Update()
+	}
+
+	m[key] = &DevLease{
+		Name:         name,
+		Identifier:   identifier,
+		DevModeType:  modeType,
+		TTLSeconds:   int64(ttl / time.Second),
+		LastActivity: time.Now().Unix(),
+	}
+	return a.Update()
+}
+
+// SvcDevLeaseRenew marks svc as active, return false if no lease found or renewing is unnecessary yet
+func (a *ApplicationMeta) SvcDevLeaseRenew(name string, svcType base.SvcType, identifier string,
+	modeType profile2.DevModeType) (bool, error) {
+	now := time.Now()
+	if lease := a.GetSvcDevLease(name, svcType, identifier, modeType); lease == nil || !lease.NeedRenew(now) {
+		return false, nil
+	}
+
+	// meta may be held for a long time, such as by terminal session,
+	// refresh it first, or DevMode ended by others will be written back
+	if a.operator != nil {
+		secret, err := a.operator.Get(a.Ns, SecretNamePrefix+a.Application)
+		if err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		if err = a.ReAssignmentBySecret(secret); err != nil {
+			return false, err
+		}
+	}
+
+	lease := a.GetSvcDevLease(name, svcType, identifier, modeType)
+	if lease == nil || !lease.NeedRenew(now) {
+		return false, nil
+	}
+	lease.LastActivity = now.Unix()
+	return true, a.Update()
+}
+
+func (a *ApplicationMeta) GetSvcDevLease(name string, svcType base.SvcType, identifier string,
+	modeType profile2.DevModeType) *DevLease {
+	return a.devLeaseOf(svcType)[devModeName(name, identifier, modeType)]
+}
+
+// GetAllDevLeases return leases of all svc
+func (a *ApplicationMeta) GetAllDevLeases() map[base.SvcType][]*DevLease {
+	result := map[base.SvcType][]*DevLease{}
+	for svcType, m := range a.DevLease {
+		for _, lease := range m {
+			result[svcType] = append(result[svcType], lease)
+		}
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package appmeta
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDevLeaseExpired(t *testing.T) {
+	last := time.Unix(1600000000, 0)
+	lease := &DevLease{TTLSeconds: 600, LastActivity: last.Unix()}
+
+	cases := []struct {
+		name    string
+		elapsed time.Duration
+		grace   time.Duration
+		expired bool
+	}{
+		{"within ttl", 599 * time.Second, 0, false},
+		{"at ttl", 600 * time.Second, 0, false},
+		{"after ttl", 601 * time.Second, 0, true},
+		{"after ttl within grace", 601 * time.Second, time.Minute, false},
+		{"at ttl plus grace", 660 * time.Second, time.Minute, false},
+		{"after ttl plus grace", 661 * time.Second, time.Minute, true},
+	}
+	for _, c := range cases {
+		if lease.Expired(last.Add(c.elapsed), c.grace) != c.expired {
+			t.Fatalf("%s: expired should be %v", c.name, c.expired)
+		}
+	}
+
+	never := &DevLease{TTLSeconds: 0, LastActivity: last.Unix()}
+	if never.Expired(last.Add(365*24*time.Hour), 0) {
+		t.Fatal("lease without ttl should never expire")
+	}
+}
+
+func TestDevLeaseNeedRenew(t *testing.T) {
+	last := time.Unix(1600000000, 0)
+	lease := &DevLease{TTLSeconds: 600, LastActivity: last.Unix()}
+
+	// renewed at most once per tenth of ttl
+	if lease.NeedRenew(last.Add(59 * time.Second)) {
+		t.Fatal("lease should not be renewed within a tenth of ttl")
+	}
+	if !lease.NeedRenew(last.Add(60 * time.Second)) {
+		t.Fatal("lease should be renewed after a tenth of ttl")
+	}
+}
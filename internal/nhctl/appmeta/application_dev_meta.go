@@ -52,11 +52,11 @@ func (from *ApplicationDevMeta) Events(to ApplicationDevMeta) *[]*ApplicationEve
 
 	marshalFrom, err := json.Marshal(from)
 	if err != nil {
-		log.Error("Error while marshal 'From ApplicationDevMeta': %s", err.Error())
+		log.Errorf("Error while marshal 'From ApplicationDevMeta': %s", err.Error())
 	}
 	marshalTo, err := json.Marshal(to)
 	if err != nil {
-		log.Error("Error while marshal 'To ApplicationDevMeta': %s", err.Error())
+		log.Errorf("Error while marshal 'To ApplicationDevMeta': %s", err.Error())
 	}
 
 	if string(marshalTo) == string(marshalFrom) {
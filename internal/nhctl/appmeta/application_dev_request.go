@@ -0,0 +1,207 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package appmeta
+
+import (
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/common/base"
+	"time"
+)
+
+const (
+	// DEV_REQ someone queues for a service in replace DevMode
+	DEV_REQ EVENT = "DEV_REQ"
+	// DEV_GRANT the holder hands off the service to the first requester in queue
+	DEV_GRANT EVENT = "DEV_GRANT"
+
+	// DevRequestGrantTimeout the granted service will be available for others
+	// if the requester does not enter DevMode in time
+	DevRequestGrantTimeout = 10 * time.Minute
+)
+
+var (
+	ErrNoDevRequest      = errors.New("No pending dev request")
+	ErrDevRequestGranted = errors.New("Svc has been handed off to another developer")
+)
+
+// ApplicationDevRequest queues of developers waiting for services in replace DevMode
+type ApplicationDevRequest map[base.SvcType]map[ /* resource name */ string][]*DevRequest
+
+type DevRequest struct {
+	Identifier  string `json:"identifier" yaml:"identifier"`
+	Container   string `json:"container,omitempty" yaml:"container,omitempty"`
+	LocalSync   string `json:"localSync,omitempty" yaml:"localSync,omitempty"`
+	DevImage    string `json:"devImage,omitempty" yaml:"devImage,omitempty"`
+	RequestedAt int64  `json:"requestedAt" yaml:"requestedAt"`
+	// unix timestamp of handing off, 0 means pending
+	GrantedAt int64 `json:"grantedAt,omitempty" yaml:"grantedAt,omitempty"`
+}
+
+func (r *DevRequest) Granted() bool {
+	return r.GrantedAt > 0
+}
+
+func (r *DevRequest) GrantExpired(now time.Time) bool {
+	return r.Granted() && now.After(time.Unix(r.GrantedAt, 0).Add(DevRequestGrantTimeout))
+}
+
+func (from ApplicationDevRequest) Events(to ApplicationDevRequest) []*ApplicationEvent {
+	result := make([]*ApplicationEvent, 0)
+	for svcType, toQueues := range to {
+		for resourceName, toQueue := range toQueues {
+			fromQueue := from[svcType][resourceName]
+			for _, request := range toQueue {
+				previous := findDevRequest(fromQueue, request.Identifier)
+				if previous == nil {
+					result = append(
+						result, &ApplicationEvent{
+							EventType: DEV_REQ, ResourceName: resourceName, Identifier: request.Identifier,
+							DevType: svcType,
+						},
+					)
+				}
+				if request.Granted() && (previous == nil || !previous.Granted()) {
+					result = append(
+						result, &ApplicationEvent{
+							EventType: DEV_GRANT, ResourceName: resourceName, Identifier: request.Identifier,
+							DevType: svcType,
+						},
+					)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func findDevRequest(queue []*DevRequest, identifier string) *DevRequest {
+	for _, request := range queue {
+		if request.Identifier == identifier {
+			return request
+		}
+	}
+	return nil
+}
+
+func (a *ApplicationMeta) devRequestsOf(svcType base.SvcType) map[string][]*DevRequest {
+	if a.DevRequest == nil {
+		a.DevRequest = ApplicationDevRequest{}
+	}
+	if _, ok := a.DevRequest[svcType.Alias()]; !ok {
+		a.DevRequest[svcType.Alias()] = map[string][]*DevRequest{}
+	}
+	return a.DevRequest[svcType.Alias()]
+}
+
+// GetSvcDevRequests return queue of the svc, granted request comes first
+func (a *ApplicationMeta) GetSvcDevRequests(name string, svcType base.SvcType) []*DevRequest {
+	return a.devRequestsOf(svcType)[name]
+}
+
+// SvcDevRequest appends request to the queue of svc, if the requester is already in the queue,
+// the request will be updated and keep its position
+func (a *ApplicationMeta) SvcDevRequest(name string, svcType base.SvcType, request *DevRequest) error {
+	a.enqueueDevRequest(name, svcType, request, time.Now())
+	return a.Update()
+}
+
+func (a *ApplicationMeta) enqueueDevRequest(name string, svcType base.SvcType, request *DevRequest, now time.Time) {
+	m := a.devRequestsOf(svcType)
+	if previous := findDevRequest(m[name], request.Identifier); previous != nil {
+		previous.Container = request.Container
+		previous.LocalSync = request.LocalSync
+		previous.DevImage = request.DevImage
+		return
+	}
+	request.RequestedAt = now.Unix()
+	m[name] = append(m[name], request)
+}
+
+// SvcDevRequestCancel removes requester from the queue of svc
+func (a *ApplicationMeta) SvcDevRequestCancel(name string, svcType base.SvcType, identifier string) error {
+	m := a.devRequestsOf(svcType)
+	if findDevRequest(m[name], identifier) == nil {
+		return nil
+	}
+	a.removeDevRequest(name, svcType, identifier)
+	return a.Update()
+}
+
+func (a *ApplicationMeta) removeDevRequest(name string, svcType base.SvcType, identifier string) {
+	m := a.devRequestsOf(svcType)
+	queue := make([]*DevRequest, 0, len(m[name]))
+	for _, request := range m[name] {
+		if request.Identifier != identifier {
+			queue = append(queue, request)
+		}
+	}
+	if len(queue) == 0 {
+		delete(m, name)
+	} else {
+		m[name] = queue
+	}
+}
+
+// SvcDevHandoff grants svc to the first pending requester,
+// requests granted but not taken in time are dropped
+func (a *ApplicationMeta) SvcDevHandoff(name string, svcType base.SvcType) (*DevRequest, error) {
+	granted, err := a.handoffDevRequest(name, svcType, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return granted, a.Update()
+}
+
+func (a *ApplicationMeta) handoffDevRequest(name string, svcType base.SvcType, now time.Time) (*DevRequest, error) {
+	m := a.devRequestsOf(svcType)
+
+	var granted *DevRequest
+	queue := make([]*DevRequest, 0, len(m[name]))
+	for _, request := range m[name] {
+		if request.GrantExpired(now) {
+			continue
+		}
+		if granted == nil && !request.Granted() {
+			request.GrantedAt = now.Unix()
+			granted = request
+		}
+		queue = append(queue, request)
+	}
+	if granted == nil {
+		return nil, ErrNoDevRequest
+	}
+	m[name] = queue
+	return granted, nil
+}
+
+// SvcDevHandoffRevoke takes back the grant if the holder failed to end DevMode after handing off,
+// the requester keeps its position in the queue
+func (a *ApplicationMeta) SvcDevHandoffRevoke(name string, svcType base.SvcType, identifier string) error {
+	if !a.revokeDevRequestGrant(name, svcType, identifier) {
+		return nil
+	}
+	return a.Update()
+}
+
+func (a *ApplicationMeta) revokeDevRequestGrant(name string, svcType base.SvcType, identifier string) bool {
+	request := findDevRequest(a.devRequestsOf(svcType)[name], identifier)
+	if request == nil || !request.Granted() {
+		return false
+	}
+	request.GrantedAt = 0
+	return true
+}
+
+// checkDevRequestGranted return ErrDevRequestGranted if svc has been handed off to others
+func (a *ApplicationMeta) checkDevRequestGranted(name string, svcType base.SvcType, identifier string) error {
+	now := time.Now()
+	for _, request := range a.devRequestsOf(svcType)[name] {
+		if request.Granted() && !request.GrantExpired(now) && request.Identifier != identifier {
+			return ErrDevRequestGranted
+		}
+	}
+	return nil
+}
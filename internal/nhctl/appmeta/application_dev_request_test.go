@@ -0,0 +1,193 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package appmeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/common/base"
+	"strings"
+	"testing"
+	"time"
+)
+
+func devRequestQueue(queue []*DevRequest) string {
+	items := make([]string, 0, len(queue))
+	for _, r := range queue {
+		item := r.Identifier
+		if r.Granted() {
+			item += "(granted)"
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ",")
+}
+
+func devRequestEvents(events []*ApplicationEvent) string {
+	items := make([]string, 0, len(events))
+	for _, e := range events {
+		items = append(items, fmt.Sprintf("%s %s %s", e.EventType, e.ResourceName, e.Identifier))
+	}
+	return strings.Join(items, ",")
+}
+
+func TestApplicationDevRequest(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	granted := now.Add(-time.Minute).Unix()
+	expired := now.Add(-DevRequestGrantTimeout - time.Minute).Unix()
+
+	cases := []struct {
+		name   string
+		queue  []*DevRequest
+		op     func(a *ApplicationMeta) error
+		err    error
+		expect string
+		events string
+	}{
+		{
+			name:   "enqueue",
+			queue:  []*DevRequest{{Identifier: "a"}},
+			op:     func(a *ApplicationMeta) error { return enqueue(a, &DevRequest{Identifier: "b"}, now) },
+			expect: "a,b",
+			events: "DEV_REQ reviews b",
+		},
+		{
+			name:  "duplicate request keeps its position",
+			queue: []*DevRequest{{Identifier: "a"}, {Identifier: "b"}},
+			op: func(a *ApplicationMeta) error {
+				return enqueue(a, &DevRequest{Identifier: "a", Container: "sidecar"}, now)
+			},
+			expect: "a,b",
+		},
+		{
+			name:  "cancel",
+			queue: []*DevRequest{{Identifier: "a"}, {Identifier: "b"}},
+			op: func(a *ApplicationMeta) error {
+				a.removeDevRequest("reviews", base.Deployment, "a")
+				return nil
+			},
+			expect: "b",
+		},
+		{
+			name:  "cancel the last one",
+			queue: []*DevRequest{{Identifier: "a"}},
+			op: func(a *ApplicationMeta) error {
+				a.removeDevRequest("reviews", base.Deployment, "a")
+				return nil
+			},
+		},
+		{
+			name: "handoff with empty queue",
+			op:   handoff(now),
+			err:  ErrNoDevRequest,
+		},
+		{
+			name:   "handoff",
+			queue:  []*DevRequest{{Identifier: "a"}, {Identifier: "b"}},
+			op:     handoff(now),
+			expect: "a(granted),b",
+			events: "DEV_GRANT reviews a",
+		},
+		{
+			name:   "handoff drops expired grant",
+			queue:  []*DevRequest{{Identifier: "a", GrantedAt: expired}, {Identifier: "b"}},
+			op:     handoff(now),
+			expect: "b(granted)",
+			events: "DEV_GRANT reviews b",
+		},
+		{
+			name:   "handoff without pending request",
+			queue:  []*DevRequest{{Identifier: "a", GrantedAt: granted}},
+			op:     handoff(now),
+			err:    ErrNoDevRequest,
+			expect: "a(granted)",
+		},
+		{
+			name:  "revoke grant",
+			queue: []*DevRequest{{Identifier: "a", GrantedAt: granted}, {Identifier: "b"}},
+			op: func(a *ApplicationMeta) error {
+				if !a.revokeDevRequestGrant("reviews", base.Deployment, "a") {
+					return errors.New("grant not revoked")
+				}
+				return nil
+			},
+			expect: "a,b",
+		},
+	}
+
+	for _, c := range cases {
+		a := &ApplicationMeta{}
+		if c.queue != nil {
+			a.devRequestsOf(base.Deployment)["reviews"] = c.queue
+		}
+		before := ApplicationDevRequest{}
+		bys, _ := json.Marshal(a.DevRequest)
+		_ = json.Unmarshal(bys, &before)
+
+		if err := c.op(a); err != c.err {
+			t.Fatalf("%s: expected error %v, got %v", c.name, c.err, err)
+		}
+		if queue := devRequestQueue(a.GetSvcDevRequests("reviews", base.Deployment)); queue != c.expect {
+			t.Fatalf("%s: expected queue %s, got %s", c.name, c.expect, queue)
+		}
+		if events := devRequestEvents(before.Events(a.DevRequest)); events != c.events {
+			t.Fatalf("%s: expected events %s, got %s", c.name, c.events, events)
+		}
+	}
+}
+
+func enqueue(a *ApplicationMeta, request *DevRequest, now time.Time) error {
+	a.enqueueDevRequest("reviews", base.Deployment, request, now)
+	return nil
+}
+
+func handoff(now time.Time) func(a *ApplicationMeta) error {
+	return func(a *ApplicationMeta) error {
+		_, err := a.handoffDevRequest("reviews", base.Deployment, now)
+		return err
+	}
+}
+
+func TestCheckDevRequestGranted(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name  string
+		queue []*DevRequest
+		err   error
+	}{
+		{"no request", nil, nil},
+		{"pending request", []*DevRequest{{Identifier: "b"}}, nil},
+		{"granted to self", []*DevRequest{{Identifier: "a", GrantedAt: now.Unix()}}, nil},
+		{"granted to others", []*DevRequest{{Identifier: "b", GrantedAt: now.Unix()}}, ErrDevRequestGranted},
+		{
+			"grant expired",
+			[]*DevRequest{{Identifier: "b", GrantedAt: now.Add(-DevRequestGrantTimeout - time.Minute).Unix()}},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		a := &ApplicationMeta{}
+		a.devRequestsOf(base.Deployment)["reviews"] = c.queue
+		if err := a.checkDevRequestGranted("reviews", base.Deployment, "a"); err != c.err {
+			t.Fatalf("%s: expected error %v, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestDevRequestGrantExpired(t *testing.T) {
+	grantedAt := time.Unix(1600000000, 0)
+	request := &DevRequest{GrantedAt: grantedAt.Unix()}
+	if request.GrantExpired(grantedAt.Add(DevRequestGrantTimeout)) {
+		t.Fatal("grant should not expire within timeout")
+	}
+	if !request.GrantExpired(grantedAt.Add(DevRequestGrantTimeout + time.Second)) {
+		t.Fatal("grant should expire after timeout")
+	}
+	if (&DevRequest{}).GrantExpired(grantedAt) {
+		t.Fatal("pending request should never expire")
+	}
+}
@@ -56,6 +56,8 @@ const (
 	SecretConfigKey           = "c"
 	SecretStateKey            = "s"
 	SecretDepKey              = "d"
+	SecretDevLeaseKey         = "l"
+	SecretDevRequestKey       = "q"
 
 	Helm           AppType = "helmGit"
 	HelmRepo       AppType = "helmRepo"
@@ -227,6 +229,12 @@ type ApplicationMeta struct {
 	// manage the dev status of the application
 	DevMeta ApplicationDevMeta `json:"dev_meta"`
 
+	// idle timeout of the DevMode
+	DevLease ApplicationDevLease `json:"dev_lease"`
+
+	// developers waiting for services in replace DevMode
+	DevRequest ApplicationDevRequest `json:"dev_request"`
+
 	// store all the config of application
 	Config *profile2.NocalHostAppConfigV2 `json:"config"`
 
@@ -315,6 +323,20 @@ func (a *ApplicationMeta) ReAssignmentBySecret(secret *corev1.Secret) error {
 		a.DevMeta = *devMeta
 	}
 
+	if bs, ok := secret.Data[SecretDevLeaseKey]; ok {
+		devLease := &ApplicationDevLease{}
+
+		_ = yaml.Unmarshal(bs, devLease)
+		a.DevLease = *devLease
+	}
+
+	if bs, ok := secret.Data[SecretDevRequestKey]; ok {
+		devRequest := &ApplicationDevRequest{}
+
+		_ = yaml.Unmarshal(bs, devRequest)
+		a.DevRequest = *devRequest
+	}
+
 	if bs, ok := secret.Data[SecretConfigKey]; ok {
 		config, _ := unmarshalConfigUnStrict(decompress(bs))
 		a.Config = config
@@ -645,6 +667,12 @@ func (a *ApplicationMeta) SvcDevStarting(name string, svcType base.SvcType, iden
 		return ErrAlreadyDev
 	}
 
+	if modeType.IsReplaceDevMode() {
+		if err := a.checkDevRequestGranted(name, svcType, identifier); err != nil {
+			return err
+		}
+	}
+
 	m[inDevStartingMark] = identifier
 	return a.Update()
 }
@@ -684,6 +712,10 @@ func (a *ApplicationMeta) SvcDevStartComplete(name string, svcType base.SvcType,
 	m[name] = identifier
 	inDevStartingMark := devStartMarkSign(name)
 	delete(m, inDevStartingMark)
+	if modeType.IsReplaceDevMode() {
+		// the request is satisfied
+		a.removeDevRequest(name, svcType, identifier)
+	}
 	return a.Update()
 }
 
@@ -704,6 +736,7 @@ func (a *ApplicationMeta) SvcDevEnd(name, identifier string, svcType base.SvcTyp
 
 	delete(m, inDevStartingMark)
 	delete(m, name)
+	delete(a.devLeaseOf(svcType), name)
 	return a.Update()
 }
 
@@ -788,6 +821,12 @@ func (a *ApplicationMeta) prepare() {
 
 	devMeta, _ := yaml.Marshal(&a.DevMeta)
 	a.Secret.Data[SecretDevMetaKey] = devMeta
+
+	devLease, _ := yaml.Marshal(&a.DevLease)
+	a.Secret.Data[SecretDevLeaseKey] = devLease
+
+	devRequest, _ := yaml.Marshal(&a.DevRequest)
+	a.Secret.Data[SecretDevRequestKey] = devRequest
 }
 
 func (a *ApplicationMeta) IsInstalled() bool {
@@ -835,7 +874,7 @@ func (a *ApplicationMeta) Uninstall(force bool) error {
 	}
 
 	if e := a.cleanUpDepConfigMap(); e != nil {
-		log.Error("Error while clean up dep config map %s ", e.Error())
+		log.Errorf("Error while clean up dep config map %s ", e.Error())
 	}
 
 	// remove hook
@@ -899,7 +938,7 @@ func (a *ApplicationMeta) cleanManifest() {
 	//goland:noinspection GoNilness
 	infos, err := resource.GetResourceInfo(op.ClientInner, true)
 	if err != nil {
-		log.Error("Error while loading manifest %s, err: %s ", a.Manifest, err)
+		log.Errorf("Error while loading manifest %s, err: %s ", a.Manifest, err)
 	}
 	for _, info := range infos {
 		utils.ShouldI(clientgoutils.DeleteResourceInfo(info), "Failed to delete resource "+info.Name)
@@ -954,6 +993,8 @@ func (a *ApplicationMeta) Delete() error {
 	a.PostDeleteManifest = ""
 	a.Manifest = ""
 	a.DevMeta = map[base.SvcType]map[string]string{}
+	a.DevLease = ApplicationDevLease{}
+	a.DevRequest = ApplicationDevRequest{}
 	a.UninstallBackOff = time.Now().Add(time.Second * 10).UnixNano()
 
 	return a.Update()
@@ -963,6 +1004,12 @@ func (a *ApplicationMeta) GetCurrentDevModeTypeOfWorkload(workloadName string, w
 	if a.CheckIfSvcDeveloping(workloadName, identifier, workloadType, profile2.DuplicateDevMode) != NONE {
 		return profile2.DuplicateDevMode
 	}
+	if a.CheckIfSvcDeveloping(workloadName, identifier, workloadType, profile2.EphemeralDevMode) != NONE {
+		return profile2.EphemeralDevMode
+	}
+	if a.CheckIfSvcDeveloping(workloadName, identifier, workloadType, profile2.LocalDevMode) != NONE {
+		return profile2.LocalDevMode
+	}
 	if a.CheckIfSvcDeveloping(workloadName, identifier, workloadType, profile2.ReplaceDevMode) != NONE {
 		return profile2.ReplaceDevMode
 	}
@@ -270,3 +270,21 @@ func GetAllApplicationMetas() []*appmeta.ApplicationMeta {
 	)
 	return metas
 }
+
+// RangeApplicationMetas calls f with all application metas watched, and the kubeconfig watching them
+func RangeApplicationMetas(f func(meta *appmeta.ApplicationMeta, configBytes []byte)) {
+	if supervisor == nil {
+		return
+	}
+
+	supervisor.deck.Range(
+		func(key, value interface{}) bool {
+			if asw, ok := value.(*applicationSecretWatcher); ok && asw != nil {
+				for _, meta := range asw.GetApplicationMetas() {
+					f(meta, asw.configBytes)
+				}
+			}
+			return true
+		},
+	)
+}
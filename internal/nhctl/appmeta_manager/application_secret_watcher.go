@@ -64,6 +64,7 @@ func (asw *applicationSecretWatcher) WatcherInfo() string {
 func (asw *applicationSecretWatcher) join(secret *v1.Secret) error {
 	devMetaBefore := appmeta.ApplicationDevMeta{}
 	devMetaCurrent := appmeta.ApplicationDevMeta{}
+	devRequestBefore := appmeta.ApplicationDevRequest{}
 
 	asw.lock.Lock()
 	defer asw.lock.Unlock()
@@ -76,12 +77,17 @@ func (asw *applicationSecretWatcher) join(secret *v1.Secret) error {
 
 	if before, ok := asw.applicationMetas[appName]; ok && before != nil {
 		devMetaBefore = before.GetApplicationDevMeta()
+		if before.DevRequest != nil {
+			devRequestBefore = before.DevRequest
+		}
 	}
 
 	devMetaCurrent = current.DevMeta
 	asw.applicationMetas[appName] = current
 
-	for _, event := range *devMetaBefore.Events(devMetaCurrent) {
+	events := *devMetaBefore.Events(devMetaCurrent)
+	events = append(events, devRequestBefore.Events(current.DevRequest)...)
+	for _, event := range events {
 		EventPush(
 			&ApplicationEventPack{
 				Event: event,
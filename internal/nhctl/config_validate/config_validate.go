@@ -18,11 +18,14 @@ import (
 	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/nocalhost"
 	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing/ignore"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"os"
+	"path"
 	"reflect"
 	"strings"
+	"time"
 )
 
 var (
@@ -30,12 +33,17 @@ var (
 	WorkLoads    = "WorkLoads"
 	SyncType     = "SyncType"
 	SyncMode     = "SyncMode"
+	SyncEngine   = "SyncEngine"
+	IgnoreFrom   = "IgnoreFrom"
+	ReversePath  = "ReversePath"
+	Compression  = "SyncCompression"
 	Quantity     = "Quantity"
 	StorageClass = "StorageClass"
 	PortForward  = "PortForward"
 	Port         = "Port"
 	Container    = "Container"
 	Language     = "Language"
+	Duration     = "Duration"
 
 	SUPPORT_SC = "NOCALHOST_SUPPORT_SC"
 	CONTAINERS = "NOCALHOST_CONTAINERS"
@@ -47,12 +55,17 @@ func init() {
 	_ = validate.RegisterValidationWithErrorMsg(WorkLoads, IsSupportsWorkLoads)
 	_ = validate.RegisterValidationWithErrorMsg(SyncType, IsSyncType)
 	_ = validate.RegisterValidationWithErrorMsg(SyncMode, IsSyncMode)
+	_ = validate.RegisterValidationWithErrorMsg(SyncEngine, IsSyncEngine)
+	_ = validate.RegisterValidationWithErrorMsg(IgnoreFrom, IsIgnoreFrom)
+	_ = validate.RegisterValidationWithErrorMsg(ReversePath, IsReversePath)
+	_ = validate.RegisterValidationWithErrorMsg(Compression, IsSyncCompression)
 	_ = validate.RegisterValidationWithErrorMsg(Quantity, IsQuantity)
 	_ = validate.RegisterValidationWithErrorMsg(StorageClass, StorageClassSupported)
 	_ = validate.RegisterValidationWithErrorMsg(PortForward, PortForwardCheck)
 	_ = validate.RegisterValidationWithErrorMsg(Port, PortCheck)
 	_ = validate.RegisterValidationWithErrorMsg(Container, ContainerCheck)
 	_ = validate.RegisterValidationWithErrorMsg(Language, LanguageCheck)
+	_ = validate.RegisterValidationWithErrorMsg(Duration, DurationCheck)
 
 	validate.RegisterTagNameFunc(
 		func(field reflect.StructField) string {
@@ -128,6 +141,59 @@ func IsSyncType(fl validator.FieldLevel) string {
 	)
 }
 
+func IsSyncEngine(fl validator.FieldLevel) string {
+	val := fl.Field().String()
+
+	return hintIfNoPass(
+		val == "" ||
+			val == _const.SyncthingSyncEngine ||
+			val == _const.ExecSyncEngine,
+		func() string {
+			return fmt.Sprintf("Must be %s or %s", _const.SyncthingSyncEngine, _const.ExecSyncEngine)
+		},
+	)
+}
+
+func IsIgnoreFrom(fl validator.FieldLevel) string {
+	val := fl.Field().String()
+
+	return hintIfNoPass(
+		val == ignore.GitIgnoreFile || val == ignore.DockerIgnoreFile,
+		func() string {
+			return fmt.Sprintf("Must be %s or %s", ignore.GitIgnoreFile, ignore.DockerIgnoreFile)
+		},
+	)
+}
+
+// IsReversePath reverse path must be inside workDir
+func IsReversePath(fl validator.FieldLevel) string {
+	val := path.Clean(fl.Field().String())
+
+	return hintIfNoPass(
+		val != "." && !path.IsAbs(val) && val != ".." && !strings.HasPrefix(val, "../"),
+		func() string {
+			return "Must be a path relative to workDir, and inside workDir"
+		},
+	)
+}
+
+func IsSyncCompression(fl validator.FieldLevel) string {
+	val := fl.Field().String()
+
+	return hintIfNoPass(
+		val == "" ||
+			val == _const.SyncCompressionAlways ||
+			val == _const.SyncCompressionMetadata ||
+			val == _const.SyncCompressionNever,
+		func() string {
+			return fmt.Sprintf(
+				"Must be %s, %s or %s",
+				_const.SyncCompressionAlways, _const.SyncCompressionMetadata, _const.SyncCompressionNever,
+			)
+		},
+	)
+}
+
 func IsSyncMode(fl validator.FieldLevel) string {
 	val := fl.Field().String()
 
@@ -199,6 +265,22 @@ func LanguageCheck(fl validator.FieldLevel) string {
 	)
 }
 
+func DurationCheck(fl validator.FieldLevel) string {
+	val := fl.Field().String()
+
+	if val == "" {
+		return ""
+	}
+
+	d, err := time.ParseDuration(val)
+	return hintIfNoPass(
+		err == nil && d > 0,
+		func() string {
+			return fmt.Sprintf("%s is not a valid positive duration, such as 30m or 4h", val)
+		},
+	)
+}
+
 func ContainerCheck(fl validator.FieldLevel) string {
 	val := fl.Field().String()
 
@@ -16,6 +16,7 @@ const (
 	DefaultNewFilePermission   = 0755
 	DefaultBinDirName          = "bin"
 	DefaultBinSyncThingDirName = "syncthing"
+	DefaultLocalDevDirName     = "local-dev"
 	DefaultLogDirName          = "logs"
 	DefaultLogFileName         = "nhctl.log"
 
@@ -27,6 +28,7 @@ const (
 	NocalhostDefaultDevSidecarName   = "nocalhost-sidecar"
 
 	OriginWorkloadDefinition    = "dev.nocalhost/origin-workload-definition"
+	EphemeralDevModeAnnotation  = "dev.nocalhost/ephemeral-dev-identifier"
 	OriginProbeDefinition       = "dev.nocalhost/origin-probe-definition"
 	DevModeCount                = "dev.nocalhost/dev-mode-count"
 	AppManagedByLabel           = "app.kubernetes.io/managed-by"
@@ -75,6 +77,12 @@ const (
 
 	HPAOriginalMaxReplicasKey = "nocalhost.dev.hpa.origin.max.replicas"
 	HPAOriginalMinReplicasKey = "nocalhost.dev.hpa.origin.min.replicas"
+	// HPAOriginDefinition specs of hpa targeting the workload, recorded in workload's annotations while in DevMode
+	HPAOriginDefinition = "dev.nocalhost/origin-hpa-definition"
+	// DevModeOrdinal ordinal of the StatefulSet's pod in DevMode, other pods are untouched
+	DevModeOrdinal = "dev.nocalhost/dev-ordinal"
+	// DevSyncEngineAnnotation sync engine of the dev pod
+	DevSyncEngineAnnotation = "dev.nocalhost/sync-engine"
 
 	// sycnthing
 
@@ -82,11 +90,29 @@ const (
 	DefaultSyncType       = "sendReceive" // default sync mode
 	SendOnlySyncType      = "sendonly"
 	SendOnlySyncTypeAlias = "send"
+	ReceiveOnlySyncType   = "receiveonly" // only for reverse sync folders
+
+	SyncCompressionAlways   = "always"
+	SyncCompressionMetadata = "metadata" // default compression of file sync
+	SyncCompressionNever    = "never"
+
+	// protocols of port-forward
+	PortForwardTCP = "tcp"
+	PortForwardUDP = "udp"
+
+	// ReversePortForwardRole role of port-forward from dev container back to local
+	ReversePortForwardRole = "REVERSE"
+	// SidecarSshPort port of ssh server in ssh version of sidecar, reverse port-forward is tunnelled by it
+	SidecarSshPort = 50022
 
 	// sync mode
 	GitIgnoreMode = "gitIgnore"
 	PatternMode   = "pattern"
 
+	// sync engine
+	SyncthingSyncEngine = "syncthing" // default sync engine
+	ExecSyncEngine      = "exec"      // stream changes through kubernetes exec api, no sidecar needed
+
 	banner = `
 ****************************************
 *      Nocalhost DevMode Terminal      *
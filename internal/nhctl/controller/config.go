@@ -6,6 +6,7 @@
 package controller
 
 import (
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/profile"
 
 	corev1 "k8s.io/api/core/v1"
@@ -81,3 +82,15 @@ func (c *Controller) GetImagePullPolicy(container string) corev1.PullPolicy {
 	}
 	return corev1.PullIfNotPresent
 }
+
+// GetSyncEngine engine syncing files to dev container, syncthing by default
+func (c *Controller) GetSyncEngine(container string) string {
+	if c.syncEngine != "" {
+		return c.syncEngine
+	}
+	devConfig := c.config.GetContainerDevConfigOrDefault(container)
+	if devConfig != nil && devConfig.Sync != nil && devConfig.Sync.Engine != "" {
+		return devConfig.Sync.Engine
+	}
+	return _const.SyncthingSyncEngine
+}
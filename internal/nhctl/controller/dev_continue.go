@@ -0,0 +1,83 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/pkg/nhctl/log"
+)
+
+// nativeSidecarMinVersion init containers with restartPolicy Always run as sidecars since kubernetes 1.29
+var nativeSidecarMinVersion = version.MustParseGeneric("v1.29.0")
+
+// ContinueInitDevContainer lets the pod blocked by dev init container proceed to its main containers
+func (c *Controller) ContinueInitDevContainer() error {
+	podName, err := c.GetDevModePodName()
+	if err != nil {
+		return err
+	}
+
+	pod, err := c.Client.GetPod(podName)
+	if err != nil {
+		return err
+	}
+
+	devContainerName, ok := pod.Annotations[_const.NocalhostDevContainerAnnotations]
+	if !ok {
+		devContainerName = _const.NocalhostDefaultDevContainerName
+	}
+
+	found := false
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == devContainerName {
+			found = true
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("Dev container of pod %s is not an init container", podName))
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == devContainerName && status.State.Terminated != nil {
+			return errors.New(fmt.Sprintf("Pod %s has already continued", podName))
+		}
+	}
+
+	log.Infof("Continuing pod %s...", podName)
+	return c.Client.Exec(podName, devContainerName, []string{"touch", InitDevContinueFile})
+}
+
+// fallbackSyncEngineOfInitContainer syncthing sidecar of dev init container runs as a native sidecar,
+// older clusters ignore its restartPolicy and the pod would be blocked by the sidecar forever,
+// so files are synced by exec engine, which needs no sidecar, instead
+func (c *Controller) fallbackSyncEngineOfInitContainer(container string) {
+	if c.GetSyncEngine(container) == _const.ExecSyncEngine || c.Client == nil {
+		return
+	}
+	info, err := c.Client.ClientSet.Discovery().ServerVersion()
+	if err != nil {
+		log.WarnE(errors.Wrap(err, ""), "Failed to get server version, assuming native sidecars are supported")
+		return
+	}
+	if !nativeSidecarSupported(info.GitVersion) {
+		log.Warnf(
+			"Native sidecars are not supported by kubernetes %s(%s+ required), files are synced by engine %s",
+			info.GitVersion, nativeSidecarMinVersion, _const.ExecSyncEngine,
+		)
+		c.syncEngine = _const.ExecSyncEngine
+	}
+}
+
+// nativeSidecarSupported unknown versions are considered as supported
+func nativeSidecarSupported(gitVersion string) bool {
+	v, err := version.ParseGeneric(gitVersion)
+	if err != nil {
+		return true
+	}
+	return v.AtLeast(nativeSidecarMinVersion)
+}
@@ -0,0 +1,78 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"nocalhost/pkg/nhctl/log"
+	"time"
+)
+
+// DevLeaseRenewInterval interval of renewing lease while a terminal session is open
+const DevLeaseRenewInterval = time.Minute
+
+// GetDevModeTTL idle timeout of DevMode, container's config first, then the application's.
+// 0 means never expire
+func (c *Controller) GetDevModeTTL(container string) (time.Duration, error) {
+	ttl := ""
+	if devConfig := c.Config().GetContainerDevConfigOrDefault(container); devConfig != nil {
+		ttl = devConfig.DevModeTTL
+	}
+	if ttl == "" && c.GetAppConfig() != nil {
+		ttl = c.GetAppConfig().ApplicationConfig.DevModeTTL
+	}
+	if ttl == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Invalid devModeTTL %s", ttl))
+	}
+	return d, nil
+}
+
+// StartDevLease records the lease of DevMode if devModeTTL is configured,
+// DevMode will be ended by daemon once it is idle for devModeTTL
+func (c *Controller) StartDevLease(container string) error {
+	ttl, err := c.GetDevModeTTL(container)
+	if err != nil {
+		return err
+	}
+	if err = c.AppMeta.SvcDevLeaseStart(c.Name, c.Type, c.Identifier, c.DevModeType, ttl); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		log.Infof("DevMode will be ended after being idle for %s", ttl.String())
+	}
+	return nil
+}
+
+// RenewDevLease marks DevMode as active
+func (c *Controller) RenewDevLease() error {
+	renewed, err := c.AppMeta.SvcDevLeaseRenew(c.Name, c.Type, c.Identifier, c.DevModeType)
+	if renewed {
+		log.Debugf("Lease of %s %s renewed", c.Type, c.Name)
+	}
+	return err
+}
+
+// keepDevLeaseAlive renews lease periodically until stop is closed
+func (c *Controller) keepDevLeaseAlive(stop <-chan struct{}) {
+	ticker := time.NewTicker(DevLeaseRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.RenewDevLease(); err != nil {
+				log.Debugf("Failed to renew lease of %s: %s", c.Name, err.Error())
+			}
+		}
+	}
+}
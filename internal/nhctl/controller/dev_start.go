@@ -15,15 +15,26 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	//"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/model"
 	"nocalhost/internal/nhctl/nocalhost"
 	"nocalhost/internal/nhctl/profile"
 	secret_config "nocalhost/internal/nhctl/syncthing/secret-config"
 	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
+// ExtraSyncRemoteHome workDir of extra dev containers are mounted under this dir in sidecar
+const ExtraSyncRemoteHome = "/var/nocalhost-sync"
+
+// InitDevContinueFile dev init container exits once the file is created by `nhctl dev continue`
+const InitDevContinueFile = "/tmp/.nocalhost-dev-continue"
+
 func (c *Controller) GetDevContainerEnv(container string) *ContainerDevEnv {
 	// Find service env
 	devEnv := make([]*profile.Env, 0)
@@ -96,6 +107,9 @@ func (c *Controller) GetSyncThingSecretName() string {
 	if c.DevModeType.IsDuplicateDevMode() {
 		return strings.Join([]string{c.Name, c.Type.String(), secret_config.SecretName, "dup", c.Identifier}, "-")
 	}
+	if c.DevModeType.IsEphemeralDevMode() {
+		return strings.Join([]string{c.Name, c.Type.String(), secret_config.SecretName, "eph", c.Identifier}, "-")
+	}
 	return strings.Join([]string{c.Name, c.Type.String(), secret_config.SecretName}, "-")
 }
 
@@ -166,15 +180,23 @@ func (c *Controller) generateSyncVolumesAndMounts(duplicateDevMode bool) ([]core
 // If PVC exists, use it directly
 // If PVC not exists, try to create one
 // If PVC failed to create, the whole process of entering DevMode will fail
-func (c *Controller) genWorkDirAndPVAndMounts(container, storageClass string, ignoreWorkDir, duplicateDevMode bool) (
-	[]corev1.Volume, []corev1.VolumeMount, error) {
+// Extra dev containers use volumes named with container suffix, to avoid conflicting with primary dev container
+func (c *Controller) genWorkDirAndPVAndMounts(container, storageClass string, ignoreWorkDir, duplicateDevMode,
+	extraDevContainer bool) ([]corev1.Volume, []corev1.VolumeMount, error) {
 
 	volumes := make([]corev1.Volume, 0)
 	volumeMounts := make([]corev1.VolumeMount, 0)
 	workDir := c.GetWorkDir(container)
 
+	volumeName := func(name string) string {
+		if extraDevContainer {
+			return name + "-" + container
+		}
+		return name
+	}
+
 	workDirVol := corev1.Volume{
-		Name: "nocalhost-shared-volume",
+		Name: volumeName("nocalhost-shared-volume"),
 		VolumeSource: corev1.VolumeSource{
 			EmptyDir: &corev1.EmptyDirVolumeSource{},
 		},
@@ -203,6 +225,9 @@ func (c *Controller) genWorkDirAndPVAndMounts(container, storageClass string, ig
 			labels[_const.ServiceTypeLabel] = string(c.Type)
 		}
 		labels[_const.PersistentVolumeDirLabel] = utils.Sha1ToString(persistentVolume.Path)
+		if extraDevContainer {
+			labels[_const.PersistentVolumeDirLabel] = utils.Sha1ToString(container + ":" + persistentVolume.Path)
+		}
 		claims, err := c.Client.GetPvcByLabels(labels)
 		if err != nil {
 			log.WarnE(err, fmt.Sprintf("Fail to get a pvc for %s", persistentVolume.Path))
@@ -254,7 +279,7 @@ func (c *Controller) genWorkDirAndPVAndMounts(container, storageClass string, ig
 			workDirResideInPersistVolumeDirs = true
 		}
 
-		persistVolName := fmt.Sprintf("persist-volume-%d", index)
+		persistVolName := volumeName(fmt.Sprintf("persist-volume-%d", index))
 		persistentVol := corev1.Volume{
 			Name: persistVolName,
 			VolumeSource: corev1.VolumeSource{
@@ -310,6 +335,18 @@ func (c *Controller) createPvcForPersistentVolumeDir(
 		capacity = "10Gi"
 	}
 
+	if c.dryRun != nil {
+		var sc *string
+		if storageClass != "" {
+			sc = &storageClass
+		}
+		if pvc, err = clientgoutils.GeneratePVC(pvcName, labels, annotations, capacity, sc); err != nil {
+			return nil, err
+		}
+		c.dryRun.PVCs = append(c.dryRun.PVCs, pvc)
+		return pvc, nil
+	}
+
 	if storageClass == "" {
 		pvc, err = c.Client.CreatePVC(pvcName, labels, annotations, capacity, nil)
 	} else {
@@ -493,6 +530,16 @@ func findDevPodName(podList ...corev1.Pod) (string, error) {
 					resultPodList = append(resultPodList, pod)
 				}
 			}
+			for _, container := range pod.Spec.EphemeralContainers {
+				if container.Name == _const.DefaultNocalhostSideCarName {
+					resultPodList = append(resultPodList, pod)
+				}
+			}
+			for _, container := range pod.Spec.InitContainers {
+				if container.Name == _const.DefaultNocalhostSideCarName {
+					resultPodList = append(resultPodList, pod)
+				}
+			}
 		}
 	}
 
@@ -519,7 +566,25 @@ func findDevPodName(podList ...corev1.Pod) (string, error) {
 				count++
 			}
 		}
-		if count == 2 {
+		// dev init container is never ready until it exits, so running means ready,
+		// and it has exited successfully after `nhctl dev continue`
+		for _, status := range latestPod.Status.InitContainerStatuses {
+			if status.Name != devContainerName && status.Name != _const.DefaultNocalhostSideCarName {
+				continue
+			}
+			if status.State.Running != nil ||
+				(status.Name == devContainerName && status.State.Terminated != nil && status.State.Terminated.ExitCode == 0) {
+				count++
+			}
+		}
+		// ephemeral containers have no probes, so running means ready
+		for _, status := range latestPod.Status.EphemeralContainerStatuses {
+			if (status.Name == devContainerName || status.Name == _const.DefaultNocalhostSideCarName) &&
+				status.State.Running != nil {
+				count++
+			}
+		}
+		if count == devPodContainerCount(latestPod.Annotations) {
 			return latestPod.Name, nil
 		}
 
@@ -528,6 +593,14 @@ func findDevPodName(podList ...corev1.Pod) (string, error) {
 	return "", errors.New("dev container not found")
 }
 
+// devPodContainerCount number of nocalhost containers in dev pod
+func devPodContainerCount(annotations map[string]string) int {
+	if annotations[_const.DevSyncEngineAnnotation] == _const.ExecSyncEngine {
+		return 1
+	}
+	return 2
+}
+
 // containerStatusForDevPod getting status msg for pod
 // return true if current pod is dev pod
 func containerStatusForDevPod(pod *corev1.Pod, consumeFun func(status string, err error)) bool {
@@ -540,7 +613,8 @@ func containerStatusForDevPod(pod *corev1.Pod, consumeFun func(status string, er
 		devContainerName = _const.NocalhostDefaultDevContainerName
 	}
 
-	// dev container must have 2 containers: nocalhost-dev & nocalhost-sidecar
+	// dev container must have 2 containers: nocalhost-dev & nocalhost-sidecar,
+	// sidecar is absent if files are synced by exec engine
 
 	containerFoundCounter := 0
 	for _, container := range pod.Spec.Containers {
@@ -548,8 +622,18 @@ func containerStatusForDevPod(pod *corev1.Pod, consumeFun func(status string, er
 			containerFoundCounter++
 		}
 	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == devContainerName || container.Name == _const.NocalhostDefaultDevSidecarName {
+			containerFoundCounter++
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == devContainerName || container.Name == _const.NocalhostDefaultDevSidecarName {
+			containerFoundCounter++
+		}
+	}
 
-	if containerFoundCounter < 2 {
+	if containerFoundCounter < devPodContainerCount(pod.Annotations) {
 		return false
 	}
 
@@ -565,7 +649,11 @@ func containerStatusForDevPod(pod *corev1.Pod, consumeFun func(status string, er
 		}
 	}
 
-	for _, status := range pod.Status.ContainerStatuses {
+	statuses := make([]corev1.ContainerStatus, 0)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	for _, status := range statuses {
 		if status.Name != devContainerName && status.Name != _const.NocalhostDefaultDevSidecarName {
 			continue
 		}
@@ -608,7 +696,7 @@ func (c *Controller) sidecarContainerSSHUsed(DevLanguage string, DevImage string
 }
 
 func (c *Controller) genContainersAndVolumes(podSpec *corev1.PodSpec,
-	containerName, devImage, storageClass string, duplicateDevMode bool) (*corev1.Container,
+	containerName, devImage, storageClass string, extraContainers []string, duplicateDevMode bool) (*corev1.Container,
 	*corev1.Container, []corev1.Volume, error) {
 
 	devContainer, err := findDevContainerInPodSpec(podSpec, containerName)
@@ -631,13 +719,34 @@ func (c *Controller) genContainersAndVolumes(podSpec *corev1.PodSpec,
 		}
 	}
 
+	if isInitContainer(podSpec, devContainer) {
+		c.fallbackSyncEngineOfInitContainer(containerName)
+	}
+
+	// exec engine streams files into dev container directly, no syncthing sidecar needed
+	execSyncEngine := c.GetSyncEngine(containerName) == _const.ExecSyncEngine
+	if execSyncEngine && len(extraContainers) > 0 {
+		return nil, nil, nil, errors.New("Extra dev containers are not supported by sync engine exec")
+	}
+	if execSyncEngine && len(c.GetSyncMappings(containerName)) > 0 {
+		return nil, nil, nil, errors.New("Sync mappings are not supported by sync engine exec")
+	}
+	if execSyncEngine && len(c.GetReversePaths(containerName)) > 0 {
+		return nil, nil, nil, errors.New("Reverse paths are not supported by sync engine exec")
+	}
+	if execSyncEngine && c.reversePortForwardUsed(containerName) {
+		return nil, nil, nil, errors.New("Reverse port-forwards are not supported by sync engine exec")
+	}
+
 	// Set volumes
-	syncthingVolumes, syncthingVolumeMounts := c.generateSyncVolumesAndMounts(duplicateDevMode)
-	devModeVolumes = append(devModeVolumes, syncthingVolumes...)
-	devModeMounts = append(devModeMounts, syncthingVolumeMounts...)
+	if !execSyncEngine {
+		syncthingVolumes, syncthingVolumeMounts := c.generateSyncVolumesAndMounts(duplicateDevMode)
+		devModeVolumes = append(devModeVolumes, syncthingVolumes...)
+		devModeMounts = append(devModeMounts, syncthingVolumeMounts...)
+	}
 
 	workDirAndPersistVolumes, workDirAndPersistVolumeMounts, err := c.genWorkDirAndPVAndMounts(
-		containerName, storageClass, workDirAlreadyMounted, duplicateDevMode,
+		containerName, storageClass, workDirAlreadyMounted, duplicateDevMode, false,
 	)
 	if err != nil {
 		return nil, nil, nil, err
@@ -651,11 +760,19 @@ func (c *Controller) genContainersAndVolumes(podSpec *corev1.PodSpec,
 	}
 
 	sideCarContainer := generateSideCarContainer(c.GetDevSidecarImage(containerName), workDir,
-		c.sidecarContainerSSHUsed(c.GetDevSidecarLanguage(containerName), devImage))
+		c.sidecarContainerSSHUsed(c.GetDevSidecarLanguage(containerName), devImage) ||
+			c.reversePortForwardUsed(containerName))
 
 	devContainer.Image = devImage
 	devContainer.Name = c.GetDevContainerName(containerName)
 	devContainer.Command = []string{"/bin/sh", "-c", "tail -f /dev/null"}
+	if isInitContainer(podSpec, devContainer) {
+		// block the pod in init stage until `nhctl dev continue`
+		devContainer.Command = []string{
+			"/bin/sh", "-c", fmt.Sprintf("while [ ! -f %s ]; do sleep 1; done", InitDevContinueFile),
+		}
+		devContainer.Args = nil
+	}
 	devContainer.WorkingDir = workDir
 
 	// set image pull policy
@@ -708,11 +825,130 @@ func (c *Controller) genContainersAndVolumes(podSpec *corev1.PodSpec,
 	}
 	rq, _ := convertResourceQuota(r)
 	sideCarContainer.Resources = *rq
+
+	if execSyncEngine {
+		return devContainer, nil, devModeVolumes, nil
+	}
+
+	devModeVolumes = c.genSyncMappingVolumes(containerName, devContainer, &sideCarContainer, devModeVolumes)
+
+	for _, extraContainer := range extraContainers {
+		extraVolumes, err := c.genExtraDevContainer(
+			podSpec, extraContainer, &sideCarContainer, storageClass, duplicateDevMode,
+		)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		devModeVolumes = append(devModeVolumes, extraVolumes...)
+	}
 	return devContainer, &sideCarContainer, devModeVolumes, nil
 }
 
+// genExtraDevContainer turns an extra container into dev container in place.
+// Its workDir is also mounted to sidecar under ExtraSyncRemoteHome,
+// so one syncthing sidecar serves all dev containers of the pod
+func (c *Controller) genExtraDevContainer(podSpec *corev1.PodSpec, containerName string,
+	sideCarContainer *corev1.Container, storageClass string, duplicateDevMode bool) ([]corev1.Volume, error) {
+
+	if containerName == "" {
+		return nil, errors.New("Extra dev container's name can not be empty")
+	}
+
+	devContainer, err := findDevContainerInPodSpec(podSpec, containerName)
+	if err != nil {
+		return nil, err
+	}
+	if isInitContainer(podSpec, devContainer) {
+		return nil, errors.New(fmt.Sprintf("Init container %s can not be an extra dev container", containerName))
+	}
+
+	workDir := c.GetWorkDir(containerName)
+	var workDirAlreadyMounted bool
+	for _, mount := range devContainer.VolumeMounts {
+		if mount.MountPath == workDir {
+			workDirAlreadyMounted = true
+		}
+	}
+
+	volumes, volumeMounts, err := c.genWorkDirAndPVAndMounts(
+		containerName, storageClass, workDirAlreadyMounted, duplicateDevMode, true,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	devImage := c.GetDevImage(containerName)
+	if devImage != "" {
+		devContainer.Image = devImage
+	}
+	devContainer.Name = c.getExtraDevContainerName(containerName)
+	devContainer.Command = []string{"/bin/sh", "-c", "tail -f /dev/null"}
+	devContainer.Args = nil
+	devContainer.WorkingDir = workDir
+	devContainer.ImagePullPolicy = c.GetImagePullPolicy(containerName)
+	for _, v := range c.GetDevContainerEnv(containerName).DevEnv {
+		devContainer.Env = append(devContainer.Env, corev1.EnvVar{Name: v.Name, Value: v.Value})
+	}
+	devContainer.VolumeMounts = append(devContainer.VolumeMounts, volumeMounts...)
+	if requirements := c.genResourceReq(containerName); requirements != nil {
+		devContainer.Resources = *requirements
+	}
+
+	// mount the volume which workDir resides in to sidecar
+	var workDirMount *corev1.VolumeMount
+	for i, mount := range devContainer.VolumeMounts {
+		if workDir != mount.MountPath && !strings.HasPrefix(workDir, strings.TrimSuffix(mount.MountPath, "/")+"/") {
+			continue
+		}
+		if workDirMount == nil || len(mount.MountPath) > len(workDirMount.MountPath) {
+			workDirMount = &devContainer.VolumeMounts[i]
+		}
+	}
+	if workDirMount == nil {
+		return nil, errors.New(fmt.Sprintf("No volume found for workDir %s of %s", workDir, containerName))
+	}
+	rel, _ := filepath.Rel(workDirMount.MountPath, workDir)
+	sideCarContainer.VolumeMounts = append(
+		sideCarContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      workDirMount.Name,
+			MountPath: ExtraDevContainerRemoteSyncDir(containerName),
+			SubPath:   strings.TrimPrefix(filepath.ToSlash(filepath.Join(workDirMount.SubPath, rel)), "."),
+		},
+	)
+	return volumes, nil
+}
+
+// extraContainerNames sorted, so that the generated pod spec is stable
+func extraContainerNames(ops *model.DevStartOptions) []string {
+	names := make([]string, 0, len(ops.ExtraContainers))
+	for name := range ops.ExtraContainers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getExtraDevContainerName extra dev container keeps its own name unless devContainerName is specified,
+// so that it won't conflict with the primary dev container
+func (c *Controller) getExtraDevContainerName(container string) string {
+	if devConfig := c.Config().GetContainerDevConfig(container); devConfig != nil && devConfig.DevContainerName != "" {
+		return devConfig.DevContainerName
+	}
+	return container
+}
+
+// ExtraDevContainerRemoteSyncDir the dir which extra dev container's workDir mounted to in sidecar
+func ExtraDevContainerRemoteSyncDir(container string) string {
+	return path.Join(ExtraSyncRemoteHome, container)
+}
+
 func patchDevContainerToPodSpec(podSpec *corev1.PodSpec, containerName string, devContainer,
 	sidecarContainer *corev1.Container, devModeVolumes []corev1.Volume) {
+	if isInitContainer(podSpec, devContainer) {
+		patchInitDevContainerToPodSpec(podSpec, devContainer, sidecarContainer, devModeVolumes)
+		return
+	}
+
 	if containerName != "" {
 		for index, c := range podSpec.Containers {
 			if c.Name == containerName {
@@ -737,7 +973,35 @@ func patchDevContainerToPodSpec(podSpec *corev1.PodSpec, containerName string, d
 		podSpec.Containers[i].StartupProbe = nil
 	}
 
-	podSpec.Containers = append(podSpec.Containers, *sidecarContainer)
+	if sidecarContainer != nil {
+		podSpec.Containers = append(podSpec.Containers, *sidecarContainer)
+	}
+}
+
+// patchInitDevContainerToPodSpec main containers won't start until the dev init container exits,
+// so sidecar runs as a native sidecar(init container always restarting) before the dev init container
+func patchInitDevContainerToPodSpec(podSpec *corev1.PodSpec, devContainer, sidecarContainer *corev1.Container,
+	devModeVolumes []corev1.Volume) {
+	podSpec.Volumes = append(podSpec.Volumes, devModeVolumes...)
+
+	devContainer.LivenessProbe = nil
+	devContainer.ReadinessProbe = nil
+	devContainer.StartupProbe = nil
+
+	if sidecarContainer == nil {
+		return
+	}
+	always := corev1.ContainerRestartPolicyAlways
+	sidecarContainer.RestartPolicy = &always
+
+	initContainers := make([]corev1.Container, 0, len(podSpec.InitContainers)+1)
+	for _, c := range podSpec.InitContainers {
+		if c.Name == devContainer.Name {
+			initContainers = append(initContainers, *sidecarContainer)
+		}
+		initContainers = append(initContainers, c)
+	}
+	podSpec.InitContainers = initContainers
 }
 
 // IsResourcesLimitTooLow
@@ -0,0 +1,204 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/profile"
+	"strings"
+	"testing"
+)
+
+func TestPatchInitDevContainerToPodSpec(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "wait-db"}, {Name: "migrate"}},
+		Containers:     []corev1.Container{{Name: "app"}},
+	}
+
+	devContainer, err := findDevContainerInPodSpec(podSpec, "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isInitContainer(podSpec, devContainer) {
+		t.Fatal("migrate should be an init container")
+	}
+	devContainer.Name = "nocalhost-dev"
+
+	sidecar := &corev1.Container{Name: "nocalhost-sidecar"}
+	patchDevContainerToPodSpec(podSpec, "migrate", devContainer, sidecar, nil)
+
+	if len(podSpec.Containers) != 1 {
+		t.Fatalf("main containers should be untouched, got %v", podSpec.Containers)
+	}
+	names := make([]string, 0)
+	for _, c := range podSpec.InitContainers {
+		names = append(names, c.Name)
+	}
+	if len(names) != 3 || names[1] != "nocalhost-sidecar" || names[2] != "nocalhost-dev" {
+		t.Fatalf("sidecar should run before dev init container, got %v", names)
+	}
+	if p := podSpec.InitContainers[1].RestartPolicy; p == nil || *p != corev1.ContainerRestartPolicyAlways {
+		t.Fatal("sidecar should be a native sidecar")
+	}
+}
+
+func TestFindDevPodNameOfInitContainer(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviews-0"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: _const.DefaultNocalhostSideCarName}, {Name: _const.NocalhostDefaultDevContainerName},
+			},
+			Containers: []corev1.Container{{Name: "app"}},
+		},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: _const.DefaultNocalhostSideCarName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				{Name: _const.NocalhostDefaultDevContainerName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+	if name, err := findDevPodName(pod); err != nil || name != pod.Name {
+		t.Fatalf("dev pod should be found before continue, got %s, %v", name, err)
+	}
+
+	// after `nhctl dev continue`
+	pod.Status.InitContainerStatuses[1].State = corev1.ContainerState{
+		Terminated: &corev1.ContainerStateTerminated{ExitCode: 0},
+	}
+	if name, err := findDevPodName(pod); err != nil || name != pod.Name {
+		t.Fatalf("dev pod should be found after continue, got %s, %v", name, err)
+	}
+
+	pod.Status.InitContainerStatuses[1].State.Terminated.ExitCode = 1
+	if _, err := findDevPodName(pod); err == nil {
+		t.Fatal("dev pod should not be ready if dev init container failed")
+	}
+}
+
+func TestNativeSidecarSupported(t *testing.T) {
+	for v, expected := range map[string]bool{
+		"v1.28.3":              false,
+		"v1.22.17-eks-0a21954": false,
+		"v1.29.0":              true,
+		"v1.30.1+k3s1":         true,
+		"unknown":              true,
+	} {
+		if nativeSidecarSupported(v) != expected {
+			t.Fatalf("native sidecar of %s should be %v", v, expected)
+		}
+	}
+}
+
+func TestReversePortForwardRejectedByExecEngine(t *testing.T) {
+	c := &Controller{
+		Name: "reviews",
+		config: &profile.ServiceConfigV2{
+			ContainerConfigs: []*profile.ContainerConfig{
+				{
+					Name: "reviews",
+					Dev: &profile.ContainerDevConfig{
+						ReversePortForward: []string{"9000:9000"},
+						Sync:               &profile.SyncConfig{Engine: _const.ExecSyncEngine},
+					},
+				},
+			},
+		},
+	}
+	if !c.reversePortForwardUsed("reviews") {
+		t.Fatal("reverse port-forward should be used")
+	}
+	podSpec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "reviews"}}}
+	if _, _, _, err := c.genContainersAndVolumes(podSpec, "reviews", "", "", nil, false); err == nil {
+		t.Fatal("reverse port-forward should be rejected by sync engine exec, no ssh server of sidecar tunnels it")
+	}
+}
+
+func TestGenExtraDevContainers(t *testing.T) {
+	c := &Controller{
+		Name: "reviews",
+		config: &profile.ServiceConfigV2{
+			ContainerConfigs: []*profile.ContainerConfig{
+				{Name: "app", Dev: &profile.ContainerDevConfig{Image: "app-dev:v1", WorkDir: "/home/app"}},
+				{Name: "proxy", Dev: &profile.ContainerDevConfig{Image: "proxy-dev:v1", WorkDir: "/home/proxy"}},
+				{
+					Name: "worker",
+					Dev: &profile.ContainerDevConfig{
+						Image: "worker-dev:v1", WorkDir: "/home/worker", DevContainerName: "worker-dev",
+					},
+				},
+			},
+		},
+	}
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "app", Image: "app:v1"}, {Name: "proxy", Image: "proxy:v1"}, {Name: "worker", Image: "worker:v1"},
+		},
+	}
+
+	devContainer, sidecar, volumes, err := c.genContainersAndVolumes(
+		podSpec, "app", "app-dev:v1", "", []string{"proxy", "worker"}, false,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if devContainer.Name != _const.NocalhostDefaultDevContainerName || devContainer.Image != "app-dev:v1" ||
+		devContainer.WorkingDir != "/home/app" {
+		t.Fatalf("unexpected main dev container %s %s %s", devContainer.Name, devContainer.Image, devContainer.WorkingDir)
+	}
+	for _, m := range devContainer.VolumeMounts {
+		if strings.HasSuffix(m.Name, "-proxy") || strings.HasSuffix(m.Name, "-worker") {
+			t.Fatalf("volume %s of extra dev container should not be mounted to main dev container", m.Name)
+		}
+	}
+
+	volumeNames := map[string]bool{}
+	for _, v := range volumes {
+		volumeNames[v.Name] = true
+	}
+	sidecarMounts := map[string]corev1.VolumeMount{}
+	for _, m := range sidecar.VolumeMounts {
+		sidecarMounts[m.MountPath] = m
+	}
+
+	for _, e := range []struct{ container, devName, image, workDir string }{
+		{"proxy", "proxy", "proxy-dev:v1", "/home/proxy"},
+		{"worker", "worker-dev", "worker-dev:v1", "/home/worker"},
+	} {
+		extra, err := findDevContainerInPodSpec(podSpec, e.devName)
+		if err != nil {
+			t.Fatalf("extra dev container %s not found: %v", e.devName, err)
+		}
+		if extra.Image != e.image || extra.WorkingDir != e.workDir {
+			t.Fatalf("unexpected extra dev container %s: %s %s", e.devName, extra.Image, extra.WorkingDir)
+		}
+
+		volume := "nocalhost-shared-volume-" + e.container
+		if !volumeNames[volume] {
+			t.Fatalf("sync volume %s of %s not found", volume, e.container)
+		}
+		var mounted bool
+		for _, m := range extra.VolumeMounts {
+			if m.Name == volume && m.MountPath == e.workDir {
+				mounted = true
+			}
+		}
+		if !mounted {
+			t.Fatalf("sync volume should be mounted to workDir of %s, got %v", e.devName, extra.VolumeMounts)
+		}
+
+		m, ok := sidecarMounts[ExtraDevContainerRemoteSyncDir(e.container)]
+		if !ok || m.Name != volume {
+			t.Fatalf("sync volume of %s should be mounted to sidecar, got %v", e.container, sidecar.VolumeMounts)
+		}
+	}
+	if ExtraDevContainerRemoteSyncDir("proxy") != ExtraSyncRemoteHome+"/proxy" {
+		t.Fatalf("unexpected remote sync dir %s", ExtraDevContainerRemoteSyncDir("proxy"))
+	}
+}
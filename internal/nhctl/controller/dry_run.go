@@ -0,0 +1,137 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/model"
+	"strings"
+)
+
+// DevModeDryRunResult objects which would be created or modified while entering DevMode
+type DevModeDryRunResult struct {
+	Workload *unstructured.Unstructured
+	PVCs     []*corev1.PersistentVolumeClaim
+	Secret   *corev1.Secret
+}
+
+// DryRunDevMode renders the workload patched by replace DevMode, the PVCs and the syncthing secret,
+// nothing will be sent to cluster
+func (c *Controller) DryRunDevMode(ops *model.DevStartOptions) (*DevModeDryRunResult, error) {
+	if !c.DevModeType.IsReplaceDevMode() {
+		return nil, errors.New(fmt.Sprintf("Dry-run does not support %s DevMode", c.DevModeType.ToString()))
+	}
+	if c.Type == base.Pod {
+		return nil, errors.New("Dry-run does not support pod")
+	}
+
+	result := &DevModeDryRunResult{PVCs: make([]*corev1.PersistentVolumeClaim, 0)}
+	c.dryRun = result
+	defer func() {
+		c.dryRun = nil
+	}()
+
+	if err := c.PatchDevModeManifest(context.TODO(), ops); err != nil {
+		return nil, err
+	}
+
+	secret, err := c.genSyncThingSecret(ops.Container, ops.LocalSyncDir)
+	if err != nil {
+		return nil, err
+	}
+	result.Secret = secret
+	result.Secret.Namespace = c.NameSpace
+	result.Secret.TypeMeta = metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"}
+	for _, pvc := range result.PVCs {
+		pvc.Namespace = c.NameSpace
+		pvc.TypeMeta = metav1.TypeMeta{Kind: "PersistentVolumeClaim", APIVersion: "v1"}
+	}
+	return result, nil
+}
+
+// renderGeneratedDeployment records the deployment which would be created by DevModeAction.Create
+func (c *Controller) renderGeneratedDeployment(deployment *appsv1.Deployment, container string) error {
+	deployment.TypeMeta = metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"}
+	deployment.Namespace = c.NameSpace
+
+	generated := &unstructured.Unstructured{}
+	var err error
+	if generated.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(deployment); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = c.patchUnstructuredWithDevConfig(generated, container); err != nil {
+		return err
+	}
+	c.dryRun.Workload = generated
+	return nil
+}
+
+func (c *Controller) patchUnstructuredWithDevConfig(obj *unstructured.Unstructured, container string) error {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	if devConfig == nil {
+		return nil
+	}
+	for _, patch := range devConfig.Patches {
+		if err := PatchUnstructured(obj, patch.Patch, patch.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PatchUnstructured applies patch to obj in memory, patchType can be json, merge or strategic,
+// the same as `kubectl patch --type`
+func PatchUnstructured(obj *unstructured.Unstructured, patch, patchType string) error {
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	patchBytes, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Invalid patch %s", patch))
+	}
+
+	var patched []byte
+	switch strings.ToLower(patchType) {
+	case "json":
+		jp, err := jsonpatch.DecodePatch(patchBytes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if patched, err = jp.Apply(original); err != nil {
+			return errors.WithStack(err)
+		}
+	case "merge":
+		if patched, err = jsonpatch.MergePatch(original, patchBytes); err != nil {
+			return errors.WithStack(err)
+		}
+	case "strategic", "":
+		typed, err := scheme.Scheme.New(obj.GroupVersionKind())
+		if err != nil {
+			return errors.Wrap(err, "Strategic merge patch is only supported by built-in resources")
+		}
+		if patched, err = strategicpatch.StrategicMergePatch(original, patchBytes, typed); err != nil {
+			return errors.WithStack(err)
+		}
+	default:
+		return errors.New(fmt.Sprintf("Unsupported patch type %s", patchType))
+	}
+
+	return errors.WithStack(obj.UnmarshalJSON(patched))
+}
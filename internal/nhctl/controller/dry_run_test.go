@@ -0,0 +1,117 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/model"
+	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/internal/nhctl/profile"
+	"testing"
+)
+
+func newTestDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "reviews"},
+			"spec": map[string]interface{}{
+				"replicas": int64(3),
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": map[string]interface{}{"app": "reviews"},
+					},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "reviews", "image": "reviews:v1"},
+							map[string]interface{}{"name": "proxy", "image": "envoy"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPatchUnstructured(t *testing.T) {
+	obj := newTestDeployment()
+	if err := PatchUnstructured(obj, `[{"op":"replace","path":"/spec/replicas","value":1}]`, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); r != 1 {
+		t.Fatalf("expected replicas 1, got %d", r)
+	}
+
+	if err := PatchUnstructured(obj, "metadata:\n  labels:\n    a: b\n", "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if obj.GetLabels()["a"] != "b" {
+		t.Fatalf("expected label a=b, got %v", obj.GetLabels())
+	}
+
+	strategic := `{"spec":{"template":{"spec":{"containers":[{"name":"proxy","image":"envoy:v2"}]}}}}`
+	if err := PatchUnstructured(obj, strategic, "strategic"); err != nil {
+		t.Fatal(err)
+	}
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if len(containers) != 2 {
+		t.Fatalf("strategic patch should merge containers by name, got %v", containers)
+	}
+	for _, c := range containers {
+		m := c.(map[string]interface{})
+		if m["name"] == "proxy" && m["image"] != "envoy:v2" {
+			t.Fatalf("expected proxy image envoy:v2, got %v", m["image"])
+		}
+	}
+
+	if err := PatchUnstructured(obj, "{}", "unknown"); err == nil {
+		t.Fatal("expected error for unsupported patch type")
+	}
+}
+
+func TestPatchDevModeManifestDryRun(t *testing.T) {
+	c := &Controller{
+		Name:          "reviews",
+		Type:          base.Deployment,
+		DevModeType:   profile.ReplaceDevMode,
+		DevModeAction: nocalhost.DefaultDevModeAction,
+		config:        &profile.ServiceConfigV2{},
+		dryRun:        &DevModeDryRunResult{},
+	}
+	obj := newTestDeployment()
+	ops := &model.DevStartOptions{Container: "reviews", DevImage: "golang:1.16"}
+	if err := c.patchDevModeManifest(obj, ops); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := c.dryRun.Workload
+	if rendered == nil {
+		t.Fatal("workload is not rendered")
+	}
+	if rendered.GetAnnotations()[_const.OriginWorkloadDefinition] == "" {
+		t.Fatal("original manifest is not recorded")
+	}
+	if r, _, _ := unstructured.NestedInt64(rendered.Object, "spec", "replicas"); r != 1 {
+		t.Fatalf("expected replicas 1 by scale patches, got %d", r)
+	}
+	podTemplate, err := GetPodTemplateFromSpecPath("/spec/template", rendered.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, container := range podTemplate.Spec.Containers {
+		names[container.Name] = container.Image
+	}
+	if names[c.GetDevContainerName("reviews")] != "golang:1.16" {
+		t.Fatalf("dev container is not patched, got %v", names)
+	}
+	if _, ok := names[_const.NocalhostDefaultDevSidecarName]; !ok {
+		t.Fatalf("sidecar is not patched, got %v", names)
+	}
+}
@@ -46,6 +46,9 @@ const (
 	AnnotationMeshTypeDev     = "dev"
 	AnnotationMeshTypeOrigin  = "origin"
 	EnvoyMeshSidecarName      = "nocalhost-mesh"
+
+	// AnnotationMeshMirrorPercent percentage of requests mirrored to the duplicate dev pod
+	AnnotationMeshMirrorPercent = "dev.mesh.nocalhost.dev/mirror-percent"
 )
 
 // ReplaceDuplicateModeImage Create a duplicate deployment instead of replacing image
@@ -91,14 +94,16 @@ func (c *Controller) ReplaceDuplicateModeImage(ctx context.Context, ops *model.D
 		podTemplate.Annotations = c.getDevContainerAnnotations(ops.Container, podTemplate.Annotations)
 
 		devContainer, sideCarContainer, devModeVolumes, err :=
-			c.genContainersAndVolumes(&podTemplate.Spec, ops.Container, ops.DevImage, ops.StorageClass, true)
+			c.genContainersAndVolumes(
+				&podTemplate.Spec, ops.Container, ops.DevImage, ops.StorageClass, extraContainerNames(ops), true,
+			)
 		if err != nil {
 			return err
 		}
 
 		patchDevContainerToPodSpec(&podTemplate.Spec, ops.Container, devContainer, sideCarContainer, devModeVolumes)
 		// add envoy sidecar
-		if len(ops.MeshHeader) != 0 {
+		if len(ops.MeshHeader) != 0 || ops.MirrorPercent > 0 {
 			err = createMeshManagerIfNotExist(ctx, c.Client.ClientSet, c.NameSpace)
 			if err != nil {
 				return err
@@ -107,7 +112,11 @@ func (c *Controller) ReplaceDuplicateModeImage(ctx context.Context, ops *model.D
 			if len(uuid) == 0 {
 				uuid = string(umClone.GetUID())
 			}
-			addAnnotationToDuplicate(podTemplate, uuid, ops.MeshHeader)
+			if ops.MirrorPercent > 0 {
+				addMirrorAnnotationToDuplicate(podTemplate, uuid, ops.MirrorPercent)
+			} else {
+				addAnnotationToDuplicate(podTemplate, uuid, ops.MeshHeader)
+			}
 			AddEnvoySidecarForMesh(podTemplate)
 			if exist := AddEnvoySidecarForMesh(podTemplateOrigin); !exist {
 				addAnnotationToMesh(podTemplateOrigin, uuid)
@@ -175,6 +184,8 @@ func (c *Controller) ReplaceDuplicateModeImage(ctx context.Context, ops *model.D
 			kind += "." + gvk.Group
 		}
 
+		// hpa is not suspended, it targets the original workload by name and never scales the duplicate one,
+		// pinning it would scale down the original workload which is still serving
 		for _, item := range c.DevModeAction.ScalePatches {
 			log.Infof("Patching %s", item.Patch)
 			if err = c.Client.Patch(kind, infos[0].Name, item.Patch, item.Type); err != nil {
@@ -206,7 +217,7 @@ func (c *Controller) ReplaceDuplicateModeImage(ctx context.Context, ops *model.D
 
 		devContainer, sideCarContainer, devModeVolumes, err :=
 			c.genContainersAndVolumes(
-				&genDeploy.Spec.Template.Spec, ops.Container, ops.DevImage, ops.StorageClass, true,
+				&genDeploy.Spec.Template.Spec, ops.Container, ops.DevImage, ops.StorageClass, extraContainerNames(ops), true,
 			)
 		if err != nil {
 			return err
@@ -253,6 +264,20 @@ func addAnnotationToDuplicate(podTemplate *v1.PodTemplateSpec, uuid string, head
 	podTemplate.SetAnnotations(anno)
 }
 
+// addMirrorAnnotationToDuplicate duplicate dev pod receives shadow copies of requests instead of the
+// requests with specified header
+func addMirrorAnnotationToDuplicate(podTemplate *v1.PodTemplateSpec, uuid string, percent int) {
+	anno := podTemplate.GetAnnotations()
+	if anno == nil {
+		anno = map[string]string{}
+	}
+	anno[AnnotationMeshUuid] = uuid
+	anno[AnnotationMeshEnable] = "true"
+	anno[AnnotationMeshType] = AnnotationMeshTypeDev
+	anno[AnnotationMeshMirrorPercent] = strconv.Itoa(percent)
+	podTemplate.SetAnnotations(anno)
+}
+
 func addAnnotationToMesh(podTemplate *v1.PodTemplateSpec, uuid string) {
 	anno := podTemplate.GetAnnotations()
 	if anno == nil {
@@ -75,7 +75,9 @@ func (r *DuplicateRawPodController) ReplaceImage(ctx context.Context, ops *model
 	originalPod.Annotations = r.getDevContainerAnnotations(ops.Container, originalPod.Annotations)
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		r.genContainersAndVolumes(&originalPod.Spec, ops.Container, ops.DevImage, ops.StorageClass, true)
+		r.genContainersAndVolumes(
+			&originalPod.Spec, ops.Container, ops.DevImage, ops.StorageClass, extraContainerNames(ops), true,
+		)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,346 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/model"
+	secret_config "nocalhost/internal/nhctl/syncthing/secret-config"
+	"nocalhost/pkg/nhctl/log"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// EphemeralTargetRootFs links to the dev container's filesystem in sidecar. Both of dev container
+	// and sidecar target the app container, the sidecar finds the dev container's process in the
+	// shared pid namespace by ephemeralDevContainerEnv, and links to its root through procfs
+	EphemeralTargetRootFs = "/nocalhost-dev-root"
+
+	// ephemeralDevContainerEnv marks the dev container's process, its value is the dev container's name
+	ephemeralDevContainerEnv    = "NOCALHOST_EPHEMERAL_DEV_CONTAINER"
+	ephemeralSyncthingConfigEnv = "NOCALHOST_SYNCTHING_CONFIG"
+	ephemeralSyncthingCertEnv   = "NOCALHOST_SYNCTHING_CERT"
+	ephemeralSyncthingKeyEnv    = "NOCALHOST_SYNCTHING_KEY"
+
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+)
+
+// EphemeralPodController attaches dev container and syncthing sidecar to a running pod
+// as ephemeral containers, the owning workload will never be modified.
+// Ephemeral containers can not be removed from a pod, so rolling back means deleting the pod
+// and letting its controller create a fresh one
+type EphemeralPodController struct {
+	*Controller
+}
+
+func (e *EphemeralPodController) ReplaceImage(ctx context.Context, ops *model.DevStartOptions) error {
+	e.Client.Context(ctx)
+
+	if e.Type == base.Pod {
+		return errors.New(
+			fmt.Sprintf("Pod %s is not managed by a controller, can not enter ephemeral DevMode", e.Name),
+		)
+	}
+
+	if len(ops.ExtraContainers) > 0 {
+		return errors.New("Extra dev containers are not supported by ephemeral DevMode")
+	}
+	if e.GetSyncEngine(ops.Container) == _const.ExecSyncEngine {
+		return errors.New("Sync engine exec is not supported by ephemeral DevMode")
+	}
+	if len(e.GetSyncMappings(ops.Container)) > 0 {
+		return errors.New("Sync mappings are not supported by ephemeral DevMode")
+	}
+
+	if cfg := e.config.GetContainerDevConfigOrDefault(ops.Container); cfg != nil && len(cfg.Patches) > 0 {
+		log.Warn("Patches will be ignored in ephemeral DevMode, workload is never modified")
+	}
+
+	if err := e.checkPodSecurity(); err != nil {
+		return err
+	}
+
+	pod, err := e.findTargetPod()
+	if err != nil {
+		return err
+	}
+
+	devContainerName := e.GetDevContainerName(ops.Container)
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name == devContainerName || ec.Name == _const.NocalhostDefaultDevSidecarName {
+			return errors.New(
+				fmt.Sprintf(
+					"Ephemeral container %s already exists in pod %s, you need to reset it first",
+					ec.Name, pod.Name,
+				),
+			)
+		}
+	}
+
+	targetContainer, err := findDevContainerInPodSpec(&pod.Spec, ops.Container)
+	if err != nil {
+		return err
+	}
+	if isInitContainer(&pod.Spec, targetContainer) {
+		return errors.New("Ephemeral DevMode does not support init container")
+	}
+
+	devContainer := e.genEphemeralDevContainer(targetContainer, devContainerName, ops)
+	sideCarContainer := e.genEphemeralSideCarContainer(targetContainer, devContainer, ops)
+
+	log.Infof("Recording ephemeral DevMode to pod %s...", pod.Name)
+	annotations := map[string]string{
+		_const.EphemeralDevModeAnnotation:       e.Identifier,
+		_const.NocalhostDevContainerAnnotations: devContainerName,
+	}
+	mBytes, _ := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err = e.Client.Patch("pod", pod.Name, string(mBytes), "merge"); err != nil {
+		return err
+	}
+
+	if pod, err = e.Client.GetPod(pod.Name); err != nil {
+		return err
+	}
+
+	log.Infof("Attaching ephemeral containers to pod %s...", pod.Name)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *devContainer, *sideCarContainer)
+	if _, err = e.Client.UpdateEphemeralContainers(pod); err != nil {
+		return err
+	}
+
+	delete(pod.Labels, "pod-template-hash")
+	e.devModePodLabels = pod.Labels
+
+	e.waitDevPodToBeReady()
+	return nil
+}
+
+// RollBack ephemeral containers can not be removed, delete the pod directly
+func (e *EphemeralPodController) RollBack(reset bool) error {
+	pods, err := e.GetEphemeralModePodList()
+	if err != nil {
+		return err
+	}
+
+	if len(pods) == 0 {
+		err1 := errors.New(fmt.Sprintf("No pod of %s is in ephemeral DevMode", e.Name))
+		if reset {
+			log.WarnE(err1, "")
+			return nil
+		}
+		return err1
+	}
+
+	for _, pod := range pods {
+		log.Infof("Deleting pod %s...", pod.Name)
+		if err = e.Client.DeletePodByName(pod.Name, 0); err != nil {
+			if !reset {
+				return err
+			}
+			log.WarnE(err, "")
+		}
+	}
+	return nil
+}
+
+// GetEphemeralModePodList return pods of the workload which ephemeral containers attached by current identifier
+func (c *Controller) GetEphemeralModePodList() ([]corev1.Pod, error) {
+	pods, err := c.listWorkloadPods()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]corev1.Pod, 0)
+	for _, pod := range pods {
+		if pod.Annotations[_const.EphemeralDevModeAnnotation] == c.Identifier {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+func (c *Controller) listWorkloadPods() ([]corev1.Pod, error) {
+	pt, err := c.GetPodTemplate()
+	if err != nil {
+		return nil, err
+	}
+	delete(pt.Labels, "pod-template-hash")
+	return c.Client.Labels(pt.Labels).ListPods()
+}
+
+// findTargetPod find a running pod which is not attached by other ephemeral DevMode
+func (e *EphemeralPodController) findTargetPod() (*corev1.Pod, error) {
+	pods, err := e.listWorkloadPods()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if _, ok := pod.Annotations[_const.EphemeralDevModeAnnotation]; ok {
+			continue
+		}
+		return pod, nil
+	}
+	return nil, errors.New(fmt.Sprintf("No running pod of %s found", e.Name))
+}
+
+func (e *EphemeralPodController) genEphemeralDevContainer(target *corev1.Container, name string,
+	ops *model.DevStartOptions) *corev1.EphemeralContainer {
+
+	devImage := ops.DevImage
+	if devImage == "" {
+		devImage = e.GetDevImage(ops.Container)
+	}
+
+	workDir := e.GetWorkDir(ops.Container)
+	devContainer := &corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:            name,
+			Image:           devImage,
+			Command:         []string{"/bin/sh", "-c", "tail -f /dev/null"},
+			WorkingDir:      workDir,
+			Env:             target.Env,
+			EnvFrom:         target.EnvFrom,
+			VolumeMounts:    target.VolumeMounts,
+			ImagePullPolicy: e.GetImagePullPolicy(ops.Container),
+			SecurityContext: target.SecurityContext,
+			Stdin:           true,
+			TTY:             true,
+		},
+		// share the pid namespace with the original container, so it can be inspected by dev container
+		TargetContainerName: target.Name,
+	}
+
+	for _, v := range e.GetDevContainerEnv(ops.Container).DevEnv {
+		devContainer.Env = append(devContainer.Env, corev1.EnvVar{Name: v.Name, Value: v.Value})
+	}
+	devContainer.Env = append(devContainer.Env, corev1.EnvVar{Name: ephemeralDevContainerEnv, Value: name})
+	return devContainer
+}
+
+// ephemeralDevRootScript waits for the process of dev container to appear in the shared pid namespace,
+// then links EphemeralTargetRootFs to its root
+func ephemeralDevRootScript(devContainerName string) string {
+	marker := ephemeralDevContainerEnv + "=" + devContainerName
+	return fmt.Sprintf(
+		`while [ ! -e %[1]s ]; do for p in /proc/[0-9]*; do `+
+			`if tr '\0' '\n' < $p/environ 2>/dev/null | grep -qx '%[2]s'; then ln -sfn $p/root %[1]s; break; fi; `+
+			`done; [ -e %[1]s ] || sleep 1; done`,
+		EphemeralTargetRootFs, marker,
+	)
+}
+
+// checkPodSecurity fails fast if the namespace enforces a PodSecurity level the sidecar violates,
+// otherwise updating ephemeral containers is rejected with an opaque error
+func (e *EphemeralPodController) checkPodSecurity() error {
+	ns, err := e.Client.ClientSet.CoreV1().Namespaces().Get(
+		e.Client.GetContext(), e.NameSpace, metav1.GetOptions{},
+	)
+	if err != nil {
+		log.WarnE(errors.Wrap(err, ""), "Failed to check PodSecurity of namespace "+e.NameSpace)
+		return nil
+	}
+	return checkEphemeralPodSecurity(e.NameSpace, ns.Labels)
+}
+
+// checkEphemeralPodSecurity: the sidecar runs as root with SYS_PTRACE, which is only allowed
+// by privileged PodSecurity level
+func checkEphemeralPodSecurity(namespace string, labels map[string]string) error {
+	level := labels[podSecurityEnforceLabel]
+	if level == "" || level == "privileged" {
+		return nil
+	}
+	return errors.New(
+		fmt.Sprintf(
+			"Namespace %s enforces PodSecurity level %s, but the sidecar of ephemeral DevMode "+
+				"needs root and SYS_PTRACE which are only allowed by level privileged, "+
+				"use replace DevMode instead",
+			namespace, level,
+		),
+	)
+}
+
+func (e *EphemeralPodController) genEphemeralSideCarContainer(target *corev1.Container,
+	devContainer *corev1.EphemeralContainer, ops *model.DevStartOptions) *corev1.EphemeralContainer {
+
+	devImage := devContainer.Image
+	sideCar := generateSideCarContainer(
+		e.GetDevSidecarImage(ops.Container), e.GetWorkDir(ops.Container),
+		e.sidecarContainerSSHUsed(e.GetDevSidecarLanguage(ops.Container), devImage) ||
+			e.reversePortForwardUsed(ops.Container),
+	)
+
+	// ephemeral containers can not mount new volumes, so syncthing secret is injected by env
+	secretName := e.GetSyncThingSecretName()
+	secretEnv := func(env, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: env,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  key,
+				},
+			},
+		}
+	}
+
+	prepare := strings.Join(
+		[]string{
+			ephemeralDevRootScript(devContainer.Name),
+			"mkdir -p " + secret_config.DefaultSyncthingSecretHome,
+			fmt.Sprintf(
+				`printf '%%s' "$%s" > %s`, ephemeralSyncthingConfigEnv,
+				filepath.Join(secret_config.DefaultSyncthingSecretHome, "config.xml"),
+			),
+			fmt.Sprintf(
+				`printf '%%s' "$%s" > %s`, ephemeralSyncthingCertEnv,
+				filepath.Join(secret_config.DefaultSyncthingSecretHome, "cert.pem"),
+			),
+			fmt.Sprintf(
+				`printf '%%s' "$%s" > %s`, ephemeralSyncthingKeyEnv,
+				filepath.Join(secret_config.DefaultSyncthingSecretHome, "key.pem"),
+			),
+		}, " && ",
+	)
+
+	var rootUID int64 = 0
+	return &corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:            sideCar.Name,
+			Image:           sideCar.Image,
+			Command:         sideCar.Command,
+			Args:            []string{prepare + " && " + sideCar.Args[0]},
+			ImagePullPolicy: e.GetImagePullPolicy(ops.Container),
+			Env: []corev1.EnvVar{
+				secretEnv(ephemeralSyncthingConfigEnv, "config.xml"),
+				secretEnv(ephemeralSyncthingCertEnv, "cert.pem"),
+				secretEnv(ephemeralSyncthingKeyEnv, "key.pem"),
+			},
+			// reading dev container's filesystem through procfs needs root and SYS_PTRACE
+			SecurityContext: &corev1.SecurityContext{
+				RunAsUser: &rootUID,
+				Capabilities: &corev1.Capabilities{
+					Add: []corev1.Capability{"SYS_PTRACE"},
+				},
+			},
+		},
+		// ephemeral containers can only target containers in pod spec, the dev container
+		// is in the same pid namespace as it also targets the app container
+		TargetContainerName: target.Name,
+	}
+}
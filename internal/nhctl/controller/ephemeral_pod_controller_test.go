@@ -0,0 +1,77 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"nocalhost/internal/nhctl/model"
+	"nocalhost/internal/nhctl/profile"
+	"strings"
+	"testing"
+)
+
+func TestGenEphemeralContainers(t *testing.T) {
+	e := &EphemeralPodController{
+		Controller: &Controller{
+			Name:        "reviews",
+			DevModeType: profile.EphemeralDevMode,
+			config:      &profile.ServiceConfigV2{},
+		},
+	}
+	target := &corev1.Container{Name: "reviews", Image: "reviews:v1"}
+	ops := &model.DevStartOptions{Container: "reviews"}
+
+	dev := e.genEphemeralDevContainer(target, "nocalhost-dev", ops)
+	sidecar := e.genEphemeralSideCarContainer(target, dev, ops)
+
+	// ephemeral containers can not be targeted, both of them must target the app container
+	if dev.TargetContainerName != target.Name || sidecar.TargetContainerName != target.Name {
+		t.Fatalf("containers should target %s, got %s and %s",
+			target.Name, dev.TargetContainerName, sidecar.TargetContainerName)
+	}
+
+	marked := false
+	for _, env := range dev.Env {
+		if env.Name == ephemeralDevContainerEnv && env.Value == dev.Name {
+			marked = true
+		}
+	}
+	if !marked {
+		t.Fatalf("process of dev container is not marked by %s", ephemeralDevContainerEnv)
+	}
+
+	args := sidecar.Args[0]
+	if !strings.HasPrefix(args, ephemeralDevRootScript(dev.Name)+" && ") {
+		t.Fatalf("sidecar should link the dev container's root before starting syncthing, got %s", args)
+	}
+	if !strings.Contains(args, "grep -qx '"+ephemeralDevContainerEnv+"=nocalhost-dev'") ||
+		!strings.Contains(args, "$p/root "+EphemeralTargetRootFs) {
+		t.Fatalf("unexpected script %s", args)
+	}
+}
+
+func TestCheckEphemeralPodSecurity(t *testing.T) {
+	cases := []struct {
+		labels map[string]string
+		reject bool
+	}{
+		{nil, false},
+		{map[string]string{podSecurityEnforceLabel: "privileged"}, false},
+		{map[string]string{podSecurityEnforceLabel: "baseline"}, true},
+		{map[string]string{podSecurityEnforceLabel: "restricted"}, true},
+		// audit and warn do not reject pods
+		{map[string]string{"pod-security.kubernetes.io/warn": "restricted"}, false},
+	}
+	for _, c := range cases {
+		err := checkEphemeralPodSecurity("default", c.labels)
+		if (err != nil) != c.reject {
+			t.Fatalf("labels %v: expected rejected %v, got %v", c.labels, c.reject, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "replace DevMode") {
+			t.Fatalf("error should suggest replace DevMode, got %v", err)
+		}
+	}
+}
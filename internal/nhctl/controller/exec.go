@@ -44,5 +44,12 @@ func (c *Controller) EnterPodTerminal(podName, container, shell, banner string)
 	if shell != "" {
 		cmd = fmt.Sprintf("(%s || zsh || bash || sh)", shell)
 	}
+
+	// an open terminal session keeps DevMode alive
+	if c.IsInDevMode() {
+		stop := make(chan struct{})
+		defer close(stop)
+		go c.keepDevLeaseAlive(stop)
+	}
 	return c.Client.ExecShell(pod, devContainerName, cmd, banner)
 }
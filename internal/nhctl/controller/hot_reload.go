@@ -0,0 +1,102 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/hotreload"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NewHotReloadRunner runner supervising run command, or debug command if debug is true, of container,
+// it's configured by hotReloadConfig of the container
+func (c *Controller) NewHotReloadRunner(container string, debug bool, stdout, stderr io.Writer) (
+	*hotreload.Runner, error,
+) {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	if devConfig == nil || devConfig.Command == nil {
+		return nil, errors.New(fmt.Sprintf("Dev command of %s is not defined", c.Name))
+	}
+	command := devConfig.Command.Run
+	if debug {
+		command = devConfig.Command.Debug
+	}
+	if len(command) == 0 {
+		return nil, errors.New(fmt.Sprintf("Dev command of %s is not defined", c.Name))
+	}
+
+	podName, err := c.GetDevModePodName()
+	if err != nil {
+		return nil, err
+	}
+	devContainer := c.GetDevContainerName(container)
+
+	r := &hotreload.Runner{
+		Command:  command,
+		Debounce: hotreload.DefaultDebounce,
+		PidFile:  hotreload.PidFile(devContainer),
+		Exec: func(cmd []string, stdout, stderr io.Writer) error {
+			return c.Client.ExecWithStream(podName, devContainer, cmd, nil, stdout, stderr)
+		},
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	if hc := devConfig.HotReloadConfig; hc != nil {
+		if hc.Debounce != "" {
+			if r.Debounce, err = time.ParseDuration(hc.Debounce); err != nil {
+				return nil, errors.Wrap(err, "")
+			}
+		}
+		r.Patterns = hc.Patterns
+		r.Signal = hc.Signal
+	}
+	return r, nil
+}
+
+// WatchSyncEvents sync events of the service until ctx is done, the channel is closed then
+func (c *Controller) WatchSyncEvents(ctx context.Context) (<-chan req.SyncEvent, error) {
+	if c.GetDevSyncEngine() == _const.ExecSyncEngine {
+		return nil, errors.New("Sync events are not supported by sync engine exec")
+	}
+	events := make(chan req.SyncEvent, 256)
+	go func() {
+		defer close(events)
+		c.NewSyncthingHttpClient(2).WatchSyncEvents(
+			ctx, func(event req.SyncEvent) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+				}
+			},
+		)
+	}()
+	return events, nil
+}
+
+// AppendHotReloadLog appends the restart log of hot reload to file in sync dir
+func (c *Controller) AppendHotReloadLog(line string) error {
+	if err := os.MkdirAll(c.GetSyncDir(), 0700); err != nil {
+		return errors.Wrap(err, "")
+	}
+	f, err := os.OpenFile(c.HotReloadLogPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s %s\n", time.Now().Format("2006-01-02 15:04:05"), line)
+	return errors.Wrap(err, "")
+}
+
+func (c *Controller) HotReloadLogPath() string {
+	return filepath.Join(c.GetSyncDir(), "hot-reload.log")
+}
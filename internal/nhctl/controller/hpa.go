@@ -6,9 +6,22 @@
 package controller
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/pkg/nhctl/log"
+	"strconv"
 )
 
+// hpaDefinition spec of hpa before entering DevMode
+type hpaDefinition struct {
+	Name string                                    `json:"name"`
+	Spec autoscalingv1.HorizontalPodAutoscalerSpec `json:"spec"`
+}
+
 func (c *Controller) ListHPA() ([]autoscalingv1.HorizontalPodAutoscaler, error) {
 	typeMeta, err := c.GetTypeMeta()
 	if err != nil {
@@ -28,3 +41,144 @@ func (c *Controller) ListHPA() ([]autoscalingv1.HorizontalPodAutoscaler, error)
 	}
 	return result, nil
 }
+
+// hpaClient gets and updates hpa in the namespace of the workload
+type hpaClient interface {
+	GetHPA(name string) (*autoscalingv1.HorizontalPodAutoscaler, error)
+	UpdateHPA(hpa *autoscalingv1.HorizontalPodAutoscaler) (*autoscalingv1.HorizontalPodAutoscaler, error)
+}
+
+// suspendHPA records specs of hpa targeting the workload to the workload's annotations,
+// then pins them to 1 replica, so that they won't fight against DevMode.
+// If specs have been recorded by a previous DevMode which was not ended correctly, they are kept
+func (c *Controller) suspendHPA(recorded string) error {
+	hl, err := c.ListHPA()
+	if err != nil {
+		return err
+	}
+	if len(hl) == 0 {
+		log.Info("No hpa found")
+		return nil
+	}
+
+	if recorded == "" {
+		patch, err := hpaDefinitionsPatch(hl)
+		if err != nil {
+			return err
+		}
+		if err = c.Client.Patch(c.Type.String(), c.Name, patch, "merge"); err != nil {
+			return err
+		}
+		log.Info("Original hpa recorded")
+	} else {
+		log.Info("Original hpa has been recorded, keep it")
+	}
+	return pinHPA(c.Client, hl)
+}
+
+// hpaDefinitionsPatch merge patch recording specs of hpa to annotation HPAOriginDefinition
+func hpaDefinitionsPatch(hl []autoscalingv1.HorizontalPodAutoscaler) (string, error) {
+	definitions := make([]hpaDefinition, 0, len(hl))
+	for _, h := range hl {
+		definitions = append(definitions, hpaDefinition{Name: h.Name, Spec: h.Spec})
+	}
+	bys, err := json.Marshal(definitions)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	mBytes, _ := json.Marshal(
+		map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{_const.HPAOriginDefinition: string(bys)},
+			},
+		},
+	)
+	return string(mBytes), nil
+}
+
+func pinHPA(client hpaClient, hl []autoscalingv1.HorizontalPodAutoscaler) error {
+	for _, h := range hl {
+		var one int32 = 1
+		h.Spec.MinReplicas = &one
+		h.Spec.MaxReplicas = 1
+		if _, err := client.UpdateHPA(&h); err != nil {
+			return err
+		}
+		log.Infof("HPA %s has been suspended", h.Name)
+	}
+	return nil
+}
+
+// restoreHPA restores hpa to the specs recorded in annotations of the workload in DevMode
+func (c *Controller) restoreHPA(devModeWorkload *unstructured.Unstructured) error {
+	recorded, err := GetAnnotationFromUnstructured(devModeWorkload, _const.HPAOriginDefinition)
+	if err != nil {
+		// DevMode entered by previous version records hpa's replicas in its own annotations
+		hl, err := c.ListHPA()
+		if err != nil {
+			return err
+		}
+		restoreHPAFromLegacyAnnotations(c.Client, hl)
+		return nil
+	}
+	return restoreHPAFromDefinitions(c.Client, recorded)
+}
+
+func restoreHPAFromDefinitions(client hpaClient, recorded string) error {
+	definitions := make([]hpaDefinition, 0)
+	if err := json.Unmarshal([]byte(recorded), &definitions); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Invalid annotation %s", _const.HPAOriginDefinition))
+	}
+
+	log.Info("Restoring hpa...")
+	for _, definition := range definitions {
+		h, err := client.GetHPA(definition.Name)
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to get hpa %s, skip restoring it", definition.Name))
+			continue
+		}
+		h.Spec = definition.Spec
+		delete(h.Annotations, _const.HPAOriginalMaxReplicasKey)
+		delete(h.Annotations, _const.HPAOriginalMinReplicasKey)
+		if _, err = client.UpdateHPA(h); err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to restore hpa %s", h.Name))
+		} else {
+			log.Infof("HPA %s has been restored", h.Name)
+		}
+	}
+	return nil
+}
+
+func restoreHPAFromLegacyAnnotations(client hpaClient, hl []autoscalingv1.HorizontalPodAutoscaler) {
+	for _, h := range hl {
+		max, maxOk := h.Annotations[_const.HPAOriginalMaxReplicasKey]
+		min, minOk := h.Annotations[_const.HPAOriginalMinReplicasKey]
+		if !maxOk && !minOk {
+			continue
+		}
+		if maxOk {
+			maxInt, err := strconv.ParseInt(max, 0, 0)
+			if err != nil {
+				log.WarnE(err, "")
+				continue
+			}
+			h.Spec.MaxReplicas = int32(maxInt)
+		}
+		if minOk {
+			minInt, err := strconv.ParseInt(min, 0, 0)
+			if err != nil {
+				log.WarnE(err, "")
+				continue
+			}
+			minInt32 := int32(minInt)
+			h.Spec.MinReplicas = &minInt32
+		}
+		delete(h.Annotations, _const.HPAOriginalMaxReplicasKey)
+		delete(h.Annotations, _const.HPAOriginalMinReplicasKey)
+		if _, err := client.UpdateHPA(&h); err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to update hpa %s", h.Name))
+		} else {
+			log.Infof("HPA %s has been recovered", h.Name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	v1 "k8s.io/client-go/kubernetes/typed/autoscaling/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"testing"
+)
+
+type fakeHPAClient struct {
+	v1.HorizontalPodAutoscalerInterface
+}
+
+func (f *fakeHPAClient) GetHPA(name string) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	return f.Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (f *fakeHPAClient) UpdateHPA(
+	hpa *autoscalingv1.HorizontalPodAutoscaler) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	return f.Update(context.TODO(), hpa, metav1.UpdateOptions{})
+}
+
+func newFakeHPAClient(hpa ...autoscalingv1.HorizontalPodAutoscaler) *fakeHPAClient {
+	clientSet := fake.NewSimpleClientset()
+	client := &fakeHPAClient{clientSet.AutoscalingV1().HorizontalPodAutoscalers("default")}
+	for i := range hpa {
+		_, _ = client.Create(context.TODO(), &hpa[i], metav1.CreateOptions{})
+	}
+	return client
+}
+
+func assertHPAReplicas(t *testing.T, client *fakeHPAClient, name string, min, max int32) {
+	h, err := client.GetHPA(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Spec.MinReplicas == nil || *h.Spec.MinReplicas != min || h.Spec.MaxReplicas != max {
+		t.Fatalf("hpa %s should be %d-%d, got %v-%d", name, min, max, h.Spec.MinReplicas, h.Spec.MaxReplicas)
+	}
+}
+
+func TestSuspendAndRestoreHPA(t *testing.T) {
+	var min int32 = 2
+	hpa := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "default"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "reviews",
+			},
+			MinReplicas: &min,
+			MaxReplicas: 5,
+		},
+	}
+	client := newFakeHPAClient(hpa)
+
+	patch, err := hpaDefinitionsPatch([]autoscalingv1.HorizontalPodAutoscaler{hpa})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workload := metav1.PartialObjectMetadata{}
+	if err = json.Unmarshal([]byte(patch), &workload); err != nil {
+		t.Fatal(err)
+	}
+	recorded := workload.Annotations[_const.HPAOriginDefinition]
+	definitions := make([]hpaDefinition, 0)
+	if err = json.Unmarshal([]byte(recorded), &definitions); err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 1 || definitions[0].Name != "reviews" || definitions[0].Spec.MaxReplicas != 5 ||
+		*definitions[0].Spec.MinReplicas != 2 || definitions[0].Spec.ScaleTargetRef.Name != "reviews" {
+		t.Fatalf("unexpected recorded hpa %s", recorded)
+	}
+
+	if err = pinHPA(client, []autoscalingv1.HorizontalPodAutoscaler{hpa}); err != nil {
+		t.Fatal(err)
+	}
+	assertHPAReplicas(t, client, "reviews", 1, 1)
+	if *hpa.Spec.MinReplicas != 2 {
+		t.Fatal("spec recorded should not be modified by suspending")
+	}
+
+	if err = restoreHPAFromDefinitions(client, recorded); err != nil {
+		t.Fatal(err)
+	}
+	assertHPAReplicas(t, client, "reviews", 2, 5)
+
+	// hpa deleted while developing is skipped
+	if err = restoreHPAFromDefinitions(client, `[{"name":"deleted"}]`); err != nil {
+		t.Fatal(err)
+	}
+	if err = restoreHPAFromDefinitions(client, "invalid"); err == nil {
+		t.Fatal("invalid annotation should fail")
+	}
+}
+
+func TestRestoreHPAFromLegacyAnnotations(t *testing.T) {
+	var one int32 = 1
+	legacy := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "legacy",
+			Namespace: "default",
+			Annotations: map[string]string{
+				_const.HPAOriginalMaxReplicasKey: "6",
+				_const.HPAOriginalMinReplicasKey: "3",
+			},
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{MinReplicas: &one, MaxReplicas: 1},
+	}
+	untouched := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "untouched", Namespace: "default"},
+		Spec:       autoscalingv1.HorizontalPodAutoscalerSpec{MinReplicas: &one, MaxReplicas: 4},
+	}
+	client := newFakeHPAClient(legacy, untouched)
+
+	restoreHPAFromLegacyAnnotations(client, []autoscalingv1.HorizontalPodAutoscaler{legacy, untouched})
+	assertHPAReplicas(t, client, "legacy", 3, 6)
+	assertHPAReplicas(t, client, "untouched", 1, 4)
+
+	h, _ := client.GetHPA("legacy")
+	if _, ok := h.Annotations[_const.HPAOriginalMaxReplicasKey]; ok {
+		t.Fatal("legacy annotations should be removed after restoring")
+	}
+	if _, ok := h.Annotations[_const.HPAOriginalMinReplicasKey]; ok {
+		t.Fatal("legacy annotations should be removed after restoring")
+	}
+}
@@ -0,0 +1,357 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LocalDevEnvFile env of the container in dotenv format, can be loaded by IDEs
+	LocalDevEnvFile = ".env"
+	// LocalDevRootFs ConfigMap/Secret volumes and service account token are materialized
+	// under this dir, keeping their mount paths in the container
+	LocalDevRootFs    = "rootfs"
+	LocalDevRootFsEnv = "NOCALHOST_LOCAL_ROOTFS"
+
+	serviceAccountMountPath        = "/var/run/secrets/kubernetes.io/serviceaccount"
+	serviceAccountRootCAConfigMap  = "kube-root-ca.crt"
+	localDevTokenExpirationSeconds = int64(24 * 60 * 60)
+)
+
+type LocalDevEnv struct {
+	Dir       string
+	Container string
+	// KEY=VALUE, in the order of kubernetes, latter ones override former ones
+	Env []string
+}
+
+func (c *Controller) GetLocalDevDir() string {
+	dirPath := ""
+	if c.Type == base.Deployment {
+		dirPath = filepath.Join(c.getAppHomeDir(), _const.DefaultLocalDevDirName, c.Name)
+	} else {
+		dirPath = filepath.Join(c.getAppHomeDir(), _const.DefaultLocalDevDirName, string(c.Type)+"-"+c.Name)
+	}
+	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
+		utils.Should(os.MkdirAll(dirPath, 0700))
+	}
+	return dirPath
+}
+
+// MaterializeLocalDevEnv resolves env, ConfigMap/Secret volume mounts and service account token
+// of the container into local dev dir
+func (c *Controller) MaterializeLocalDevEnv(container string) (*LocalDevEnv, error) {
+	podTemplate, err := c.GetPodTemplate()
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &podTemplate.Spec
+	target, err := findDevContainerInPodSpec(podSpec, container)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := c.GetLocalDevDir()
+	rootFs := filepath.Join(dir, LocalDevRootFs)
+	if err = os.RemoveAll(rootFs); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	env, err := c.resolveContainerEnv(podSpec, target)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, fmt.Sprintf("%s=%s", LocalDevRootFsEnv, rootFs))
+
+	volumes := map[string]*corev1.Volume{}
+	for i := range podSpec.Volumes {
+		volumes[podSpec.Volumes[i].Name] = &podSpec.Volumes[i]
+	}
+	saMounted := false
+	for _, vm := range target.VolumeMounts {
+		v, ok := volumes[vm.Name]
+		if !ok {
+			continue
+		}
+		files, err := c.volumeFiles(podSpec.ServiceAccountName, v)
+		if err != nil {
+			return nil, err
+		}
+		if files == nil {
+			log.Debugf("Volume %s is neither ConfigMap nor Secret, skip it", vm.Name)
+			continue
+		}
+		if err = writeLocalDevFiles(filepath.Join(rootFs, vm.MountPath), vm.SubPath, files); err != nil {
+			return nil, err
+		}
+		saMounted = saMounted || vm.MountPath == serviceAccountMountPath
+	}
+
+	if !saMounted && (podSpec.AutomountServiceAccountToken == nil || *podSpec.AutomountServiceAccountToken) {
+		files, err := c.serviceAccountFiles(podSpec.ServiceAccountName)
+		if err != nil {
+			log.WarnE(err, "Failed to materialize service account token")
+		} else if err = writeLocalDevFiles(filepath.Join(rootFs, serviceAccountMountPath), "", files); err != nil {
+			return nil, err
+		}
+	}
+
+	envFile := strings.Builder{}
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		envFile.WriteString(fmt.Sprintf("%s=%s\n", kv[0], strconv.Quote(kv[1])))
+	}
+	if err = os.WriteFile(filepath.Join(dir, LocalDevEnvFile), []byte(envFile.String()), 0600); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	log.Infof("Env and volumes of container %s have been materialized to %s", target.Name, dir)
+	return &LocalDevEnv{Dir: dir, Container: target.Name, Env: env}, nil
+}
+
+func (c *Controller) resolveContainerEnv(podSpec *corev1.PodSpec, container *corev1.Container) ([]string, error) {
+	env := make([]string, 0)
+	for _, from := range container.EnvFrom {
+		data, err := c.envFromData(from)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			env = append(env, fmt.Sprintf("%s%s=%s", from.Prefix, k, data[k]))
+		}
+	}
+	for _, e := range container.Env {
+		value, err := c.envVarValue(podSpec, e)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, fmt.Sprintf("%s=%s", e.Name, value))
+	}
+	return env, nil
+}
+
+func (c *Controller) envFromData(from corev1.EnvFromSource) (map[string][]byte, error) {
+	if from.ConfigMapRef != nil {
+		return c.configMapData(from.ConfigMapRef.Name, from.ConfigMapRef.Optional)
+	}
+	if from.SecretRef != nil {
+		return c.secretData(from.SecretRef.Name, from.SecretRef.Optional)
+	}
+	return nil, nil
+}
+
+func (c *Controller) envVarValue(podSpec *corev1.PodSpec, e corev1.EnvVar) (string, error) {
+	if e.ValueFrom == nil {
+		return e.Value, nil
+	}
+	switch {
+	case e.ValueFrom.ConfigMapKeyRef != nil:
+		ref := e.ValueFrom.ConfigMapKeyRef
+		data, err := c.configMapData(ref.Name, ref.Optional)
+		return string(data[ref.Key]), err
+	case e.ValueFrom.SecretKeyRef != nil:
+		ref := e.ValueFrom.SecretKeyRef
+		data, err := c.secretData(ref.Name, ref.Optional)
+		return string(data[ref.Key]), err
+	case e.ValueFrom.FieldRef != nil:
+		switch e.ValueFrom.FieldRef.FieldPath {
+		case "metadata.namespace":
+			return c.NameSpace, nil
+		case "metadata.name":
+			return os.Hostname()
+		case "spec.serviceAccountName":
+			return podSpec.ServiceAccountName, nil
+		case "status.podIP", "status.hostIP":
+			return "127.0.0.1", nil
+		}
+	}
+	log.Debugf("Env %s can not be resolved locally, leave it empty", e.Name)
+	return "", nil
+}
+
+func (c *Controller) configMapData(name string, optional *bool) (map[string][]byte, error) {
+	cm, err := c.Client.GetConfigMaps(name)
+	if err != nil {
+		if k8serrors.IsNotFound(errors.Cause(err)) && optional != nil && *optional {
+			return map[string][]byte{}, nil
+		}
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	return data, nil
+}
+
+func (c *Controller) secretData(name string, optional *bool) (map[string][]byte, error) {
+	secret, err := c.Client.GetSecret(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) && optional != nil && *optional {
+			return map[string][]byte{}, nil
+		}
+		return nil, errors.Wrap(err, "")
+	}
+	return secret.Data, nil
+}
+
+// volumeFiles returns relative path -> content of files in the volume, nil if the volume can not be materialized
+func (c *Controller) volumeFiles(serviceAccount string, v *corev1.Volume) (map[string][]byte, error) {
+	switch {
+	case v.ConfigMap != nil:
+		data, err := c.configMapData(v.ConfigMap.Name, v.ConfigMap.Optional)
+		return keyToPathFiles(data, v.ConfigMap.Items), err
+	case v.Secret != nil:
+		data, err := c.secretData(v.Secret.SecretName, v.Secret.Optional)
+		return keyToPathFiles(data, v.Secret.Items), err
+	case v.Projected != nil:
+		files := map[string][]byte{}
+		for _, source := range v.Projected.Sources {
+			var (
+				data map[string][]byte
+				err  error
+			)
+			switch {
+			case source.ConfigMap != nil:
+				data, err = c.configMapData(source.ConfigMap.Name, source.ConfigMap.Optional)
+				data = keyToPathFiles(data, source.ConfigMap.Items)
+			case source.Secret != nil:
+				data, err = c.secretData(source.Secret.Name, source.Secret.Optional)
+				data = keyToPathFiles(data, source.Secret.Items)
+			case source.ServiceAccountToken != nil:
+				var token string
+				token, err = c.Client.CreateServiceAccountToken(
+					defaultServiceAccount(serviceAccount), localDevTokenExpirationSeconds,
+				)
+				data = map[string][]byte{source.ServiceAccountToken.Path: []byte(token)}
+			}
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range data {
+				files[k] = v
+			}
+		}
+		return files, nil
+	}
+	return nil, nil
+}
+
+func (c *Controller) serviceAccountFiles(serviceAccount string) (map[string][]byte, error) {
+	token, err := c.Client.CreateServiceAccountToken(defaultServiceAccount(serviceAccount), localDevTokenExpirationSeconds)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		corev1.ServiceAccountTokenKey:     []byte(token),
+		corev1.ServiceAccountNamespaceKey: []byte(c.NameSpace),
+	}
+	if ca, err := c.configMapData(serviceAccountRootCAConfigMap, nil); err == nil {
+		files[corev1.ServiceAccountRootCAKey] = ca[corev1.ServiceAccountRootCAKey]
+	}
+	return files, nil
+}
+
+func defaultServiceAccount(serviceAccount string) string {
+	if serviceAccount == "" {
+		return "default"
+	}
+	return serviceAccount
+}
+
+func keyToPathFiles(data map[string][]byte, items []corev1.KeyToPath) map[string][]byte {
+	if data == nil {
+		return nil
+	}
+	if len(items) == 0 {
+		return data
+	}
+	files := map[string][]byte{}
+	for _, item := range items {
+		if v, ok := data[item.Key]; ok {
+			files[item.Path] = v
+		}
+	}
+	return files
+}
+
+// writeLocalDevFiles writes files to mountPath, only the file of subPath is written if specified
+func writeLocalDevFiles(mountPath, subPath string, files map[string][]byte) error {
+	for p, content := range files {
+		target := filepath.Join(mountPath, filepath.FromSlash(p))
+		if subPath != "" {
+			if p != subPath {
+				continue
+			}
+			target = mountPath
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if err := os.WriteFile(target, content, 0600); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// RunLocalProcess runs `command.run` of the container with its env under workDir,
+// lease of DevMode is renewed while the process is running
+func (c *Controller) RunLocalProcess(container, workDir string) error {
+	if container == "" {
+		if svcProfile, err := c.GetProfile(); err == nil {
+			container = svcProfile.OriginDevContainer
+		}
+	}
+
+	var run []string
+	if devConfig := c.config.GetContainerDevConfigOrDefault(container); devConfig != nil && devConfig.Command != nil {
+		run = devConfig.Command.Run
+	}
+	if len(run) == 0 {
+		return errors.New(fmt.Sprintf("Run command of container %s is not configured", container))
+	}
+
+	localEnv, err := c.MaterializeLocalDevEnv(container)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(run[0], run[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), localEnv.Env...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if c.IsInDevMode() {
+		stop := make(chan struct{})
+		defer close(stop)
+		go c.keepDevLeaseAlive(stop)
+	}
+	log.Infof("Running %s in %s...", strings.Join(run, " "), workDir)
+	return errors.Wrap(cmd.Run(), "")
+}
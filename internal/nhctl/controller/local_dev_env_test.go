@@ -0,0 +1,67 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/profile"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteLocalDevFiles(t *testing.T) {
+	data := map[string][]byte{"app.yaml": []byte("port: 80"), "log.yaml": []byte("level: info")}
+	files := keyToPathFiles(data, []corev1.KeyToPath{{Key: "app.yaml", Path: "conf/app.yaml"}})
+	if len(files) != 1 || string(files["conf/app.yaml"]) != "port: 80" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	dir := t.TempDir()
+	if err := writeLocalDevFiles(filepath.Join(dir, "etc"), "", files); err != nil {
+		t.Fatal(err)
+	}
+	if bys, err := os.ReadFile(filepath.Join(dir, "etc", "conf", "app.yaml")); err != nil || string(bys) != "port: 80" {
+		t.Fatalf("unexpected content %s, err %v", bys, err)
+	}
+
+	if err := writeLocalDevFiles(filepath.Join(dir, "log.yaml"), "log.yaml", data); err != nil {
+		t.Fatal(err)
+	}
+	if bys, err := os.ReadFile(filepath.Join(dir, "log.yaml")); err != nil || string(bys) != "level: info" {
+		t.Fatalf("subPath should be mounted as a file, got %s, err %v", bys, err)
+	}
+}
+
+func TestLocalDevModeStatus(t *testing.T) {
+	meta := &appmeta.ApplicationMeta{DevMeta: appmeta.ApplicationDevMeta{}}
+	c := &Controller{Name: "reviews", Type: base.Deployment, Identifier: "me", AppMeta: meta}
+	other := &Controller{Name: "reviews", Type: base.Deployment, Identifier: "other", AppMeta: meta}
+	key := "reviews-" + string(profile.LocalDevMode) + "-me"
+
+	// starting
+	meta.DevMeta[base.Deployment.Alias()] = map[string]string{key + appmeta.DEV_STARTING_SUFFIX: "me"}
+	if !c.IsInDevMode() || !c.IsInDevModeStarting() || c.GetCurrentDevModeType() != profile.LocalDevMode {
+		t.Fatal("local DevMode should be starting")
+	}
+
+	// started
+	meta.DevMeta[base.Deployment.Alias()] = map[string]string{key: "me"}
+	if !c.IsInDevMode() || c.IsInDevModeStarting() || !c.IsProcessor() {
+		t.Fatal("local DevMode should be started by me")
+	}
+	if other.IsInDevMode() || other.IsProcessor() {
+		t.Fatal("local DevMode should not influence others")
+	}
+
+	// ended
+	delete(meta.DevMeta[base.Deployment.Alias()], key)
+	if c.IsInDevMode() || c.IsProcessor() || c.GetCurrentDevModeType() != profile.NoneDevMode {
+		t.Fatal("local DevMode should be ended")
+	}
+}
@@ -0,0 +1,101 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"nocalhost/internal/nhctl/daemon_client"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/model"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/vpn/util"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"strings"
+)
+
+// LocalPodController runs the workload as a local process instead of a dev container,
+// pods of the workload are reversed by vpn, so in-cluster traffic reaches the local process
+type LocalPodController struct {
+	*Controller
+}
+
+func (l *LocalPodController) ReplaceImage(ctx context.Context, ops *model.DevStartOptions) error {
+	l.Client.Context(ctx)
+
+	if len(ops.ExtraContainers) > 0 {
+		return errors.New("Extra dev containers are not supported by local DevMode")
+	}
+	if !util.IsSudoDaemonServing() {
+		return errors.New("Sudo daemon is not running, run 'nhctl vpn connect' first")
+	}
+
+	localEnv, err := l.MaterializeLocalDevEnv(ops.Container)
+	if err != nil {
+		return err
+	}
+	// vpn container will be injected, record the container to run locally before that
+	if err = l.UpdateSvcProfile(
+		func(svcProfile *profile.SvcProfileV2) error {
+			svcProfile.OriginDevContainer = localEnv.Container
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+
+	log.Infof("Reversing traffic of %s to local...", l.Name)
+	return l.sendVPNOperateCommand(command.Connect)
+}
+
+func (l *LocalPodController) RollBack(reset bool) error {
+	defer func() {
+		if err := os.RemoveAll(l.GetLocalDevDir()); err != nil {
+			log.WarnE(errors.Wrap(err, ""), "Failed to clean local dev dir")
+		}
+	}()
+
+	log.Infof("Cancelling traffic reversing of %s...", l.Name)
+	return l.sendVPNOperateCommand(command.DisConnect)
+}
+
+func (l *LocalPodController) reverseResource() string {
+	return fmt.Sprintf("%s/%s", l.Type.String(), l.Name)
+}
+
+func (l *LocalPodController) sendVPNOperateCommand(action command.VPNOperation) error {
+	client, err := daemon_client.GetDaemonClient(false)
+	if err != nil {
+		return err
+	}
+	return client.SendVPNOperateCommand(
+		l.Client.KubeConfigFilePath(), l.NameSpace, action, l.reverseResource(), func(r io.Reader) error {
+			stream := bufio.NewReader(r)
+			for {
+				line, _, err := stream.ReadLine()
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					return errors.Wrap(err, "")
+				}
+				switch {
+				case len(line) == 0:
+				case strings.Contains(string(line), util.EndSignOK):
+					return nil
+				case strings.Contains(string(line), util.EndSignFailed):
+					return errors.New(fmt.Sprintf("Failed to %s %s", action, l.reverseResource()))
+				default:
+					log.Info(string(line))
+				}
+			}
+		},
+	)
+}
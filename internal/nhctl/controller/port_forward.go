@@ -8,6 +8,7 @@ package controller
 import (
 	"fmt"
 	"github.com/pkg/errors"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/daemon_client"
 	"nocalhost/internal/nhctl/model"
 	"nocalhost/internal/nhctl/profile"
@@ -18,7 +19,7 @@ import (
 	"time"
 )
 
-func (c *Controller) EndDevPortForward(localPort int, remotePort int) error {
+func (c *Controller) EndDevPortForward(localPort int, remotePort int, protocol, role string) error {
 
 	svcProfile, err := c.GetProfile()
 	if err != nil {
@@ -26,7 +27,7 @@ func (c *Controller) EndDevPortForward(localPort int, remotePort int) error {
 	}
 
 	for _, portForward := range svcProfile.DevPortForwardList {
-		if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+		if portForward.Is(localPort, remotePort, protocol, role) {
 			client, err := daemon_client.GetDaemonClient(portForward.Sudo)
 			if err != nil {
 				return err
@@ -39,7 +40,7 @@ func (c *Controller) EndDevPortForward(localPort int, remotePort int) error {
 					Service:     c.Name,
 					ServiceType: string(c.Type),
 					PodName:     "",
-				}, localPort, remotePort,
+				}, localPort, remotePort, portForward.GetProtocol(), portForward.Role,
 			)
 		}
 	}
@@ -59,7 +60,7 @@ func StopPortForward(ns, nid, app, svc string, portForward *profile.DevPortForwa
 			Service:     svc,
 			ServiceType: portForward.ServiceType,
 			PodName:     portForward.PodName,
-		}, portForward.LocalPort, portForward.RemotePort,
+		}, portForward.LocalPort, portForward.RemotePort, portForward.GetProtocol(), portForward.Role,
 	)
 }
 
@@ -70,14 +71,19 @@ func (c *Controller) StopAllPortForward() error {
 	}
 
 	for _, portForward := range svcProfile.DevPortForwardList {
-		utils.Should(c.EndDevPortForward(portForward.LocalPort, portForward.RemotePort))
+		utils.Should(
+			c.EndDevPortForward(
+				portForward.LocalPort, portForward.RemotePort, portForward.GetProtocol(), portForward.Role,
+			),
+		)
 	}
 	return nil
 }
 
-// StopPortForwardByPort port format 8080:80
+// StopPortForwardByPort port format 8080:80, or udp:8125:8125
 func (c *Controller) StopPortForwardByPort(port string) error {
 
+	protocol, port := utils.GetPortForwardProtocol(port)
 	ports := strings.Split(port, ":")
 	localPort, err := strconv.Atoi(ports[0])
 	if err != nil {
@@ -87,11 +93,13 @@ func (c *Controller) StopPortForwardByPort(port string) error {
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
-	return c.EndDevPortForward(localPort, remotePort)
+	return c.EndDevPortForward(localPort, remotePort, protocol, "")
 }
 
-func (c *Controller) UpdatePortForwardStatus(localPort int, remotePort int, portStatus string, reason string) error {
-	pf, err := c.GetPortForward(localPort, remotePort)
+func (c *Controller) UpdatePortForwardStatus(
+	localPort int, remotePort int, protocol, role, portStatus string, reason string,
+) error {
+	pf, err := c.GetPortForward(localPort, remotePort, protocol, role)
 	if err != nil {
 		return err
 	}
@@ -107,7 +115,7 @@ func (c *Controller) UpdatePortForwardStatus(localPort int, remotePort int, port
 	return c.UpdateSvcProfile(
 		func(svcProfile *profile.SvcProfileV2) error {
 			for _, portForward := range svcProfile.DevPortForwardList {
-				if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+				if portForward.Is(localPort, remotePort, protocol, role) {
 					portForward.Status = portStatus
 					portForward.Reason = reason
 					portForward.Updated = time.Now().Format("2006-01-02 15:04:05")
@@ -120,13 +128,15 @@ func (c *Controller) UpdatePortForwardStatus(localPort int, remotePort int, port
 }
 
 // GetPortForward If not found return err
-func (c *Controller) GetPortForward(localPort, remotePort int) (*profile.DevPortForward, error) {
+func (c *Controller) GetPortForward(
+	localPort, remotePort int, protocol, role string,
+) (*profile.DevPortForward, error) {
 	svcProfile, err := c.GetProfile()
 	if err != nil {
 		return nil, err
 	}
 	for _, pf := range svcProfile.DevPortForwardList {
-		if pf.LocalPort == localPort && pf.RemotePort == remotePort {
+		if pf.Is(localPort, remotePort, protocol, role) {
 			return pf, nil
 		}
 	}
@@ -152,14 +162,52 @@ func (c *Controller) PortForwardAfterDevStart(podName, containerName string) err
 			log.WarnE(err, "")
 			continue
 		}
-		log.Infof("Forwarding %d:%d", lPort, rPort)
-		utils.Should(c.PortForward(podName, lPort, rPort, ""))
+		protocol, _ := utils.GetPortForwardProtocol(pf)
+		log.Infof("Forwarding %s %d:%d", protocol, lPort, rPort)
+		utils.Should(c.PortForwardByProtocol(podName, protocol, lPort, rPort, ""))
+	}
+
+	for _, pf := range cc.ReversePortForward {
+		lPort, rPort, err := utils.GetPortForwardForString(pf)
+		if err != nil {
+			log.WarnE(err, "")
+			continue
+		}
+		if existed, _ := c.CheckIfPortForwardExists(
+			lPort, rPort, _const.PortForwardTCP, _const.ReversePortForwardRole,
+		); existed {
+			continue
+		}
+		log.Infof("Reverse forwarding %d <- %d", lPort, rPort)
+		utils.Should(c.PortForward(podName, lPort, rPort, _const.ReversePortForwardRole))
 	}
 	return nil
 }
 
-// PortForward Role: If set to "SYNC", means it is a pf used for syncthing
+// reversePortForwardUsed reverse port-forward is tunnelled by ssh server of sidecar, so the ssh version
+// of sidecar running as root is used if it's configured, and the tunnel logs in as root by password,
+// see utils.DefaultRoot
+func (c *Controller) reversePortForwardUsed(container string) bool {
+	cc := c.Config().GetContainerDevConfigOrDefault(container)
+	return cc != nil && len(cc.ReversePortForward) > 0
+}
+
+// PortForward Role: If set to "SYNC", means it is a pf used for syncthing,
+// if set to "REVERSE", remotePort of dev container is forwarded to localPort
 func (c *Controller) PortForward(podName string, localPort, remotePort int, role string) error {
+	return c.PortForwardByProtocol(podName, _const.PortForwardTCP, localPort, remotePort, role)
+}
+
+// PortForwardByProtocol protocol is tcp or udp
+func (c *Controller) PortForwardByProtocol(podName, protocol string, localPort, remotePort int, role string) error {
+	return c.PortForwardWithIdleTimeout(podName, protocol, localPort, remotePort, role, "")
+}
+
+// PortForwardWithIdleTimeout the port-forward is stopped by daemon after no traffic for idleTimeout,
+// empty idleTimeout means never
+func (c *Controller) PortForwardWithIdleTimeout(
+	podName, protocol string, localPort, remotePort int, role, idleTimeout string,
+) error {
 
 	isAdmin := utils.IsSudoUser()
 	client, err := daemon_client.GetDaemonClient(isAdmin)
@@ -174,20 +222,65 @@ func (c *Controller) PortForward(podName string, localPort, remotePort int, role
 		PodName:     podName,
 	}
 
-	if err = client.SendStartPortForwardCommand(nhResource, localPort, remotePort, role, c.AppMeta.NamespaceId); err != nil {
+	if err = client.SendStartPortForwardCommand(
+		nhResource, localPort, remotePort, role, c.AppMeta.NamespaceId, protocol, idleTimeout,
+	); err != nil {
 		return err
 	} else {
 		return c.SetPortForwardedStatus(true) //  todo: move port-forward start
 	}
 }
 
-func (c *Controller) CheckIfPortForwardExists(localPort, remotePort int) (bool, error) {
+// PortForwardToService port-forward to the service, connections are balanced across its ready endpoints
+func (c *Controller) PortForwardToService(service string, localPort, remotePort int, idleTimeout string) error {
+	client, err := daemon_client.GetDaemonClient(utils.IsSudoUser())
+	if err != nil {
+		return err
+	}
+	nhResource := &model.NocalHostResource{
+		NameSpace:   c.NameSpace,
+		Application: c.AppName,
+		Service:     c.Name,
+		ServiceType: c.Type.String(),
+	}
+	if err = client.SendStartServicePortForwardCommand(
+		nhResource, service, localPort, remotePort, c.AppMeta.NamespaceId, idleTimeout,
+	); err != nil {
+		return err
+	}
+	return c.SetPortForwardedStatus(true)
+}
+
+// UpdatePortForwardEndpoints records ready endpoints of port-forward to service
+func (c *Controller) UpdatePortForwardEndpoints(localPort, remotePort int, endpoints []string) error {
+	status, reason := "LISTEN", fmt.Sprintf("%d ready endpoints", len(endpoints))
+	if len(endpoints) == 0 {
+		status, reason = "NO ENDPOINTS", "no ready endpoints"
+	}
+	return c.UpdateSvcProfile(
+		func(svcProfile *profile.SvcProfileV2) error {
+			for _, portForward := range svcProfile.DevPortForwardList {
+				// port-forward to service is always tcp
+				if portForward.Is(localPort, remotePort, _const.PortForwardTCP, "") {
+					portForward.Status = status
+					portForward.Reason = reason
+					portForward.Endpoints = endpoints
+					portForward.Updated = time.Now().Format("2006-01-02 15:04:05")
+					break
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func (c *Controller) CheckIfPortForwardExists(localPort, remotePort int, protocol, role string) (bool, error) {
 	svcProfile, err := c.GetProfile()
 	if err != nil {
 		return false, err
 	}
 	for _, portForward := range svcProfile.DevPortForwardList {
-		if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+		if portForward.Is(localPort, remotePort, protocol, role) {
 			return true, nil
 		}
 	}
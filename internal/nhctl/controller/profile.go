@@ -153,7 +153,7 @@ func (c *Controller) AddPortForwardToDB(port *profile.DevPortForward) error {
 	)
 }
 
-func (c *Controller) DeletePortForwardFromDB(localPort, remotePort int) error {
+func (c *Controller) DeletePortForwardFromDB(localPort, remotePort int, protocol, role string) error {
 
 	return c.UpdateSvcProfile(
 		func(svcProfile *profile.SvcProfileV2) error {
@@ -163,7 +163,7 @@ func (c *Controller) DeletePortForwardFromDB(localPort, remotePort int) error {
 
 			indexToDelete := -1
 			for index, portForward := range svcProfile.DevPortForwardList {
-				if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+				if portForward.Is(localPort, remotePort, protocol, role) {
 					indexToDelete = index
 					break
 				}
@@ -53,7 +53,9 @@ func (r *RawPodController) ReplaceImage(ctx context.Context, ops *model.DevStart
 		r.GetDevContainerName(ops.Container)
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		r.genContainersAndVolumes(&originalPod.Spec, ops.Container, ops.DevImage, ops.StorageClass, false)
+		r.genContainersAndVolumes(
+			&originalPod.Spec, ops.Container, ops.DevImage, ops.StorageClass, extraContainerNames(ops), false,
+		)
 	if err != nil {
 		return err
 	}
@@ -111,6 +113,8 @@ func (r *RawPodController) RollBack(reset bool) error {
 	return nil
 }
 
+// findDevContainerInPodSpec the returned container may be an init container,
+// use isInitContainer to check it
 func findDevContainerInPodSpec(pod *corev1.PodSpec, containerName string) (*corev1.Container, error) {
 	var devContainer *corev1.Container
 
@@ -120,6 +124,11 @@ func findDevContainerInPodSpec(pod *corev1.PodSpec, containerName string) (*core
 				return &pod.Containers[index], nil
 			}
 		}
+		for index, c := range pod.InitContainers {
+			if c.Name == containerName {
+				return &pod.InitContainers[index], nil
+			}
+		}
 		return nil, errors.New(fmt.Sprintf("Container %s not found", containerName))
 	} else {
 		if len(pod.Containers) > 1 {
@@ -137,3 +146,13 @@ func findDevContainerInPodSpec(pod *corev1.PodSpec, containerName string) (*core
 	}
 	return devContainer, nil
 }
+
+// isInitContainer returns true if container points to one of the init containers of pod
+func isInitContainer(pod *corev1.PodSpec, container *corev1.Container) bool {
+	for i := range pod.InitContainers {
+		if &pod.InitContainers[i] == container {
+			return true
+		}
+	}
+	return false
+}
@@ -26,6 +26,7 @@ import (
 	"nocalhost/internal/nhctl/watcher"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -45,6 +46,11 @@ type Controller struct {
 	config           *profile.ServiceConfigV2
 	DevModeAction    base.DevModeAction
 	devModePodLabels kblabels.Set
+
+	// objects are collected here instead of being created while rendering in dry-run
+	dryRun *DevModeDryRunResult
+	// syncEngine overrides the configured sync engine, see fallbackSyncEngineOfInitContainer
+	syncEngine string
 }
 
 type jsonPatch struct {
@@ -93,18 +99,39 @@ func (c *Controller) IsInDuplicateDevModeStarting() bool {
 	return c.AppMeta.CheckIfSvcDeveloping(c.Name, c.Identifier, c.Type, profile.DuplicateDevMode) == appmeta.STARTING
 }
 
+func (c *Controller) IsInEphemeralDevMode() bool {
+	return c.AppMeta.CheckIfSvcDeveloping(c.Name, c.Identifier, c.Type, profile.EphemeralDevMode) != appmeta.NONE
+}
+
+func (c *Controller) IsInEphemeralDevModeStarting() bool {
+	return c.AppMeta.CheckIfSvcDeveloping(c.Name, c.Identifier, c.Type, profile.EphemeralDevMode) == appmeta.STARTING
+}
+
+func (c *Controller) IsInLocalDevMode() bool {
+	return c.AppMeta.CheckIfSvcDeveloping(c.Name, c.Identifier, c.Type, profile.LocalDevMode) != appmeta.NONE
+}
+
+func (c *Controller) IsInLocalDevModeStarting() bool {
+	return c.AppMeta.CheckIfSvcDeveloping(c.Name, c.Identifier, c.Type, profile.LocalDevMode) == appmeta.STARTING
+}
+
 func (c *Controller) IsInDevMode() bool {
-	return c.IsInDuplicateDevMode() || c.IsInReplaceDevMode()
+	return c.IsInDuplicateDevMode() || c.IsInReplaceDevMode() || c.IsInEphemeralDevMode() || c.IsInLocalDevMode()
 }
 
 func (c *Controller) IsInDevModeStarting() bool {
-	return c.IsInDuplicateDevModeStarting() || c.IsInReplaceDevModeStarting()
+	return c.IsInDuplicateDevModeStarting() || c.IsInReplaceDevModeStarting() ||
+		c.IsInEphemeralDevModeStarting() || c.IsInLocalDevModeStarting()
 }
 
 // IsProcessor Check if service is developing in this device
 func (c *Controller) IsProcessor() bool {
 	return c.AppMeta.SvcDevModePossessor(
 		c.Name, c.Type, c.Identifier, profile.DuplicateDevMode,
+	) || c.AppMeta.SvcDevModePossessor(
+		c.Name, c.Type, c.Identifier, profile.EphemeralDevMode,
+	) || c.AppMeta.SvcDevModePossessor(
+		c.Name, c.Type, c.Identifier, profile.LocalDevMode,
 	) || c.AppMeta.SvcDevModePossessor(c.Name, c.Type, c.Identifier, profile.ReplaceDevMode)
 }
 
@@ -237,6 +264,7 @@ func (c *Controller) getDevContainerAnnotations(devContainer string, originAnnos
 	}
 
 	originAnnos[_const.NocalhostDevContainerAnnotations] = c.GetDevContainerName(devContainer)
+	originAnnos[_const.DevSyncEngineAnnotation] = c.GetSyncEngine(devContainer)
 	return originAnnos
 }
 
@@ -255,6 +283,14 @@ func (c *Controller) patchAfterDevContainerReplaced(containerName, resourceType,
 	<-time.Tick(time.Second)
 }
 
+// patchWorkload patches the resource in cluster, or obj in memory while dry-running
+func (c *Controller) patchWorkload(obj *unstructured.Unstructured, resourceType, resourceName, patch, patchType string) error {
+	if c.dryRun != nil {
+		return PatchUnstructured(obj, patch, patchType)
+	}
+	return c.Client.Patch(resourceType, resourceName, patch, patchType)
+}
+
 func (c *Controller) getGeneratedDeploymentName() string {
 	id, _ := utils.GetShortUuid()
 	return fmt.Sprintf("%s-gen-%s", c.Name, id)
@@ -275,7 +311,21 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 	if err != nil {
 		return err
 	}
+	if err = c.patchDevModeManifest(unstructuredObj, ops); err != nil {
+		return err
+	}
+	if c.dryRun == nil {
+		c.waitDevPodToBeReady()
+	}
+	return nil
+}
 
+// patchDevModeManifest patches unstructuredObj to DevMode in cluster, or only in memory while dry-running,
+// the rendered workload(or the generated deployment if DevModeAction.Create) is recorded to c.dryRun then
+func (c *Controller) patchDevModeManifest(unstructuredObj *unstructured.Unstructured, ops *model.DevStartOptions) error {
+	var err error
+	// hpa recorded by a previous DevMode which was not ended correctly
+	recordedHPA := unstructuredObj.GetAnnotations()[_const.HPAOriginDefinition]
 	RemoveUselessInfo(unstructuredObj)
 
 	var originalSpecJson []byte
@@ -295,25 +345,45 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 		}
 	}
 
-	mBytes, _ := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]string{_const.OriginWorkloadDefinition: string(originalSpecJson)}}})
+	devAnnotations := map[string]string{_const.OriginWorkloadDefinition: string(originalSpecJson)}
+	if ops.Ordinal != nil {
+		if err = c.checkOrdinal(unstructuredObj, *ops.Ordinal); err != nil {
+			return err
+		}
+		devAnnotations[_const.DevModeOrdinal] = strconv.Itoa(*ops.Ordinal)
+	}
+
+	mBytes, _ := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": devAnnotations}})
 
-	if err = c.Client.Patch(c.Type.String(), c.Name, string(mBytes), "merge"); err != nil {
+	if err = c.patchWorkload(unstructuredObj, c.Type.String(), c.Name, string(mBytes), "merge"); err != nil {
 		return err
 	}
 	log.Info("Original manifest recorded")
 
-	log.Info("Executing ScalePatches...")
-	for _, item := range c.DevModeAction.ScalePatches {
-		log.Infof("Patching %s(%s)", item.Patch, item.Type)
-		if err := c.Client.Patch(c.Type.String(), c.Name, item.Patch, item.Type); err != nil {
-			return err
+	// other pods keep running while developing a single ordinal, so hpa and replicas are untouched
+	if ops.Ordinal == nil {
+		if c.dryRun == nil {
+			log.Info("Suspending hpa...")
+			if err = c.suspendHPA(recordedHPA); err != nil {
+				return err
+			}
+		}
+
+		log.Info("Executing ScalePatches...")
+		for _, item := range c.DevModeAction.ScalePatches {
+			log.Infof("Patching %s(%s)", item.Patch, item.Type)
+			if err := c.patchWorkload(unstructuredObj, c.Type.String(), c.Name, item.Patch, item.Type); err != nil {
+				return err
+			}
 		}
 	}
 
 	podSpec := &podTemplate.Spec
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		c.genContainersAndVolumes(podSpec, ops.Container, ops.DevImage, ops.StorageClass, false)
+		c.genContainersAndVolumes(
+			podSpec, ops.Container, ops.DevImage, ops.StorageClass, extraContainerNames(ops), false,
+		)
 	if err != nil {
 		return err
 	}
@@ -322,28 +392,26 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 
 	if !c.DevModeAction.Create {
 
-		log.Info("Update strategy to RECREATE")
-		specPath := "/spec/strategy"
-		strategy := &appsv1.DeploymentStrategy{
-			Type:          appsv1.RecreateDeploymentStrategyType,
-			RollingUpdate: nil,
-		}
-		jsonPatches := make([]jsonPatch, 0)
-		jsonPatches = append(
-			jsonPatches, jsonPatch{
-				Op:    "replace",
-				Path:  specPath,
-				Value: strategy,
-			},
-		)
-		bys, _ := json.Marshal(jsonPatches)
-		if err = c.Client.Patch(c.Type.String(), c.Name, string(bys), "json"); err != nil {
-			log.WarnE(err, "")
+		var ordinalStrategy *appsv1.StatefulSetUpdateStrategy
+		if ops.Ordinal != nil {
+			if ordinalStrategy, err = c.patchOrdinalUpdateStrategy(unstructuredObj, *ops.Ordinal); err != nil {
+				return err
+			}
+		} else {
+			log.Info("Update strategy to RECREATE")
+			strategy := &appsv1.DeploymentStrategy{
+				Type:          appsv1.RecreateDeploymentStrategyType,
+				RollingUpdate: nil,
+			}
+			bys, _ := json.Marshal([]jsonPatch{{Op: "replace", Path: "/spec/strategy", Value: strategy}})
+			if err = c.patchWorkload(unstructuredObj, c.Type.String(), c.Name, string(bys), "json"); err != nil {
+				log.WarnE(err, "")
+			}
 		}
 
 		log.Info("Patching development container...")
-		specPath = c.DevModeAction.PodTemplatePath + "/spec"
-		jsonPatches = make([]jsonPatch, 0)
+		specPath := c.DevModeAction.PodTemplatePath + "/spec"
+		jsonPatches := make([]jsonPatch, 0)
 		jsonPatches = append(
 			jsonPatches, jsonPatch{
 				Op:    "replace",
@@ -351,9 +419,9 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 				Value: podSpec,
 			},
 		)
-		bys, _ = json.Marshal(jsonPatches)
+		bys, _ := json.Marshal(jsonPatches)
 
-		if err = c.Client.Patch(c.Type.String(), c.Name, string(bys), "json"); err != nil {
+		if err = c.patchWorkload(unstructuredObj, c.Type.String(), c.Name, string(bys), "json"); err != nil {
 			return err
 		}
 
@@ -362,7 +430,7 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 		jsonPatches = make([]jsonPatch, 0)
 		jsonPatches = append(
 			jsonPatches, jsonPatch{
-				Op:    "replace",
+				Op:    "add", // pod template may have no annotations yet
 				Path:  specPath,
 				Value: c.getDevContainerAnnotations(ops.Container, podTemplate.GetAnnotations()),
 			},
@@ -370,11 +438,25 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 		bys, _ = json.Marshal(jsonPatches)
 		patchContent := string(bys)
 
-		if err = c.Client.Patch(c.Type.String(), c.Name, patchContent, "json"); err != nil {
+		if err = c.patchWorkload(unstructuredObj, c.Type.String(), c.Name, patchContent, "json"); err != nil {
 			return err
 		}
 
+		if c.dryRun != nil {
+			if err = c.patchUnstructuredWithDevConfig(unstructuredObj, ops.Container); err != nil {
+				return err
+			}
+			c.dryRun.Workload = unstructuredObj
+			return nil
+		}
+
 		c.patchAfterDevContainerReplaced(ops.Container, c.Type.String(), c.Name)
+
+		if ordinalStrategy != nil && ordinalStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			if err = c.recreateOrdinalPod(*ops.Ordinal); err != nil {
+				return err
+			}
+		}
 	} else {
 		// Some workload's pod may not have labels, such as cronjob, we need to give it one
 		if len(podTemplate.Labels) == 0 {
@@ -397,6 +479,9 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 		generatedDeployment.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyAlways
 		generatedDeployment.Spec.Strategy.RollingUpdate = nil
 		generatedDeployment.Spec.Strategy.Type = appsv1.RecreateDeploymentStrategyType
+		if c.dryRun != nil {
+			return c.renderGeneratedDeployment(generatedDeployment, ops.Container)
+		}
 		if _, err = c.Client.CreateDeployment(generatedDeployment); err != nil {
 			return err
 		}
@@ -405,8 +490,6 @@ func (c *Controller) PatchDevModeManifest(ctx context.Context, ops *model.DevSta
 
 	delete(podTemplate.Labels, "pod-template-hash")
 	c.devModePodLabels = podTemplate.Labels
-
-	c.waitDevPodToBeReady()
 	return nil
 }
 
@@ -0,0 +1,135 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/pkg/nhctl/log"
+	"strconv"
+	"time"
+)
+
+// checkOrdinal makes sure the pod of ordinal exists in the StatefulSet
+func (c *Controller) checkOrdinal(obj *unstructured.Unstructured, ordinal int) error {
+	if c.Type != base.StatefulSet {
+		return errors.New(fmt.Sprintf("Ordinal is only supported by %s", base.StatefulSet))
+	}
+	if !c.DevModeType.IsReplaceDevMode() {
+		return errors.New("Ordinal is only supported by replace DevMode")
+	}
+	replicas := statefulSetReplicas(obj)
+	if ordinal < 0 || ordinal >= replicas {
+		return errors.New(fmt.Sprintf("Ordinal %d out of range, %s has %d replicas", ordinal, c.Name, replicas))
+	}
+	return nil
+}
+
+func statefulSetReplicas(obj *unstructured.Unstructured) int {
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil || !found {
+		return 1
+	}
+	return int(replicas)
+}
+
+// ordinalUpdateStrategy only the pod of ordinal picks up the dev template:
+// if it is the last one, partitioned rolling update is enough,
+// otherwise pods with larger ordinals would be updated too, so update it on delete
+func ordinalUpdateStrategy(replicas, ordinal int) *appsv1.StatefulSetUpdateStrategy {
+	if ordinal == replicas-1 {
+		partition := int32(ordinal)
+		return &appsv1.StatefulSetUpdateStrategy{
+			Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+		}
+	}
+	return &appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+}
+
+func (c *Controller) ordinalPodName(ordinal int) string {
+	return fmt.Sprintf("%s-%d", c.Name, ordinal)
+}
+
+// patchOrdinalUpdateStrategy must be called before patching dev container to the pod template
+func (c *Controller) patchOrdinalUpdateStrategy(obj *unstructured.Unstructured, ordinal int) (
+	*appsv1.StatefulSetUpdateStrategy, error) {
+	strategy := ordinalUpdateStrategy(statefulSetReplicas(obj), ordinal)
+	if strategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		log.Warnf(
+			"Pods except %s will not be updated until DevMode ends, "+
+				"but they will pick up the dev container if deleted in DevMode", c.ordinalPodName(ordinal),
+		)
+	}
+	bys, _ := json.Marshal([]jsonPatch{{Op: "replace", Path: "/spec/updateStrategy", Value: strategy}})
+	log.Infof("Update strategy to %s", strategy.Type)
+	return strategy, c.patchWorkload(obj, c.Type.String(), c.Name, string(bys), "json")
+}
+
+// recreateOrdinalPod makes the pod of ordinal pick up the current pod template under OnDelete strategy
+func (c *Controller) recreateOrdinalPod(ordinal int) error {
+	log.Infof("Recreating pod %s...", c.ordinalPodName(ordinal))
+	return c.Client.DeletePod(c.ordinalPodName(ordinal), false, 30*time.Second)
+}
+
+func getDevModeOrdinal(obj *unstructured.Unstructured) (int, bool) {
+	o, ok := obj.GetAnnotations()[_const.DevModeOrdinal]
+	if !ok {
+		return 0, false
+	}
+	ordinal, err := strconv.Atoi(o)
+	if err != nil {
+		return 0, false
+	}
+	return ordinal, true
+}
+
+// rollbackOrdinal restores pod template and update strategy(partition) of the StatefulSet in place,
+// instead of recreating it, which would restart all the pods
+func (c *Controller) rollbackOrdinal(devModeWorkload *unstructured.Unstructured, ordinal int, originalSpecJson string) error {
+	original := &appsv1.StatefulSet{}
+	if err := json.Unmarshal([]byte(originalSpecJson), original); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Invalid annotation %s", _const.OriginWorkloadDefinition))
+	}
+
+	log.Info("Restoring pod template...")
+	bys, _ := json.Marshal([]jsonPatch{{Op: "replace", Path: "/spec/template", Value: original.Spec.Template}})
+	if err := c.Client.Patch(c.Type.String(), c.Name, string(bys), "json"); err != nil {
+		return err
+	}
+
+	strategyType, _, _ := unstructured.NestedString(devModeWorkload.Object, "spec", "updateStrategy", "type")
+	if strategyType == string(appsv1.OnDeleteStatefulSetStrategyType) {
+		if err := c.recreateOrdinalPod(ordinal); err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to recreate pod %s", c.ordinalPodName(ordinal)))
+		}
+	}
+
+	log.Info("Restoring update strategy...")
+	bys, _ = json.Marshal(
+		[]jsonPatch{{Op: "replace", Path: "/spec/updateStrategy", Value: original.Spec.UpdateStrategy}},
+	)
+	if err := c.Client.Patch(c.Type.String(), c.Name, string(bys), "json"); err != nil {
+		return err
+	}
+
+	mBytes, _ := json.Marshal(
+		map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					_const.OriginWorkloadDefinition: nil,
+					_const.DevModeOrdinal:           nil,
+				},
+			},
+		},
+	)
+	return c.Client.Patch(c.Type.String(), c.Name, string(mBytes), "merge")
+}
@@ -0,0 +1,23 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"testing"
+)
+
+func TestOrdinalUpdateStrategy(t *testing.T) {
+	s := ordinalUpdateStrategy(3, 2)
+	if s.Type != appsv1.RollingUpdateStatefulSetStrategyType || *s.RollingUpdate.Partition != 2 {
+		t.Fatalf("the last ordinal should be updated by partition, got %v", s)
+	}
+
+	s = ordinalUpdateStrategy(3, 1)
+	if s.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		t.Fatalf("ordinal in the middle should be updated on delete, got %v", s)
+	}
+}
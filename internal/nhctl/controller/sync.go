@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"io/ioutil"
 	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncengine"
 	"nocalhost/internal/nhctl/syncthing"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
@@ -19,11 +20,12 @@ import (
 )
 
 func (c *Controller) StopFileSyncOnly() error {
+	utils.Should(syncengine.Stop(c.GetSyncDir()))
 
 	pf, err := c.GetPortForwardForSync()
 	utils.Should(err)
 	if pf != nil {
-		utils.Should(c.EndDevPortForward(pf.LocalPort, pf.RemotePort))
+		utils.Should(c.EndDevPortForward(pf.LocalPort, pf.RemotePort, pf.GetProtocol(), pf.Role))
 	}
 
 	// read and clean up pid file
@@ -0,0 +1,250 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/syncthing"
+	"nocalhost/internal/nhctl/syncthing/conflict"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ConflictKeepLocal  = "local"
+	ConflictKeepRemote = "remote"
+	ConflictMerge      = "merge"
+)
+
+// SyncConflictVersion local or remote version of a conflicted file
+type SyncConflictVersion struct {
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	Deleted bool      `json:"deleted,omitempty"`
+	content []byte
+}
+
+// SyncConflict file whose local and remote versions diverged
+type SyncConflict struct {
+	Path string `json:"path"`
+	// ConflictCopy copy created by syncthing for the losing version,
+	// empty if the folder is send only and remote version only resides in dev container
+	ConflictCopy string              `json:"conflictCopy,omitempty"`
+	Local        SyncConflictVersion `json:"local"`
+	Remote       SyncConflictVersion `json:"remote"`
+	Diff         string              `json:"diff"`
+}
+
+// ListSyncConflicts conflict copies of the local sync dir, and files changed remotely
+// which are never synced back to send only folder
+func (c *Controller) ListSyncConflicts(container string) ([]*SyncConflict, error) {
+	localDir, err := c.localSyncDir()
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := map[string]*SyncConflict{}
+	remoteShortID := conflict.ShortDeviceID(syncthing.DefaultRemoteDeviceID)
+	err = filepath.Walk(
+		localDir, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			original, modifiedBy, ok := conflict.ParseConflictCopy(p)
+			if !ok {
+				return nil
+			}
+			rel, _ := filepath.Rel(localDir, original)
+			copyRel, _ := filepath.Rel(localDir, p)
+			sc := &SyncConflict{Path: filepath.ToSlash(rel), ConflictCopy: filepath.ToSlash(copyRel)}
+			// conflict copy holds the version of the device who modified it
+			if modifiedBy == remoteShortID {
+				sc.Local, sc.Remote = readConflictVersion(original), readConflictVersion(p)
+			} else {
+				sc.Local, sc.Remote = readConflictVersion(p), readConflictVersion(original)
+			}
+			conflicts[sc.Path] = sc
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	if c.isSendOnlySync(container) {
+		needs, err := c.NewSyncthingHttpClient(10).FolderNeed()
+		if err != nil {
+			log.WarnE(err, "Failed to get files changed remotely, is file sync running?")
+		}
+		for _, need := range needs {
+			if _, ok := conflicts[need.Name]; ok {
+				continue
+			}
+			sc := &SyncConflict{
+				Path:   need.Name,
+				Local:  readConflictVersion(filepath.Join(localDir, filepath.FromSlash(need.Name))),
+				Remote: SyncConflictVersion{ModTime: need.Modified, Size: need.Size, Deleted: need.Deleted},
+			}
+			if !need.Deleted {
+				if sc.Remote.content, err = c.readRemoteFile(container, need.Name); err != nil {
+					return nil, err
+				}
+			}
+			conflicts[sc.Path] = sc
+		}
+	}
+
+	result := make([]*SyncConflict, 0, len(conflicts))
+	for _, sc := range conflicts {
+		sc.Diff = conflict.UnifiedDiff(
+			path.Join(ConflictKeepLocal, sc.Path), path.Join(ConflictKeepRemote, sc.Path),
+			sc.Local.content, sc.Remote.content,
+		)
+		result = append(result, sc)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// ResolveSyncConflict keeps local or remote version of the conflicted file, or merges them with conflict markers,
+// the resolution is propagated by syncthing
+func (c *Controller) ResolveSyncConflict(container string, sc *SyncConflict, resolution string) error {
+	localDir, err := c.localSyncDir()
+	if err != nil {
+		return err
+	}
+	client := c.NewSyncthingHttpClient(10)
+	target := filepath.Join(localDir, filepath.FromSlash(sc.Path))
+
+	// remote version only resides in dev container, local version is pushed again by touching it,
+	// so that it's newer than the remote one, other files of the folder are untouched
+	if sc.ConflictCopy == "" && resolution == ConflictKeepLocal {
+		if sc.Local.Deleted {
+			return c.removeRemoteFile(container, sc.Path)
+		}
+		touched := sc.Local
+		touched.ModTime = time.Now()
+		if err = writeConflictVersion(target, touched); err != nil {
+			return err
+		}
+		return client.ScanSub(sc.Path)
+	}
+
+	switch resolution {
+	case ConflictKeepLocal:
+		err = writeConflictVersion(target, sc.Local)
+	case ConflictKeepRemote:
+		err = writeConflictVersion(target, sc.Remote)
+	case ConflictMerge:
+		if conflict.IsBinary(sc.Local.content) || conflict.IsBinary(sc.Remote.content) {
+			return errors.New(fmt.Sprintf("Binary file %s can not be merged", sc.Path))
+		}
+		err = writeConflictVersion(
+			target, SyncConflictVersion{
+				ModTime: time.Now(),
+				content: conflict.MergeWithMarkers(
+					ConflictKeepLocal, ConflictKeepRemote, sc.Local.content, sc.Remote.content,
+				),
+			},
+		)
+	default:
+		return errors.New(fmt.Sprintf("Unsupported resolution %s", resolution))
+	}
+	if err != nil {
+		return err
+	}
+
+	subs := []string{sc.Path}
+	if sc.ConflictCopy != "" {
+		if err = os.Remove(filepath.Join(localDir, filepath.FromSlash(sc.ConflictCopy))); err != nil &&
+			!os.IsNotExist(err) {
+			return errors.Wrap(err, "")
+		}
+		subs = append(subs, sc.ConflictCopy)
+	}
+	return client.ScanSub(subs...)
+}
+
+func (c *Controller) localSyncDir() (string, error) {
+	svcProfile, err := c.GetProfile()
+	if err != nil {
+		return "", err
+	}
+	if len(svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin) == 0 {
+		return "", errors.New(fmt.Sprintf("No local sync dir found for %s", c.Name))
+	}
+	return svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin[0], nil
+}
+
+// isSendOnlySync local files are synced to remote in one direction unless sync type is sendReceive
+func (c *Controller) isSendOnlySync(container string) bool {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	return devConfig == nil || devConfig.Sync == nil || devConfig.Sync.Type != _const.DefaultSyncType
+}
+
+func (c *Controller) readRemoteFile(container, name string) ([]byte, error) {
+	podName, err := c.GetDevModePodName()
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err = c.Client.ExecWithStream(
+		podName, c.GetDevContainerName(container), []string{"cat", "--", path.Join(c.GetWorkDir(container), name)},
+		nil, stdout, stderr,
+	); err != nil {
+		return nil, errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (c *Controller) removeRemoteFile(container, name string) error {
+	podName, err := c.GetDevModePodName()
+	if err != nil {
+		return err
+	}
+	stderr := &bytes.Buffer{}
+	if err = c.Client.ExecWithStream(
+		podName, c.GetDevContainerName(container), []string{"rm", "-f", "--", path.Join(c.GetWorkDir(container), name)},
+		nil, nil, stderr,
+	); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func readConflictVersion(p string) SyncConflictVersion {
+	info, err := os.Stat(p)
+	if err != nil {
+		return SyncConflictVersion{Deleted: true}
+	}
+	content, _ := ioutil.ReadFile(p)
+	return SyncConflictVersion{ModTime: info.ModTime(), Size: info.Size(), content: content}
+}
+
+func writeConflictVersion(p string, v SyncConflictVersion) error {
+	if v.Deleted {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := ioutil.WriteFile(p, v.content, _const.DefaultNewFilePermission); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return errors.Wrap(os.Chtimes(p, v.ModTime, v.ModTime), "")
+}
@@ -0,0 +1,69 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"io"
+	"nocalhost/internal/nhctl/syncengine"
+	"strings"
+)
+
+// NewExecSyncEngine engine pushing files of localSyncDir to dev container of podName through exec api,
+// args are the args of nhctl serving the engine in background
+func (c *Controller) NewExecSyncEngine(container, podName string, localSyncDir []string,
+	args []string) (*syncengine.ExecEngine, error) {
+	if len(localSyncDir) == 0 {
+		return nil, errors.New("Local sync dir can not be empty")
+	}
+	if len(localSyncDir) > 1 {
+		return nil, errors.New("Sync engine exec only supports one local sync dir")
+	}
+	// files are pushed only, nothing is synced back
+	if len(c.GetReversePaths(container)) > 0 {
+		return nil, errors.New("Reverse paths are not supported by sync engine exec")
+	}
+
+	e := &syncengine.ExecEngine{
+		LocalDir:  localSyncDir[0],
+		RemoteDir: c.GetWorkDir(container),
+		Home:      c.GetSyncDir(),
+		Args:      args,
+		Executor:  c.devContainerExecutor(container, podName),
+	}
+	if devConfig := c.Config().GetContainerDevConfigOrDefault(container); devConfig != nil && devConfig.Sync != nil {
+		e.IgnoredPattern = devConfig.Sync.IgnoreFilePattern
+		e.IgnoreFrom = devConfig.Sync.IgnoreFrom
+	}
+	return e, nil
+}
+
+// GetDevSyncEngine engine syncing files of the service in DevMode, which is recorded while entering DevMode,
+// it may differ from the configured one, see fallbackSyncEngineOfInitContainer
+func (c *Controller) GetDevSyncEngine() string {
+	svcProfile, err := c.GetProfile()
+	if err != nil {
+		return c.GetSyncEngine("")
+	}
+	if svcProfile.SyncEngine != "" {
+		return svcProfile.SyncEngine
+	}
+	return c.GetSyncEngine(svcProfile.OriginDevContainer)
+}
+
+// devContainerExecutor runs commands in dev container of podName, stderr is attached to the error
+func (c *Controller) devContainerExecutor(container, podName string) syncengine.Executor {
+	return func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+		stderr := &bytes.Buffer{}
+		if err := c.Client.ExecWithStream(
+			podName, c.GetDevContainerName(container), cmd, stdin, stdout, stderr,
+		); err != nil {
+			return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,179 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	secret_config "nocalhost/internal/nhctl/syncthing/secret-config"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SyncMappingRemoteHome remote dirs of sync mappings are mounted under this dir in sidecar
+const SyncMappingRemoteHome = "/var/nocalhost-mapping"
+
+// GetSyncMappings sync mappings of container, besides the local sync dir to workDir
+func (c *Controller) GetSyncMappings(container string) []*profile.SyncMapping {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	if devConfig == nil || devConfig.Sync == nil {
+		return nil
+	}
+	return devConfig.Sync.Mappings
+}
+
+// SyncMappingRemoteSyncDir the dir which remote dir of the ith sync mapping mounted to in sidecar
+func SyncMappingRemoteSyncDir(i int) string {
+	return path.Join(SyncMappingRemoteHome, strconv.Itoa(i+1))
+}
+
+func syncMappingFolderName(i int) string {
+	return fmt.Sprintf("map-%d", i+1)
+}
+
+func syncMappingVolumeName(i int) string {
+	return fmt.Sprintf("nocalhost-sync-mapping-%d", i+1)
+}
+
+// syncMappingIgnoreKey key of .stignore of the ith sync mapping in syncthing secret
+func syncMappingIgnoreKey(i int) string {
+	return fmt.Sprintf("stignore-map-%d", i+1)
+}
+
+// syncMappingIgnore patterns of .stignore of the mapping, both local and remote folders use them
+func syncMappingIgnore(m *profile.SyncMapping) []string {
+	lines := make([]string, 0, len(m.IgnoreFilePattern))
+	for _, p := range m.IgnoreFilePattern {
+		if strings.HasPrefix(p, "./") {
+			p = p[1:]
+		}
+		lines = append(lines, p)
+	}
+	return lines
+}
+
+// syncMappingFolders syncthing folders of mappings, relative local dir is resolved by localRoot
+func syncMappingFolders(mappings []*profile.SyncMapping, localRoot string) ([]*syncthing.Folder, error) {
+	folders := make([]*syncthing.Folder, 0, len(mappings))
+	for i, m := range mappings {
+		local := m.Local
+		if !filepath.IsAbs(local) {
+			local = filepath.Join(localRoot, local)
+		}
+		if info, err := os.Stat(local); err != nil || !info.IsDir() {
+			return nil, errors.New(fmt.Sprintf("Local dir %s of sync mapping is not found", local))
+		}
+		// type of local sync dir is used if not specified
+		folderType := m.Type
+		if folderType == _const.SendOnlySyncTypeAlias {
+			folderType = _const.SendOnlySyncType
+		}
+		folders = append(
+			folders, &syncthing.Folder{
+				Name:       syncMappingFolderName(i),
+				LocalPath:  local,
+				RemotePath: SyncMappingRemoteSyncDir(i),
+				Type:       folderType,
+				// patterns of the shared .nhignore are relative to the local sync dir, not for mappings
+				Ignore: syncMappingIgnore(m),
+			},
+		)
+	}
+	return folders, nil
+}
+
+// genSyncMappingVolumes emptyDir for each sync mapping, it's mounted to the remote dir in dev container,
+// and to SyncMappingRemoteSyncDir in sidecar, .stignore of the mapping is copied from syncthing secret.
+// The emptyDir hides contents of the image under the remote dir, they're replaced by the local dir
+func (c *Controller) genSyncMappingVolumes(container string, devContainer, sidecar *corev1.Container,
+	volumes []corev1.Volume) []corev1.Volume {
+	mappings := c.GetSyncMappings(container)
+	if len(mappings) == 0 {
+		return volumes
+	}
+
+	prepare := make([]string, 0)
+	for i, m := range mappings {
+		volumes = append(
+			volumes, corev1.Volume{
+				Name:         syncMappingVolumeName(i),
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			},
+		)
+		devContainer.VolumeMounts = append(
+			devContainer.VolumeMounts, corev1.VolumeMount{Name: syncMappingVolumeName(i), MountPath: m.Remote},
+		)
+		sidecar.VolumeMounts = append(
+			sidecar.VolumeMounts,
+			corev1.VolumeMount{Name: syncMappingVolumeName(i), MountPath: SyncMappingRemoteSyncDir(i)},
+		)
+		if len(m.IgnoreFilePattern) > 0 {
+			prepare = append(
+				prepare, fmt.Sprintf(
+					"cp %s %s", path.Join(secret_config.DefaultSyncthingSecretHome, syncMappingIgnoreKey(i)),
+					path.Join(SyncMappingRemoteSyncDir(i), ".stignore"),
+				),
+			)
+		}
+	}
+
+	if len(prepare) > 0 {
+		// .stignore is in secret volume, whose items are listed explicitly
+		defaultMode := int32(_const.DefaultNewFilePermission)
+		for i := range volumes {
+			if volumes[i].Name != secret_config.SecretName || volumes[i].Secret == nil {
+				continue
+			}
+			for j, m := range mappings {
+				if len(m.IgnoreFilePattern) > 0 {
+					volumes[i].Secret.Items = append(
+						volumes[i].Secret.Items,
+						corev1.KeyToPath{Key: syncMappingIgnoreKey(j), Path: syncMappingIgnoreKey(j), Mode: &defaultMode},
+					)
+				}
+			}
+		}
+		if len(sidecar.Args) > 0 {
+			sidecar.Args[0] = strings.Join(prepare, " && ") + " && " + sidecar.Args[0]
+		}
+	}
+	return volumes
+}
+
+// syncMappingSecretData .stignore of sync mappings stored in syncthing secret
+func (c *Controller) syncMappingSecretData(container string, data map[string][]byte) {
+	for i, m := range c.GetSyncMappings(container) {
+		if len(m.IgnoreFilePattern) > 0 {
+			data[syncMappingIgnoreKey(i)] = []byte(strings.Join(syncMappingIgnore(m), "\n") + "\n")
+		}
+	}
+}
+
+// GetSyncMappingStatus status of each sync mapping folder
+func (c *Controller) GetSyncMappingStatus(container string) []*req.FolderSyncStatus {
+	mappings := c.GetSyncMappings(container)
+	result := make([]*req.FolderSyncStatus, 0, len(mappings))
+	client := c.NewSyncthingHttpClient(2)
+	for i, m := range mappings {
+		result = append(
+			result, &req.FolderSyncStatus{
+				Folder:          syncMappingFolderName(i),
+				Local:           m.Local,
+				Remote:          m.Remote,
+				SyncthingStatus: client.WithFolder("nh-" + syncMappingFolderName(i)).GetSyncthingStatus(),
+			},
+		)
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"nocalhost/internal/nhctl/profile"
+	secret_config "nocalhost/internal/nhctl/syncthing/secret-config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyncMappingFolders(t *testing.T) {
+	root := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(root, "proto"), 0700)
+	mappings := []*profile.SyncMapping{
+		{Local: "proto", Remote: "/usr/src/proto", IgnoreFilePattern: []string{"./build", "*.tmp"}},
+		{Local: root, Remote: "/opt/conf", Type: "send"},
+	}
+
+	folders, err := syncMappingFolders(mappings, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folders[0].LocalPath != filepath.Join(root, "proto") || folders[1].LocalPath != root {
+		t.Fatalf("unexpected local path %s, %s", folders[0].LocalPath, folders[1].LocalPath)
+	}
+	// each mapping has its own ignore file, even if it has no patterns
+	if !reflect.DeepEqual(folders[0].Ignore, []string{"/build", "*.tmp"}) {
+		t.Fatalf("unexpected ignore of mapping %v", folders[0].Ignore)
+	}
+	if folders[1].Ignore == nil || len(folders[1].Ignore) != 0 {
+		t.Fatalf("mapping without patterns should not use the shared ignore file, got %v", folders[1].Ignore)
+	}
+	if folders[1].Type != "sendonly" {
+		t.Fatalf("unexpected type %s", folders[1].Type)
+	}
+
+	if _, err = syncMappingFolders([]*profile.SyncMapping{{Local: "missing", Remote: "/a"}}, root); err == nil {
+		t.Fatal("missing local dir should fail")
+	}
+}
+
+func TestGenSyncMappingVolumes(t *testing.T) {
+	c := &Controller{
+		config: &profile.ServiceConfigV2{
+			ContainerConfigs: []*profile.ContainerConfig{
+				{
+					Name: "reviews",
+					Dev: &profile.ContainerDevConfig{
+						Sync: &profile.SyncConfig{
+							Mappings: []*profile.SyncMapping{
+								{Local: "proto", Remote: "/usr/src/proto", IgnoreFilePattern: []string{"*.tmp"}},
+								{Local: "conf", Remote: "/opt/conf"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	dev := &corev1.Container{Name: "reviews"}
+	sidecar := &corev1.Container{Name: "nocalhost-sidecar", Args: []string{"serve"}}
+	volumes := []corev1.Volume{
+		{
+			Name:         secret_config.SecretName,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{}},
+		},
+	}
+
+	volumes = c.genSyncMappingVolumes("reviews", dev, sidecar, volumes)
+	if len(volumes) != 3 || len(dev.VolumeMounts) != 2 || len(sidecar.VolumeMounts) != 2 {
+		t.Fatalf("expected an emptyDir for each mapping, got %d volumes", len(volumes))
+	}
+	if dev.VolumeMounts[1].MountPath != "/opt/conf" || sidecar.VolumeMounts[1].MountPath != SyncMappingRemoteSyncDir(1) {
+		t.Fatalf("unexpected mounts %v, %v", dev.VolumeMounts, sidecar.VolumeMounts)
+	}
+	// only .stignore of the mapping with patterns is copied
+	if items := volumes[0].Secret.Items; len(items) != 1 || items[0].Key != syncMappingIgnoreKey(0) {
+		t.Fatalf("unexpected secret items %v", items)
+	}
+	if !strings.HasPrefix(sidecar.Args[0], "cp ") || !strings.HasSuffix(sidecar.Args[0], " && serve") {
+		t.Fatalf("unexpected sidecar args %s", sidecar.Args[0])
+	}
+
+	data := map[string][]byte{}
+	c.syncMappingSecretData("reviews", data)
+	if len(data) != 1 || string(data[syncMappingIgnoreKey(0)]) != "*.tmp\n" {
+		t.Fatalf("unexpected secret data %v", data)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/syncengine"
+	"nocalhost/internal/nhctl/syncthing"
+	"nocalhost/internal/nhctl/syncthing/ignore"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SyncVerifyResult differences between local dir and remote dir of a sync folder
+type SyncVerifyResult struct {
+	Folder string `json:"folder"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+	// Missing files exist locally only
+	Missing []string `json:"missing"`
+	// Extra files exist remotely only, such as files generated in dev container
+	Extra     []string `json:"extra"`
+	Differing []string `json:"differing"`
+
+	ignored func(rel string) bool
+}
+
+// Converged local files are all synced, extra remote files are not taken into account
+func (r *SyncVerifyResult) Converged() bool {
+	return len(r.Missing) == 0 && len(r.Differing) == 0
+}
+
+// VerifySync compares content hashes of the local sync dir and sync mappings with the ones of dev container,
+// files ignored by sync are skipped
+func (c *Controller) VerifySync(container string) ([]*SyncVerifyResult, error) {
+	localDir, err := c.localSyncDir()
+	if err != nil {
+		return nil, err
+	}
+	podName, err := c.GetDevModePodName()
+	if err != nil {
+		return nil, err
+	}
+	executor := c.devContainerExecutor(container, podName)
+
+	ignored, err := c.syncIgnoredMatcher(localDir)
+	if err != nil {
+		return nil, err
+	}
+	results := []*SyncVerifyResult{
+		{Folder: syncthing.DefaultFolderName, Local: localDir, Remote: c.GetWorkDir(container), ignored: ignored},
+	}
+	mappingFolders, err := syncMappingFolders(c.GetSyncMappings(container), localDir)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range c.GetSyncMappings(container) {
+		// patterns of mapping are applied in dev container besides the common ones
+		patterns := append(ignore.Parse(syncMappingIgnore(m)), "/.stignore")
+		results = append(
+			results, &SyncVerifyResult{
+				Folder: "nh-" + mappingFolders[i].Name,
+				Local:  mappingFolders[i].LocalPath,
+				Remote: m.Remote,
+				ignored: func(rel string) bool {
+					return ignore.Match(patterns, rel) || ignored(rel)
+				},
+			},
+		)
+	}
+
+	for _, r := range results {
+		log.Infof("Verifying %s and %s", r.Local, r.Remote)
+		local, err := hashLocalDir(r.Local, r.ignored)
+		if err != nil {
+			return nil, err
+		}
+		remote, err := syncengine.HashRemoteDir(executor, r.Remote)
+		if err != nil {
+			return nil, err
+		}
+		r.compare(local, remote)
+	}
+	return results, nil
+}
+
+func (r *SyncVerifyResult) compare(local, remote map[string]string) {
+	r.Missing, r.Extra, r.Differing = make([]string, 0), make([]string, 0), make([]string, 0)
+	for p, hash := range local {
+		remoteHash, ok := remote[p]
+		if !ok {
+			r.Missing = append(r.Missing, p)
+		} else if remoteHash != hash {
+			r.Differing = append(r.Differing, p)
+		}
+	}
+	for p := range remote {
+		if _, ok := local[p]; !ok && !r.ignored(p) {
+			r.Extra = append(r.Extra, p)
+		}
+	}
+	sort.Strings(r.Missing)
+	sort.Strings(r.Extra)
+	sort.Strings(r.Differing)
+}
+
+// FixSync rescans the affected paths, and overrides remote changes of send only folder,
+// extra remote files are kept
+func (c *Controller) FixSync(container string, results []*SyncVerifyResult) error {
+	client := c.NewSyncthingHttpClient(10)
+	for _, r := range results {
+		if r.Converged() {
+			continue
+		}
+		folderClient := client.WithFolder(r.Folder)
+		if err := folderClient.ScanSub(append(append([]string{}, r.Missing...), r.Differing...)...); err != nil {
+			return err
+		}
+		if len(r.Differing) == 0 || !c.isSendOnlySync(container) {
+			continue
+		}
+		needs, err := folderClient.FolderNeed()
+		if err != nil {
+			return err
+		}
+		if len(needs) > 0 {
+			log.Infof("Overriding remote changes of %s", r.Folder)
+			if err = folderClient.FolderOverride(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncIgnoredMatcher matches files ignored by the ignore file of running syncthing
+func (c *Controller) syncIgnoredMatcher(localDir string) (func(rel string) bool, error) {
+	ignoreFile := filepath.Join(c.GetSyncDir(), syncthing.IgnoredFIle)
+	content, err := ioutil.ReadFile(ignoreFile)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Failed to read %s, is file sync running?", ignoreFile))
+	}
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines {
+		if strings.TrimSpace(line) == syncthing.EnableParseFromGitIgnore {
+			patterns, err := ignore.Derive(localDir, []string{ignore.GitIgnoreFile})
+			if err != nil {
+				return nil, err
+			}
+			return func(rel string) bool { return ignore.Match(patterns, rel) }, nil
+		}
+	}
+	patterns := ignore.Parse(lines)
+	return func(rel string) bool { return ignore.Match(patterns, rel) }, nil
+}
+
+// hashLocalDir hashes files of dir which are not ignored, keyed by slash path relative to dir
+func hashLocalDir(dir string, ignored func(rel string) bool) (map[string]string, error) {
+	result := map[string]string{}
+	err := filepath.Walk(
+		dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil || rel == "." {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if ignored(rel) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			hash, err := syncengine.HashFile(p)
+			if err != nil {
+				if os.IsNotExist(errors.Cause(err)) {
+					return nil
+				}
+				return err
+			}
+			result[rel] = hash
+			return nil
+		},
+	)
+	return result, errors.Wrap(err, "")
+}
@@ -9,15 +9,20 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/internal/nhctl/profile"
 	"nocalhost/internal/nhctl/syncthing/network/req"
 	"nocalhost/internal/nhctl/syncthing/ports"
 	secret_config "nocalhost/internal/nhctl/syncthing/secret-config"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"nocalhost/internal/nhctl/syncthing"
 	"nocalhost/pkg/nhctl/log"
@@ -28,6 +33,9 @@ func (c *Controller) NewSyncthing(container string, localSyncDir []string, syncD
 ) {
 	var err error
 	remotePath := c.GetWorkDir(container)
+	if c.DevModeType.IsEphemeralDevMode() {
+		remotePath = path.Join(EphemeralTargetRootFs, remotePath)
+	}
 	appProfile, err := c.GetProfileForUpdate()
 	if err != nil {
 		return nil, err
@@ -103,17 +111,9 @@ func (c *Controller) NewSyncthing(container string, localSyncDir []string, syncD
 		Type:             sendMode, // sendonly mode
 		Folders:          []*syncthing.Folder{},
 		RescanInterval:   "300",
+		Compression:      _const.SyncCompressionMetadata,
 	}
-	svcConfig := c.Config()
-	devConfig := svcConfig.GetContainerDevConfigOrDefault(container)
-	if devConfig != nil && devConfig.Sync != nil {
-		// enable delete protection by default
-		// or use the val user specify
-		s.IgnoreDelete = devConfig.Sync.DeleteProtection == nil || *devConfig.Sync.DeleteProtection
-		s.EnableParseFromGitIgnore = devConfig.Sync.Mode == _const.GitIgnoreMode
-		s.SyncedPattern = devConfig.Sync.FilePattern
-		s.IgnoredPattern = devConfig.Sync.IgnoreFilePattern
-	}
+	applySyncConfig(s, c.Config().GetContainerDevConfigOrDefault(container))
 
 	// TODO, warn: multi local sync dir is Deprecated, now it's implement by IgnoreFiles
 	// before creating syncthing sidecar, it need to know how many directories it should sync
@@ -136,10 +136,175 @@ func (c *Controller) NewSyncthing(container string, localSyncDir []string, syncD
 			index++
 		}
 	}
-	_ = appProfile.Save()
+
+	// files generated in dev container under reverse paths are synced back only,
+	// they're ignored by the folders above, see applySyncConfig
+	if len(s.Folders) > 0 {
+		for i, reversePath := range c.GetReversePaths(container) {
+			s.Folders = append(
+				s.Folders,
+				&syncthing.Folder{
+					Name:       fmt.Sprintf("reverse-%d", i+1),
+					LocalPath:  filepath.Join(s.Folders[0].LocalPath, filepath.FromSlash(reversePath)),
+					RemotePath: path.Join(remotePath, reversePath),
+					Type:       _const.ReceiveOnlySyncType,
+					RemoteType: _const.SendOnlySyncType,
+					// everything sent by dev container is received, patterns of the shared .nhignore are not for it
+					Ignore: []string{},
+				},
+			)
+		}
+	}
+
+	if len(s.Folders) > 0 {
+		mappingFolders, err := syncMappingFolders(c.GetSyncMappings(container), s.Folders[0].LocalPath)
+		if err != nil {
+			return nil, err
+		}
+		s.Folders = append(s.Folders, mappingFolders...)
+	}
+
+	// extra dev containers' workDir are mounted to sidecar, served by the same syncthing
+	extraContainers := make([]string, 0, len(svcProfile.ExtraDevContainers))
+	for extraContainer := range svcProfile.ExtraDevContainers {
+		extraContainers = append(extraContainers, extraContainer)
+	}
+	sort.Strings(extraContainers)
+	for _, extraContainer := range extraContainers {
+		s.Folders = append(
+			s.Folders,
+			&syncthing.Folder{
+				Name:       extraContainer,
+				LocalPath:  svcProfile.ExtraDevContainers[extraContainer],
+				RemotePath: ExtraDevContainerRemoteSyncDir(extraContainer),
+			},
+		)
+	}
+	// ports allocated while dry-running are not recorded
+	if c.dryRun == nil {
+		_ = appProfile.Save()
+	}
 	return s, nil
 }
 
+func applySyncConfig(s *syncthing.Syncthing, devConfig *profile.ContainerDevConfig) {
+	if devConfig != nil && devConfig.Sync != nil {
+		// enable delete protection by default
+		// or use the val user specify
+		s.IgnoreDelete = devConfig.Sync.DeleteProtection == nil || *devConfig.Sync.DeleteProtection
+		s.EnableParseFromGitIgnore = devConfig.Sync.Mode == _const.GitIgnoreMode
+		s.SyncedPattern = devConfig.Sync.FilePattern
+		s.IgnoredPattern = devConfig.Sync.IgnoreFilePattern
+		s.IgnoreFrom = devConfig.Sync.IgnoreFrom
+		s.ExcludedPaths = make([]string, 0, len(devConfig.Sync.ReversePaths))
+		for _, reversePath := range devConfig.Sync.ReversePaths {
+			s.ExcludedPaths = append(s.ExcludedPaths, cleanReversePath(reversePath))
+		}
+		if tuning := devConfig.Sync.Tuning; tuning != nil {
+			s.MaxSendKbps, s.MaxRecvKbps = tuning.MaxSendKbps, tuning.MaxRecvKbps
+			if tuning.Compression != "" {
+				s.Compression = tuning.Compression
+			}
+			if tuning.RescanIntervalS > 0 {
+				s.RescanInterval = strconv.Itoa(tuning.RescanIntervalS)
+			}
+			if tuning.FsWatcherDelayS > 0 {
+				s.FileWatcherDelay = tuning.FsWatcherDelayS
+			}
+			if tuning.MaxFileSize != "" {
+				if q, err := resource.ParseQuantity(tuning.MaxFileSize); err == nil {
+					s.MaxFileSize = q.Value()
+				}
+			}
+		}
+	}
+}
+
+// GetReversePaths paths relative to workDir of container, which are synced from dev container only
+func (c *Controller) GetReversePaths(container string) []string {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	if devConfig == nil || devConfig.Sync == nil {
+		return nil
+	}
+	paths := make([]string, 0, len(devConfig.Sync.ReversePaths))
+	for _, p := range devConfig.Sync.ReversePaths {
+		paths = append(paths, cleanReversePath(p))
+	}
+	return paths
+}
+
+// cleanReversePath returns the reverse path relative to workDir in slash form
+func cleanReversePath(reversePath string) string {
+	return strings.Trim(path.Clean(filepath.ToSlash(reversePath)), "/")
+}
+
+// RegenerateSyncIgnoreIfChanged regenerates ignore file of syncthing if patterns derived from
+// .gitignore or .dockerignore changed, and rescans the folder to make it effective
+func (c *Controller) RegenerateSyncIgnoreIfChanged(container string) error {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	if devConfig == nil || devConfig.Sync == nil {
+		return nil
+	}
+	// large files may be generated at any time
+	if len(devConfig.Sync.IgnoreFrom) == 0 && (devConfig.Sync.Tuning == nil || devConfig.Sync.Tuning.MaxFileSize == "") {
+		return nil
+	}
+	svcProfile, err := c.GetProfile()
+	if err != nil {
+		return err
+	}
+	if len(svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin) == 0 {
+		return nil
+	}
+
+	s := &syncthing.Syncthing{
+		LocalHome: c.GetSyncDir(),
+		Folders:   []*syncthing.Folder{{Name: "1", LocalPath: svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin[0]}},
+	}
+	applySyncConfig(s, devConfig)
+	if mappingFolders, err := syncMappingFolders(
+		devConfig.Sync.Mappings, svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin[0],
+	); err == nil {
+		s.Folders = append(s.Folders, mappingFolders...)
+	}
+	changed, err := s.RegenerateIgnoredFileConfig()
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+	log.Infof("Ignored pattern of %s changed, rescanning", c.Name)
+	client := c.NewSyncthingHttpClient(2)
+	for _, folder := range changed {
+		if err = client.WithFolder("nh-" + folder).Scan(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplySyncTuning applies tuning of sync config to running syncthing without restarting DevMode,
+// tuning of the remote syncthing takes effect after DevMode restarted
+func (c *Controller) ApplySyncTuning(container string) error {
+	devConfig := c.Config().GetContainerDevConfigOrDefault(container)
+	s := &syncthing.Syncthing{
+		Compression:      _const.SyncCompressionMetadata,
+		FileWatcherDelay: syncthing.DefaultFileWatcherDelay,
+	}
+	applySyncConfig(s, devConfig)
+	rescanInterval, _ := strconv.Atoi(s.RescanInterval)
+	if err := c.NewSyncthingHttpClient(10).ApplyTuning(
+		req.Tuning{
+			MaxSendKbps:     s.MaxSendKbps,
+			MaxRecvKbps:     s.MaxRecvKbps,
+			Compression:     s.Compression,
+			RescanIntervalS: rescanInterval,
+			FsWatcherDelayS: s.FileWatcherDelay,
+		},
+	); err != nil {
+		return err
+	}
+	return c.RegenerateSyncIgnoreIfChanged(container)
+}
+
 func (c *Controller) NewSyncthingHttpClient(reqTimeoutSecond int) *req.SyncthingHttpClient {
 	svcProfile, _ := c.GetProfile()
 
@@ -152,34 +317,44 @@ func (c *Controller) NewSyncthingHttpClient(reqTimeoutSecond int) *req.Syncthing
 	)
 }
 
-func (c *Controller) CreateSyncThingSecret(container string, localSyncDir []string, duplicateDevMode bool) error {
-
-	// Delete service folder
-	dir := c.GetSyncDir()
-	if err2 := os.RemoveAll(dir); err2 != nil {
-		log.Logf("Failed to delete dir: %s before starting syncthing, err: %v", dir, err2)
-	}
-
+func (c *Controller) genSyncThingSecret(container string, localSyncDir []string) (*corev1.Secret, error) {
 	newSyncthing, err := c.NewSyncthing(container, localSyncDir, false)
 	if err != nil {
-		return errors.Wrap(err, "Failed to create syncthing process")
+		return nil, errors.Wrap(err, "Failed to create syncthing process")
 	}
 	// set syncthing secret
 	config, err := newSyncthing.GetRemoteConfigXML()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	data := map[string][]byte{
+		"config.xml": config,
+		"cert.pem":   []byte(secret_config.CertPEM),
+		"key.pem":    []byte(secret_config.KeyPEM),
 	}
+	c.syncMappingSecretData(container, data)
 
-	syncSecret := &corev1.Secret{
+	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: c.GetSyncThingSecretName(),
 		},
 		Type: corev1.SecretTypeOpaque,
-		Data: map[string][]byte{
-			"config.xml": config,
-			"cert.pem":   []byte(secret_config.CertPEM),
-			"key.pem":    []byte(secret_config.KeyPEM),
-		},
+		Data: data,
+	}, nil
+}
+
+func (c *Controller) CreateSyncThingSecret(container string, localSyncDir []string, duplicateDevMode bool) error {
+
+	// Delete service folder
+	dir := c.GetSyncDir()
+	if err2 := os.RemoveAll(dir); err2 != nil {
+		log.Logf("Failed to delete dir: %s before starting syncthing, err: %v", dir, err2)
+	}
+
+	syncSecret, err := c.genSyncThingSecret(container, localSyncDir)
+	if err != nil {
+		return err
 	}
 
 	// check if secret exist
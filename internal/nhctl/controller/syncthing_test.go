@@ -0,0 +1,59 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing"
+	"reflect"
+	"testing"
+)
+
+func newReversePathsController(mode string) *Controller {
+	return &Controller{
+		Name: "reviews",
+		config: &profile.ServiceConfigV2{
+			ContainerConfigs: []*profile.ContainerConfig{
+				{
+					Name: "reviews",
+					Dev: &profile.ContainerDevConfig{
+						Sync: &profile.SyncConfig{
+							Mode:              mode,
+							IgnoreFilePattern: []string{"*.log"},
+							ReversePaths:      []string{"./gen/", "build/out"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestReversePathsExcluded(t *testing.T) {
+	for _, mode := range []string{_const.PatternMode, _const.GitIgnoreMode} {
+		c := newReversePathsController(mode)
+		if paths := c.GetReversePaths("reviews"); !reflect.DeepEqual(paths, []string{"gen", "build/out"}) {
+			t.Fatalf("unexpected reverse paths %v", paths)
+		}
+
+		s := &syncthing.Syncthing{}
+		applySyncConfig(s, c.Config().GetContainerDevConfigOrDefault("reviews"))
+		if !reflect.DeepEqual(s.ExcludedPaths, []string{"gen", "build/out"}) {
+			t.Fatalf("reverse paths should be excluded in %s mode, got %v", mode, s.ExcludedPaths)
+		}
+		if !reflect.DeepEqual(s.IgnoredPattern, []string{"*.log"}) {
+			t.Fatalf("reverse paths should not leak into ignored pattern, got %v", s.IgnoredPattern)
+		}
+	}
+}
+
+func TestReversePathsRejectedByExecEngine(t *testing.T) {
+	c := newReversePathsController(_const.PatternMode)
+	if _, err := c.NewExecSyncEngine("reviews", "reviews-0", []string{t.TempDir()}, nil); err == nil {
+		t.Fatal("reverse paths should be rejected by sync engine exec")
+	}
+}
@@ -47,6 +47,9 @@ func (c *Controller) GetPodList() ([]corev1.Pod, error) {
 	if c.DevModeType.IsDuplicateDevMode() {
 		return c.GetDuplicateModePodList()
 	}
+	if c.DevModeType.IsEphemeralDevMode() {
+		return c.GetEphemeralModePodList()
+	}
 	if c.Type == base.Pod {
 		pod, err := c.Client.GetPod(c.Name)
 		if err != nil {
@@ -201,6 +204,10 @@ func (c *Controller) RollbackFromAnnotation(reset bool) error {
 		log.Infof("Annotation %s found, use it", _const.OriginWorkloadDefinition)
 	}
 
+	if ordinal, ok := getDevModeOrdinal(devModeWorkload); ok {
+		return c.rollbackOrdinal(devModeWorkload, ordinal, osj)
+	}
+
 	originalWorkload, err = c.Client.GetResourceInfoFromString(osj, true)
 	if err != nil {
 		return err
@@ -235,7 +242,15 @@ func (c *Controller) RollbackFromAnnotation(reset bool) error {
 		}
 	}
 
-	return c.Client.ApplyResourceInfo(originalWorkload[0], nil)
+	if err = c.Client.ApplyResourceInfo(originalWorkload[0], nil); err != nil {
+		return err
+	}
+
+	// restore hpa after the original workload recovered, or it will scale the dev workload
+	if err = c.restoreHPA(devModeWorkload); err != nil {
+		log.WarnE(err, "Failed to restore hpa")
+	}
+	return nil
 }
 
 // GetUnstructuredMapByPath Path must be like: /spec/template
@@ -358,6 +373,8 @@ func RemoveUselessInfo(u *unstructured.Unstructured) {
 		return
 	}
 	delete(a, _const.OriginWorkloadDefinition)
+	delete(a, _const.HPAOriginDefinition)
+	delete(a, _const.DevModeOrdinal)
 	delete(a, "kubectl.kubernetes.io/last-applied-configuration")
 	delete(a, OriginSpecJson) // remove deprecated annotation
 	u.SetAnnotations(a)
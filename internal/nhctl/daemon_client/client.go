@@ -268,6 +268,47 @@ func (d *DaemonClient) SendGetDaemonServerStatusCommand() (*daemon_common.Daemon
 	return status, nil
 }
 
+// SendGetPortForwardStatsCommand get traffic of port-forwards running in daemon
+func (d *DaemonClient) SendGetPortForwardStatsCommand() ([]*daemon_common.PortForwardStats, error) {
+	cmd := &command.BaseCommand{CommandType: command.GetPortForwardStats, ClientStack: string(debug.Stack())}
+	bys, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	stats := make([]*daemon_common.PortForwardStats, 0)
+	if err = d.sendAndWaitForResponse(bys, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// SendNamespaceForwardCommand returns status of namespace forwards, all of them for list action
+func (d *DaemonClient) SendNamespaceForwardCommand(
+	kubeconfig, ns string, action command.NamespaceForwardOperation, hostsFile, dnsAddress string,
+) ([]*daemon_common.NamespaceForwardStatus, error) {
+	cmd := &command.NamespaceForwardCommand{
+		CommandType: command.NamespaceForward,
+		ClientStack: string(debug.Stack()),
+
+		KubeConfig: kubeconfig,
+		Namespace:  ns,
+		Action:     action,
+		HostsFile:  hostsFile,
+		DNSAddress: dnsAddress,
+	}
+	bys, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	status := make([]*daemon_common.NamespaceForwardStatus, 0)
+	if err = d.sendAndWaitForResponse(bys, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
 func (d *DaemonClient) SendAuthCheckCommand(ns, kubeConfigContent string, needChecks ...string) (bool, error) {
 	acCmd := &command.AuthCheckCommand{
 		CommandType: command.AuthCheck,
@@ -329,7 +370,7 @@ func (d *DaemonClient) SendGetApplicationMetasCommand(ns, kubeConfig string) ([]
 }
 
 func (d *DaemonClient) SendStartPortForwardCommand(
-	nhSvc *model.NocalHostResource, localPort, remotePort int, role, nid string,
+	nhSvc *model.NocalHostResource, localPort, remotePort int, role, nid, protocol, idleTimeout string,
 ) error {
 
 	startPFCmd := &command.PortForwardCommand{
@@ -345,6 +386,36 @@ func (d *DaemonClient) SendStartPortForwardCommand(
 		RemotePort:  remotePort,
 		Role:        role,
 		Nid:         nid,
+		Protocol:    protocol,
+		IdleTimeout: idleTimeout,
+	}
+
+	bys, err := json.Marshal(startPFCmd)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return d.sendAndWaitForResponse(bys, nil)
+}
+
+// SendStartServicePortForwardCommand port-forward to endpoints of service instead of a pod
+func (d *DaemonClient) SendStartServicePortForwardCommand(
+	nhSvc *model.NocalHostResource, service string, localPort, remotePort int, nid, idleTimeout string,
+) error {
+
+	startPFCmd := &command.PortForwardCommand{
+		CommandType: command.StartPortForward,
+		ClientStack: string(debug.Stack()),
+
+		NameSpace:     nhSvc.NameSpace,
+		AppName:       nhSvc.Application,
+		Service:       nhSvc.Service,
+		ServiceType:   nhSvc.ServiceType,
+		LocalPort:     localPort,
+		RemotePort:    remotePort,
+		Nid:           nid,
+		TargetService: service,
+		IdleTimeout:   idleTimeout,
 	}
 
 	bys, err := json.Marshal(startPFCmd)
@@ -356,7 +427,9 @@ func (d *DaemonClient) SendStartPortForwardCommand(
 }
 
 // SendStopPortForwardCommand send port forward to daemon
-func (d *DaemonClient) SendStopPortForwardCommand(nhSvc *model.NocalHostResource, localPort, remotePort int) error {
+func (d *DaemonClient) SendStopPortForwardCommand(
+	nhSvc *model.NocalHostResource, localPort, remotePort int, protocol, role string,
+) error {
 
 	startPFCmd := &command.PortForwardCommand{
 		CommandType: command.StopPortForward,
@@ -370,6 +443,8 @@ func (d *DaemonClient) SendStopPortForwardCommand(nhSvc *model.NocalHostResource
 		PodName:     nhSvc.PodName,
 		LocalPort:   localPort,
 		RemotePort:  remotePort,
+		Protocol:    protocol,
+		Role:        role,
 	}
 
 	bys, err := json.Marshal(startPFCmd)
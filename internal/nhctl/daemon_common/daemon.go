@@ -9,6 +9,7 @@ import (
 	"context"
 	"github.com/pkg/errors"
 	"io/ioutil"
+	"nocalhost/internal/nhctl/pfstats"
 	"nocalhost/internal/nhctl/syncthing/daemon"
 	"nocalhost/internal/nhctl/utils"
 	"path/filepath"
@@ -40,15 +41,54 @@ type CheckClusterStatus struct {
 }
 
 type PortForwardProfile struct {
-	Cancel     context.CancelFunc `json:"-"` // For canceling a port forward
-	StopCh     chan error         `json:"-"`
-	NameSpace  string             `json:"nameSpace"`
-	AppName    string             `json:"appName"`
-	SvcName    string             `json:"svcName"`
-	SvcType    string             `json:"svcType"`
-	Role       string             `json:"role"`
-	LocalPort  int                `json:"localPort"`
-	RemotePort int                `json:"remotePort"`
+	Cancel     context.CancelCauseFunc `json:"-"` // For canceling a port forward, cause tells why it's canceled
+	StopCh     chan error              `json:"-"`
+	NameSpace  string                  `json:"nameSpace"`
+	AppName    string                  `json:"appName"`
+	SvcName    string                  `json:"svcName"`
+	SvcType    string                  `json:"svcType"`
+	Role       string                  `json:"role"`
+	LocalPort  int                     `json:"localPort"`
+	RemotePort int                     `json:"remotePort"`
+	Protocol   string                  `json:"protocol,omitempty"`
+	Counter    *pfstats.Counter        `json:"-"` // traffic of the port-forward
+}
+
+// PortForwardStats traffic of a running port-forward
+type PortForwardStats struct {
+	NameSpace  string `json:"nameSpace"`
+	AppName    string `json:"appName"`
+	SvcName    string `json:"svcName"`
+	SvcType    string `json:"svcType"`
+	Role       string `json:"role"`
+	LocalPort  int    `json:"localPort"`
+	RemotePort int    `json:"remotePort"`
+	Protocol   string `json:"protocol,omitempty"`
+	pfstats.Stats
+}
+
+// NamespaceForwardStatus services of the namespace forwarded to local addresses
+type NamespaceForwardStatus struct {
+	Namespace  string                     `json:"namespace"`
+	KubeConfig string                     `json:"kubeConfig"`
+	HostsFile  string                     `json:"hostsFile,omitempty"`
+	HostsError string                     `json:"hostsError,omitempty"` // the hosts block is not written
+	DNSAddress string                     `json:"dnsAddress,omitempty"`
+	Services   []*NamespaceForwardService `json:"services"`
+}
+
+type NamespaceForwardService struct {
+	Name      string                 `json:"name"`
+	Address   string                 `json:"address"`
+	Hostnames []string               `json:"hostnames,omitempty"` // empty if not published, see nsforward.Publishable
+	Ports     []NamespaceForwardPort `json:"ports"`
+}
+
+// NamespaceForwardPort LocalPort differs from Port if Port can not be listened on Address
+type NamespaceForwardPort struct {
+	Name      string `json:"name"`
+	Port      int    `json:"port"`
+	LocalPort int    `json:"localPort"`
 }
 
 type DaemonServerStatusResponse struct {
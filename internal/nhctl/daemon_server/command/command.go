@@ -32,6 +32,8 @@ const (
 	VPNStatus             DaemonCommandType = "VPNStatus"
 	SudoVPNStatus         DaemonCommandType = "SudoVPNStatus"
 	AuthCheck             DaemonCommandType = "AuthCheck"
+	GetPortForwardStats   DaemonCommandType = "GetPortForwardStats"
+	NamespaceForward      DaemonCommandType = "NamespaceForward"
 
 	PREVIEW_VERSION = 0
 	SUCCESS         = 200
@@ -69,6 +71,9 @@ type PortForwardCommand struct {
 	OwnerKind       string            `json:"ownerKind"`
 	OwnerApiVersion string            `json:"ownerApiVersion"`
 	OwnerName       string            `json:"ownerName"`
+	Protocol        string            `json:"protocol"`
+	TargetService   string            `json:"targetService"`
+	IdleTimeout     string            `json:"idleTimeout"` // stop the port-forward after idle for the duration
 }
 
 type GetApplicationMetaCommand struct {
@@ -155,6 +160,25 @@ type VPNOperateCommand struct {
 	Action     VPNOperation `json:"operation" yaml:"operation"`
 }
 
+type NamespaceForwardCommand struct {
+	CommandType DaemonCommandType
+	ClientStack string
+
+	KubeConfig string                    `json:"kubeConfig" yaml:"kubeConfig"`
+	Namespace  string                    `json:"namespace" yaml:"namespace"`
+	Action     NamespaceForwardOperation `json:"operation" yaml:"operation"`
+	HostsFile  string                    `json:"hostsFile" yaml:"hostsFile"`
+	DNSAddress string                    `json:"dnsAddress" yaml:"dnsAddress"`
+}
+
+type NamespaceForwardOperation string
+
+const (
+	NamespaceForwardStart NamespaceForwardOperation = "start"
+	NamespaceForwardStop  NamespaceForwardOperation = "stop"
+	NamespaceForwardList  NamespaceForwardOperation = "list"
+)
+
 type VPNOperation string
 
 const (
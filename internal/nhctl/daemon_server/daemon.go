@@ -88,6 +88,10 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 					return nil
 				}
 
+				if pack.Event.EventType == appmeta.DEV_REQ || pack.Event.EventType == appmeta.DEV_GRANT {
+					return handleDevRequestEvent(pack)
+				}
+
 				kubeconfig := k8sutil.GetOrGenKubeConfigPath(string(pack.KubeConfigBytes))
 				nhApp, err := app.NewApplication(pack.AppName, pack.Ns, kubeconfig, true)
 				if err != nil {
@@ -100,8 +104,9 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 				}
 
 				// Only replace DevMode's DEV_END event needs to handling
-				// Because duplicate DevMode will not be affected by other user
-				if nhController.IsInDuplicateDevMode() {
+				// Because duplicate, ephemeral and local DevMode will not be affected by other user
+				if nhController.IsInDuplicateDevMode() || nhController.IsInEphemeralDevMode() ||
+					nhController.IsInLocalDevMode() {
 					return nil
 				}
 
@@ -112,6 +117,13 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 					)
 
 					_ = nhController.StopSyncAndPortForwardProcess(true)
+
+					// the service may be handed off to current user
+					if profile, err := nhApp.GetProfile(); err == nil && profile.Identifier != pack.Event.Identifier {
+						if err = startDevModeIfGranted(pack, profile); err != nil {
+							log.LogE(err)
+						}
+					}
 				} else if pack.Event.EventType == appmeta.DEV_STA {
 					profile, err := nhApp.GetProfile()
 					if err != nil {
@@ -151,6 +163,8 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 
 		go reconnectSyncthingIfNeededWithPeriod(time.Second * 30)
 
+		go checkDevLeaseWithPeriod(time.Minute)
+
 		go func() {
 			time.Sleep(30 * time.Second)
 			if err := nocalhost_cleanup.CleanUp(false); err != nil {
@@ -242,6 +256,7 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 
 	// Recovering port forward
 	go pfManager.RecoverAllPortForward()
+	go recoverNamespaceForwards()
 
 	//// Recovering syncthing
 	//go recoverSyncthing()
@@ -351,6 +366,25 @@ func handleCommand(conn net.Conn, bys []byte, cmdType command.DaemonCommandType,
 			},
 		)
 
+	case command.GetPortForwardStats:
+		err = Process(
+			conn, func(conn net.Conn) (interface{}, error) {
+				return pfManager.ListPortForwardStats(), nil
+			},
+		)
+
+	case command.NamespaceForward:
+		err = Process(
+			conn, func(conn net.Conn) (interface{}, error) {
+				cmd := &command.NamespaceForwardCommand{}
+				if err = json.Unmarshal(bys, cmd); err != nil {
+					return nil, errors.Wrap(err, "")
+				}
+
+				return handleNamespaceForward(cmd)
+			},
+		)
+
 	case command.AuthCheck:
 		err = Process(
 			conn, func(conn net.Conn) (interface{}, error) {
@@ -0,0 +1,198 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/appmeta_manager"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/internal/nhctl/utils"
+	k8sutil "nocalhost/pkg/nhctl/k8sutils"
+	"nocalhost/pkg/nhctl/log"
+	"sync"
+	"time"
+)
+
+// devLeaseGracePeriod daemon of other users waits a while longer before ending an expired DevMode,
+// leave the chance to the daemon of the developer first
+const devLeaseGracePeriod = 10 * time.Minute
+
+// namespace-nid-appName-serviceType-serviceName -> last syncthing folder version observed
+var syncVersions sync.Map
+
+// checkDevLeaseWithPeriod ends DevMode whose lease is expired periodically
+func checkDevLeaseWithPeriod(duration time.Duration) {
+	tick := time.NewTicker(duration)
+	for {
+		select {
+		case <-tick.C:
+			checkDevLease()
+		}
+	}
+}
+
+func checkDevLease() {
+	defer utils.RecoverFromPanic()
+
+	appmeta_manager.RangeApplicationMetas(
+		func(meta *appmeta.ApplicationMeta, configBytes []byte) {
+			if meta == nil || !meta.IsInstalled() {
+				return
+			}
+			for svcType, leases := range meta.GetAllDevLeases() {
+				for _, lease := range leases {
+					if err := checkDevLeaseOf(meta, configBytes, svcType, lease); err != nil {
+						log.LogE(err)
+					}
+				}
+			}
+		},
+	)
+}
+
+type devLeaseAction int
+
+const (
+	devLeaseKeep devLeaseAction = iota
+	devLeaseRenew
+	devLeaseEnd
+	// devLeaseClean DevMode has been ended by client not knowing lease, just clean the lease
+	devLeaseClean
+)
+
+func checkDevLeaseOf(meta *appmeta.ApplicationMeta, configBytes []byte, svcType base.SvcType,
+	lease *appmeta.DevLease) error {
+
+	var owner, syncing bool
+	if appProfile, err := nocalhost.GetProfileV2(meta.Ns, meta.Application, meta.NamespaceId); err == nil &&
+		appProfile.Identifier == lease.Identifier {
+		owner = true
+		svc, err := controller.NewController(
+			meta.Ns, lease.Name, meta.Application, lease.Identifier, svcType.Origin(), nil, meta,
+		)
+		if err != nil {
+			return err
+		}
+		syncing = isSyncing(svc)
+	}
+
+	switch devLeaseActionOf(lease, time.Now(), owner, syncing) {
+	case devLeaseRenew:
+		return renewDevLease(meta, configBytes, svcType, lease)
+	case devLeaseEnd:
+		return endExpiredDevMode(meta, configBytes, svcType, lease, devLeaseGraceOf(owner))
+	}
+	return nil
+}
+
+// devLeaseGraceOf daemon of the developer who owns the lease ends it without grace
+func devLeaseGraceOf(owner bool) time.Duration {
+	if owner {
+		return 0
+	}
+	return devLeaseGracePeriod
+}
+
+// devLeaseActionOf only daemon of the owner renews the lease while files are syncing,
+// any daemon ends the DevMode once its lease is expired
+func devLeaseActionOf(lease *appmeta.DevLease, now time.Time, owner, syncing bool) devLeaseAction {
+	if owner && syncing && !lease.Expired(now, 0) && lease.NeedRenew(now) {
+		return devLeaseRenew
+	}
+	if lease.Expired(now, devLeaseGraceOf(owner)) {
+		return devLeaseEnd
+	}
+	return devLeaseKeep
+}
+
+// isSyncing return true if files have been synced since last check
+func isSyncing(svc *controller.Controller) bool {
+	status, err := svc.NewSyncthingHttpClient(2).FolderStatus()
+	if err != nil {
+		return false
+	}
+
+	key := toKey(svc)
+	last, ok := syncVersions.Load(key)
+	syncVersions.Store(key, status.Version)
+	return ok && last.(int) != status.Version
+}
+
+// devModeControllerOf returns controller acting as the developer who owns the lease
+func devModeControllerOf(meta *appmeta.ApplicationMeta, configBytes []byte, svcType base.SvcType,
+	lease *appmeta.DevLease) (*controller.Controller, error) {
+	kubeconfig := k8sutil.GetOrGenKubeConfigPath(string(configBytes))
+	nhApp, err := app.NewApplication(meta.Application, meta.Ns, kubeconfig, true)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := nhApp.Controller(lease.Name, svcType.Origin())
+	if err != nil {
+		return nil, err
+	}
+	svc.Identifier = lease.Identifier
+	svc.DevModeType = lease.DevModeType
+	return svc, nil
+}
+
+func renewDevLease(meta *appmeta.ApplicationMeta, configBytes []byte, svcType base.SvcType,
+	lease *appmeta.DevLease) error {
+	svc, err := devModeControllerOf(meta, configBytes, svcType, lease)
+	if err != nil {
+		return err
+	}
+	return svc.RenewDevLease()
+}
+
+func endExpiredDevMode(meta *appmeta.ApplicationMeta, configBytes []byte, svcType base.SvcType,
+	lease *appmeta.DevLease, grace time.Duration) error {
+	svc, err := devModeControllerOf(meta, configBytes, svcType, lease)
+	if err != nil {
+		return err
+	}
+
+	switch expiredDevLeaseAction(svc.AppMeta, svc.Type, lease, time.Now(), grace) {
+	case devLeaseKeep:
+		return nil
+	case devLeaseClean:
+		return svc.AppMeta.SvcDevEnd(svc.Name, svc.Identifier, svc.Type, svc.DevModeType)
+	}
+
+	log.Logf(
+		"%s DevMode of %s-%s-%s has been idle for %s, ending it",
+		svc.DevModeType.ToString(), meta.Ns, meta.Application, svc.Name, lease.TTL().String(),
+	)
+	// SvcDevEnd in DevEnd will emit DEV_END event to all daemons watching the application
+	if err = svc.DevEnd(true); err != nil {
+		return err
+	}
+	if !svc.DevModeType.IsEphemeralDevMode() {
+		if err = svc.DecreaseDevModeCount(); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Failed to decrease DevMode count of %s", svc.Name))
+		}
+	}
+	return nil
+}
+
+// expiredDevLeaseAction checks the expired lease again with the latest meta
+func expiredDevLeaseAction(meta *appmeta.ApplicationMeta, svcType base.SvcType, lease *appmeta.DevLease,
+	now time.Time, grace time.Duration) devLeaseAction {
+	// lease may have been renewed or removed by others
+	current := meta.GetSvcDevLease(lease.Name, svcType, lease.Identifier, lease.DevModeType)
+	if current == nil || !current.Expired(now, grace) {
+		return devLeaseKeep
+	}
+	if meta.CheckIfSvcDeveloping(lease.Name, lease.Identifier, svcType, lease.DevModeType) == appmeta.NONE {
+		return devLeaseClean
+	}
+	return devLeaseEnd
+}
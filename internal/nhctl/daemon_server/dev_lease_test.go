@@ -0,0 +1,96 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/profile"
+	"testing"
+	"time"
+)
+
+func TestDevLeaseActionOf(t *testing.T) {
+	last := time.Unix(1600000000, 0)
+	lease := &appmeta.DevLease{TTLSeconds: 3600, LastActivity: last.Unix()}
+	ttl := lease.TTL()
+
+	cases := []struct {
+		name    string
+		elapsed time.Duration
+		owner   bool
+		syncing bool
+		action  devLeaseAction
+	}{
+		{"owner syncing before renew interval", ttl/10 - time.Second, true, true, devLeaseKeep},
+		{"owner syncing at renew interval", ttl / 10, true, true, devLeaseRenew},
+		{"owner idle", ttl / 2, true, false, devLeaseKeep},
+		{"others never renew", ttl / 2, false, true, devLeaseKeep},
+		{"owner syncing at ttl", ttl, true, true, devLeaseRenew},
+		// activity after expiring does not bring DevMode back
+		{"owner syncing after ttl", ttl + time.Second, true, true, devLeaseEnd},
+		{"owner idle after ttl", ttl + time.Second, true, false, devLeaseEnd},
+		{"others within grace", ttl + devLeaseGracePeriod, false, false, devLeaseKeep},
+		{"others after grace", ttl + devLeaseGracePeriod + time.Second, false, false, devLeaseEnd},
+	}
+	for _, c := range cases {
+		if action := devLeaseActionOf(lease, last.Add(c.elapsed), c.owner, c.syncing); action != c.action {
+			t.Fatalf("%s: expected action %d, got %d", c.name, c.action, action)
+		}
+	}
+}
+
+func TestExpiredDevLeaseAction(t *testing.T) {
+	last := time.Unix(1600000000, 0)
+	newMeta := func(developing bool, lastActivity time.Time) (*appmeta.ApplicationMeta, *appmeta.DevLease) {
+		lease := &appmeta.DevLease{
+			Name: "reviews", Identifier: "id", DevModeType: profile.ReplaceDevMode,
+			TTLSeconds: 600, LastActivity: lastActivity.Unix(),
+		}
+		meta := &appmeta.ApplicationMeta{
+			DevMeta:  appmeta.ApplicationDevMeta{base.DEPLOYMENT: {}},
+			DevLease: appmeta.ApplicationDevLease{base.DEPLOYMENT: {"reviews": lease}},
+		}
+		if developing {
+			meta.DevMeta[base.DEPLOYMENT]["reviews"] = "id"
+		}
+		return meta, lease
+	}
+	now := last.Add(time.Hour)
+
+	meta, lease := newMeta(true, last)
+	if action := expiredDevLeaseAction(meta, base.Deployment, lease, now, 0); action != devLeaseEnd {
+		t.Fatalf("expired DevMode should be ended, got %d", action)
+	}
+
+	// DevMode ended by a client not knowing lease
+	meta, lease = newMeta(false, last)
+	if action := expiredDevLeaseAction(meta, base.Deployment, lease, now, 0); action != devLeaseClean {
+		t.Fatalf("lease of DevMode ended already should be cleaned, got %d", action)
+	}
+
+	// renewed by others since checked
+	meta, lease = newMeta(true, last)
+	meta.DevLease[base.DEPLOYMENT]["reviews"] = &appmeta.DevLease{
+		Name: "reviews", Identifier: "id", TTLSeconds: 600, LastActivity: now.Unix(),
+	}
+	if action := expiredDevLeaseAction(meta, base.Deployment, lease, now, 0); action != devLeaseKeep {
+		t.Fatalf("renewed lease should be kept, got %d", action)
+	}
+
+	// removed by others since checked
+	meta, lease = newMeta(true, last)
+	delete(meta.DevLease[base.DEPLOYMENT], "reviews")
+	if action := expiredDevLeaseAction(meta, base.Deployment, lease, now, 0); action != devLeaseKeep {
+		t.Fatalf("removed lease should be kept, got %d", action)
+	}
+
+	meta, lease = newMeta(true, last)
+	action := expiredDevLeaseAction(meta, base.Deployment, lease, last.Add(11*time.Minute), time.Hour)
+	if action != devLeaseKeep {
+		t.Fatalf("lease within grace should be kept, got %d", action)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/appmeta_manager"
+	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing/daemon"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"time"
+)
+
+// handleDevRequestEvent notifies the holder of a service while someone queues for it
+func handleDevRequestEvent(pack *appmeta_manager.ApplicationEventPack) error {
+	meta := appmeta_manager.GetApplicationMeta(pack.Ns, pack.AppName, pack.KubeConfigBytes)
+	appProfile, err := nocalhost.GetProfileV2(pack.Ns, pack.AppName, meta.NamespaceId)
+	if err != nil {
+		return nil
+	}
+
+	name, svcType := pack.Event.ResourceName, pack.Event.DevType
+	switch pack.Event.EventType {
+	case appmeta.DEV_REQ:
+		if meta.SvcDevModePossessor(name, svcType, appProfile.Identifier, profile.ReplaceDevMode) {
+			log.Logf(
+				"%s-%s-%s is requested by %s, run `nhctl dev handoff %s -d %s -t %s -n %s` to hand it off",
+				pack.Ns, pack.AppName, name, pack.Event.Identifier, pack.AppName, name, svcType.Origin(), pack.Ns,
+			)
+		}
+	case appmeta.DEV_GRANT:
+		if appProfile.Identifier == pack.Event.Identifier {
+			log.Logf(
+				"%s-%s-%s has been handed off to you, DevMode will be started once the holder ends it",
+				pack.Ns, pack.AppName, name,
+			)
+		}
+	}
+	return nil
+}
+
+// startDevModeIfGranted starts DevMode for the requester after the holder ends DevMode of the service
+func startDevModeIfGranted(pack *appmeta_manager.ApplicationEventPack, appProfile *profile.AppProfileV2) error {
+	meta := appmeta_manager.GetApplicationMeta(pack.Ns, pack.AppName, pack.KubeConfigBytes)
+	name, svcType := pack.Event.ResourceName, pack.Event.DevType
+
+	var request *appmeta.DevRequest
+	for _, r := range meta.GetSvcDevRequests(name, svcType) {
+		if r.Identifier == appProfile.Identifier {
+			request = r
+		}
+	}
+	if request == nil || !request.Granted() || request.GrantExpired(time.Now()) {
+		return nil
+	}
+
+	nhctlPath, err := utils.GetNhctlPath()
+	if err != nil {
+		return err
+	}
+
+	// nhctl dev start bookinfo -d productpage -t deployment -n ns --kubeconfig ~/.kube/config --without-terminal
+	args := []string{
+		nhctlPath, "dev", "start", pack.AppName, "-d", name, "-t", svcType.Origin().String(), "-n", pack.Ns,
+		"--kubeconfig", appProfile.Kubeconfig, "--without-terminal",
+	}
+	if request.Container != "" {
+		args = append(args, "-c", request.Container)
+	}
+	if request.LocalSync != "" {
+		args = append(args, "-s", request.LocalSync)
+	}
+	if request.DevImage != "" {
+		args = append(args, "-i", request.DevImage)
+	}
+	log.Logf("Starting DevMode of %s-%s-%s handed off by others", pack.Ns, pack.AppName, name)
+	return daemon.RunSubProcess(args, nil, false)
+}
@@ -54,6 +54,7 @@ func startHttpServer() {
 
 	http.HandleFunc("/config-save", handlingConfigSave)
 	http.HandleFunc("/config-get", handlingConfigGet)
+	http.HandleFunc("/sync-events", handlingSyncEvents)
 
 	err := http.ListenAndServe("127.0.0.1:"+strconv.Itoa(daemon_common.DaemonHttpPort), nil)
 	if err != nil {
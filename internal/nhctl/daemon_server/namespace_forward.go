@@ -0,0 +1,157 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"nocalhost/internal/nhctl/nsforward"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+type namespaceForward struct {
+	*nsforward.Forwarder
+	cmd *command.NamespaceForwardCommand
+}
+
+var (
+	nsForwardLock sync.Mutex
+	// kubeconfig/namespace -> forwarder
+	nsForwards = map[string]*namespaceForward{}
+)
+
+func handleNamespaceForward(cmd *command.NamespaceForwardCommand) ([]*daemon_common.NamespaceForwardStatus, error) {
+	nsForwardLock.Lock()
+	defer nsForwardLock.Unlock()
+
+	key := cmd.KubeConfig + "/" + cmd.Namespace
+	switch cmd.Action {
+	case command.NamespaceForwardStart:
+		if f, ok := nsForwards[key]; ok {
+			return []*daemon_common.NamespaceForwardStatus{f.Status()}, nil
+		}
+		f, err := nsforward.NewForwarder(
+			cmd.KubeConfig, cmd.Namespace, nsforward.Options{HostsFile: cmd.HostsFile, DNSAddress: cmd.DNSAddress},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err = f.Start(); err != nil {
+			f.Stop()
+			return nil, err
+		}
+		nsForwards[key] = &namespaceForward{Forwarder: f, cmd: cmd}
+		saveNamespaceForwards()
+		return []*daemon_common.NamespaceForwardStatus{f.Status()}, nil
+	case command.NamespaceForwardStop:
+		f, ok := nsForwards[key]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Namespace %s is not forwarded", cmd.Namespace))
+		}
+		f.Stop()
+		delete(nsForwards, key)
+		saveNamespaceForwards()
+		return []*daemon_common.NamespaceForwardStatus{}, nil
+	case command.NamespaceForwardList:
+		result := make([]*daemon_common.NamespaceForwardStatus, 0, len(nsForwards))
+		for _, f := range nsForwards {
+			result = append(result, f.Status())
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+		return result, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported namespace forward operation %s", cmd.Action))
+	}
+}
+
+// namespaceForwardsFile namespace forwards of the daemon are recorded to it, so they are recovered
+// after the daemon restarted, such as by `nhctl upgrade`
+func namespaceForwardsFile() string {
+	name := "namespace-forwards.json"
+	if isSudo {
+		name = "namespace-forwards-sudo.json"
+	}
+	return filepath.Join(nocalhost_path.GetNhctlHomeDir(), name)
+}
+
+// saveNamespaceForwards nsForwardLock must be held
+func saveNamespaceForwards() {
+	keys := make([]string, 0, len(nsForwards))
+	for key := range nsForwards {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	cmds := make([]*command.NamespaceForwardCommand, 0, len(keys))
+	for _, key := range keys {
+		cmd := nsForwards[key].cmd
+		cmds = append(
+			cmds, &command.NamespaceForwardCommand{
+				CommandType: command.NamespaceForward,
+				KubeConfig:  cmd.KubeConfig,
+				Namespace:   cmd.Namespace,
+				Action:      command.NamespaceForwardStart,
+				HostsFile:   cmd.HostsFile,
+				DNSAddress:  cmd.DNSAddress,
+			},
+		)
+	}
+
+	bys, err := json.Marshal(cmds)
+	if err == nil {
+		err = ioutil.WriteFile(namespaceForwardsFile(), bys, 0644)
+	}
+	if err != nil {
+		log.LogE(errors.Wrap(err, "Failed to save namespace forwards"))
+	}
+}
+
+// recoverNamespaceForwards restarts namespace forwards recorded before the daemon restarted,
+// the managed hosts block of the one failed to restart is removed, so stale addresses are not resolved
+func recoverNamespaceForwards() {
+	defer utils.RecoverFromPanic()
+
+	bys, err := ioutil.ReadFile(namespaceForwardsFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.LogE(errors.Wrap(err, ""))
+		}
+		return
+	}
+	cmds := make([]*command.NamespaceForwardCommand, 0)
+	if err = json.Unmarshal(bys, &cmds); err != nil {
+		log.LogE(errors.Wrap(err, ""))
+		return
+	}
+
+	for _, cmd := range cmds {
+		log.Infof("Recovering namespace forward of %s", cmd.Namespace)
+		cmd.Action = command.NamespaceForwardStart
+		if _, err = handleNamespaceForward(cmd); err == nil {
+			continue
+		}
+		log.WarnE(err, fmt.Sprintf("Failed to recover namespace forward of %s", cmd.Namespace))
+		if cmd.HostsFile != "" {
+			if err = nsforward.WriteHosts(cmd.HostsFile, cmd.Namespace, nil); err != nil {
+				log.LogE(err)
+			}
+		}
+	}
+
+	// the failed ones are not recorded any more
+	nsForwardLock.Lock()
+	saveNamespaceForwards()
+	nsForwardLock.Unlock()
+}
@@ -6,9 +6,9 @@
 package daemon_server
 
 import (
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
-	"golang.org/x/net/context"
 	"io/ioutil"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -19,6 +19,7 @@ import (
 	"net"
 	"nocalhost/internal/nhctl/app"
 	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/daemon_common"
 	"nocalhost/internal/nhctl/daemon_server/command"
 	"nocalhost/internal/nhctl/dbutils"
@@ -48,17 +49,15 @@ func NewPortForwardManager() *PortForwardManager {
 }
 
 func (p *PortForwardManager) StopPortForwardGoRoutine(cmd *command.PortForwardCommand) error {
-	key := fmt.Sprintf("%d:%d", cmd.LocalPort, cmd.RemotePort)
+	key := pfKey(cmd.LocalPort, cmd.RemotePort, cmd.Protocol, cmd.Role)
+	p.lock.Lock()
 	pfProfile, ok := p.pfList[key]
+	delete(p.pfList, key)
+	p.lock.Unlock()
 	if ok {
-		pfProfile.Cancel()
-		var err error
-		select {
-		case err = <-pfProfile.StopCh:
-		default:
-		}
-		delete(p.pfList, key)
-		return err
+		// removed from pfList already, so nothing will be sent to its StopCh by notifyStopped
+		pfProfile.Cancel(nil)
+		return nil
 	}
 
 	kube, err := nocalhost.GetKubeConfigFromProfile(cmd.NameSpace, cmd.AppName, cmd.Nid)
@@ -77,11 +76,13 @@ func (p *PortForwardManager) StopPortForwardGoRoutine(cmd *command.PortForwardCo
 	if err != nil {
 		return err
 	}
-	return nhController.DeletePortForwardFromDB(cmd.LocalPort, cmd.RemotePort)
+	return nhController.DeletePortForwardFromDB(cmd.LocalPort, cmd.RemotePort, cmd.Protocol, cmd.Role)
 }
 
 // ListAllRunningPortForwardGoRoutineProfile
 func (p *PortForwardManager) ListAllRunningPFGoRoutineProfile() []*daemon_common.PortForwardProfile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	result := make([]*daemon_common.PortForwardProfile, 0)
 	for _, v := range p.pfList {
 		result = append(result, v)
@@ -103,6 +104,10 @@ func (p *PortForwardManager) RecoverPortForwardForApplication(ns, appName, nid s
 	for _, svcProfile := range profile.SvcProfile {
 		for _, pf := range svcProfile.DevPortForwardList {
 			if pf.Sudo == isSudo { // Only recover port-forward managed by this daemon server
+				// idle stopped port-forward is started again by user only
+				if pf.Status == idleStoppedStatus {
+					continue
+				}
 				found = true
 				log.Logf("Recovering port-forward %d:%d of %s-%s-%s", pf.LocalPort, pf.RemotePort, nid, ns, appName)
 				svcType := pf.ServiceType
@@ -131,6 +136,9 @@ func (p *PortForwardManager) RecoverPortForwardForApplication(ns, appName, nid s
 						OwnerKind:       pf.OwnerKind,
 						OwnerApiVersion: pf.OwnerApiVersion,
 						Labels:          pf.Labels,
+						Protocol:        pf.Protocol,
+						TargetService:   pf.TargetService,
+						IdleTimeout:     pf.IdleTimeout,
 					}, false,
 				)
 				if err != nil {
@@ -227,13 +235,52 @@ func GetTopController(refs []v1.OwnerReference, client *clientgoutils.ClientGoUt
 	}
 }
 
+// runningPortForward returns the running port-forward of key
+func (p *PortForwardManager) runningPortForward(key string) (*daemon_common.PortForwardProfile, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pf, ok := p.pfList[key]
+	return pf, ok
+}
+
+func (p *PortForwardManager) addRunningPortForward(key string, pf *daemon_common.PortForwardProfile) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pfList[key] = pf
+}
+
+func (p *PortForwardManager) removeRunningPortForward(key string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.pfList, key)
+}
+
+// notifyStopped sends err to StopCh of the running port-forward of key, if it's not removed yet
+func (p *PortForwardManager) notifyStopped(key string, err error) {
+	if pf, ok := p.runningPortForward(key); ok {
+		pf.StopCh <- err
+	}
+}
+
+// pfKey key of port-forward in pfList, udp and reverse port-forwards may use the same ports as a tcp one
+func pfKey(localPort, remotePort int, protocol, role string) string {
+	key := fmt.Sprintf("%d:%d", localPort, remotePort)
+	if protocol == _const.PortForwardUDP {
+		key += "/" + protocol
+	}
+	if role == _const.ReversePortForwardRole {
+		key += "/reverse"
+	}
+	return key
+}
+
 // StartPortForwardGoRoutine Start a port-forward
 // If saveToDB is true, record it to leveldb
 func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortForwardCommand, saveToDB bool) error {
 
-	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
-	key := fmt.Sprintf("%d:%d", localPort, remotePort)
-	if _, ok := p.pfList[key]; ok {
+	localPort, remotePort, protocol := startCmd.LocalPort, startCmd.RemotePort, startCmd.Protocol
+	key := pfKey(localPort, remotePort, protocol, startCmd.Role)
+	if _, ok := p.runningPortForward(key); ok {
 		log.Logf("Port-forward %d:%d has been running in another go routine, stop it first", localPort, remotePort)
 		if err := p.StopPortForwardGoRoutine(startCmd); err != nil {
 			log.LogE(err)
@@ -250,19 +297,43 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 	}
 
 	address := fmt.Sprintf("0.0.0.0:%d", startCmd.LocalPort)
-	listener, err := net.Listen("tcp", address)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Port %d is unavailable: %s", startCmd.LocalPort, err.Error()))
+	if startCmd.Protocol == _const.PortForwardUDP && startCmd.TargetService != "" {
+		return errors.New("UDP port-forward to service is not supported")
+	}
+	if startCmd.Role == _const.ReversePortForwardRole {
+		// local port is served by local app
+	} else if startCmd.Protocol == _const.PortForwardUDP {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return errors.New(fmt.Sprintf("UDP port %d is unavailable: %s", startCmd.LocalPort, err.Error()))
+		}
+		_ = conn.Close()
+	} else {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Port %d is unavailable: %s", startCmd.LocalPort, err.Error()))
+		}
+		_ = listener.Close()
 	}
-	_ = listener.Close()
 
 	nhController, err := nocalhostApp.Controller(startCmd.Service, base.SvcType(startCmd.ServiceType))
 	if err != nil {
 		return err
 	}
 
+	if saveToDB {
+		p.deleteIdleStoppedFromDB(startCmd, nhController)
+	}
+
 	_ = p.recordPortForward(startCmd.NameSpace, startCmd.Nid, startCmd.AppName, func() bool { return true })
 
+	if startCmd.TargetService != "" {
+		return p.startServicePortForwardGoRoutine(startCmd, saveToDB, nocalhostApp, nhController)
+	}
+	if startCmd.Role == _const.ReversePortForwardRole {
+		return p.startReversePortForwardGoRoutine(startCmd, saveToDB, nocalhostApp, nhController)
+	}
+
 	howToGetCurrentPod := func() (*corev1.Pod, error) {
 		// first find the pod should be port-forward
 		var currentPod *corev1.Pod
@@ -299,7 +370,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 	var currentPod *corev1.Pod
 	if saveToDB {
 		// Check if port forward already exists
-		if existed, _ := nhController.CheckIfPortForwardExists(localPort, remotePort); existed {
+		if existed, _ := nhController.CheckIfPortForwardExists(localPort, remotePort, protocol, startCmd.Role); existed {
 			return errors.New(fmt.Sprintf("Port forward %d:%d already exists", localPort, remotePort))
 		}
 
@@ -314,6 +385,8 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 			Sudo:            isSudo,
 			DaemonServerPid: os.Getpid(),
 			ServiceType:     startCmd.ServiceType,
+			IdleTimeout:     startCmd.IdleTimeout,
+			Protocol:        startCmd.Protocol,
 		}
 
 		if currentPod, err = howToGetCurrentPod(); err != nil {
@@ -344,8 +417,9 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 
 	startCmd.PodName = currentPod.Name
 
-	ctx, cancel := context.WithCancel(context.TODO())
-	p.pfList[key] = &daemon_common.PortForwardProfile{
+	ctx, cancel := context.WithCancelCause(context.TODO())
+	counter := p.countTraffic(ctx, startCmd)
+	p.addRunningPortForward(key, &daemon_common.PortForwardProfile{
 		Cancel:     cancel,
 		StopCh:     make(chan error, 1),
 		NameSpace:  startCmd.NameSpace,
@@ -355,30 +429,15 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 		AppName:    startCmd.AppName,
 		LocalPort:  startCmd.LocalPort,
 		RemotePort: startCmd.RemotePort,
-	}
+		Protocol:   startCmd.Protocol,
+		Counter:    counter,
+	})
 	go func() {
 		defer utils.RecoverFromPanic()
 
 		log.Logf("Forwarding %d:%d", localPort, remotePort)
 
-		logDir := filepath.Join(nocalhost.GetLogDir(), "port-forward")
-		if _, err = os.Stat(logDir); err != nil {
-			if os.IsNotExist(err) {
-				if err = os.MkdirAll(logDir, 0644); err != nil {
-					log.LogE(errors.Wrap(err, ""))
-				}
-			} else {
-				log.LogE(errors.Wrap(err, ""))
-			}
-		}
-
-		stdout, err := os.OpenFile(
-			filepath.Join(
-				logDir, fmt.Sprintf(
-					"%s_%s_%s_%d_%d", startCmd.NameSpace, startCmd.AppName, startCmd.Service, localPort, remotePort,
-				),
-			), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0755,
-		)
+		stdout, err := openPortForwardLog(startCmd)
 		if err != nil {
 			log.LogE(err)
 		}
@@ -426,7 +485,9 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 				case <-readyCh:
 					log.Infof("Port forward %d:%d is ready", localPort, remotePort)
 					p.lock.Lock()
-					_ = nhController.UpdatePortForwardStatus(localPort, remotePort, "LISTEN", "listen")
+					_ = nhController.UpdatePortForwardStatus(
+						localPort, remotePort, protocol, startCmd.Role, "LISTEN", "listen",
+					)
 					p.lock.Unlock()
 				case <-time.After(60 * time.Second):
 					log.Infof("Waiting Port forward %d:%d timeout", localPort, remotePort)
@@ -435,7 +496,11 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 
 			go func() {
 				defer utils.RecoverFromPanic()
-				errCh <- nocalhostApp.PortForward(startCmd.PodName, localPort, remotePort, readyCh, stopCh, stream)
+				if startCmd.Protocol == _const.PortForwardUDP {
+					errCh <- nocalhostApp.UDPPortForward(startCmd.PodName, localPort, remotePort, readyCh, stopCh, stream)
+				} else {
+					errCh <- nocalhostApp.PortForward(startCmd.PodName, localPort, remotePort, readyCh, stopCh, stream)
+				}
 				log.Logf("Port-forward %d:%d occurs errors", localPort, remotePort)
 			}()
 
@@ -455,7 +520,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 					if pod, err := howToGetCurrentPod(); err != nil {
 						p.lock.Lock()
 						err = nhController.UpdatePortForwardStatus(
-							localPort, remotePort, "RECONNECTING",
+							localPort, remotePort, protocol, startCmd.Role, "RECONNECTING",
 							reconnectMsg,
 						)
 						p.lock.Unlock()
@@ -477,20 +542,20 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 					log.Logf("failed to find socat, err: %v", errs)
 					p.lock.Lock()
 					err = nhController.UpdatePortForwardStatus(
-						localPort, remotePort, "Socat not found", "failed to find socat",
+						localPort, remotePort, protocol, startCmd.Role, "Socat not found", "failed to find socat",
 					)
 					p.lock.Unlock()
 					if err != nil {
 						log.LogE(err)
 					}
-					delete(p.pfList, key)
+					p.removeRunningPortForward(key)
 					return
 				} else {
 
 					log.Warn(reconnectMsg)
 					p.lock.Lock()
 					err = nhController.UpdatePortForwardStatus(
-						localPort, remotePort, "RECONNECTING",
+						localPort, remotePort, protocol, startCmd.Role, "RECONNECTING",
 						reconnectMsg,
 					)
 					p.lock.Unlock()
@@ -510,7 +575,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 				closeChanGracefully(stopCh)
 				//delete(p.pfList, key)
 				log.Logf("Delete port-forward %d:%d record", localPort, remotePort)
-				err = nhController.DeletePortForwardFromDB(localPort, remotePort)
+				err = p.deleteStoppedFromDB(ctx, nhController, localPort, remotePort, protocol, startCmd.Role)
 				if err != nil {
 					log.LogE(err)
 				}
@@ -521,9 +586,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 					},
 				)
 
-				if pfProfile, ok := p.pfList[key]; ok {
-					pfProfile.StopCh <- err
-				}
+				p.notifyStopped(key, err)
 				return
 			}
 		}
@@ -531,6 +594,29 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 	return nil
 }
 
+// openPortForwardLog opens the log file of port-forward, output of port-forwarder is written to it
+func openPortForwardLog(startCmd *command.PortForwardCommand) (*os.File, error) {
+	logDir := filepath.Join(nocalhost.GetLogDir(), "port-forward")
+	if _, err := os.Stat(logDir); err != nil {
+		if os.IsNotExist(err) {
+			if err = os.MkdirAll(logDir, 0644); err != nil {
+				log.LogE(errors.Wrap(err, ""))
+			}
+		} else {
+			log.LogE(errors.Wrap(err, ""))
+		}
+	}
+
+	return os.OpenFile(
+		filepath.Join(
+			logDir, fmt.Sprintf(
+				"%s_%s_%s_%d_%d", startCmd.NameSpace, startCmd.AppName, startCmd.Service,
+				startCmd.LocalPort, startCmd.RemotePort,
+			),
+		), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0755,
+	)
+}
+
 func closeChanGracefully(stopCh chan struct{}) {
 	select {
 	case _, ok := <-stopCh:
@@ -0,0 +1,28 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	_const "nocalhost/internal/nhctl/const"
+	"testing"
+)
+
+func TestPfKey(t *testing.T) {
+	keys := map[string]bool{}
+	for _, key := range []string{
+		pfKey(9000, 9000, _const.PortForwardTCP, ""),
+		pfKey(9000, 9000, _const.PortForwardUDP, ""),
+		pfKey(9000, 9000, _const.PortForwardTCP, _const.ReversePortForwardRole),
+	} {
+		if keys[key] {
+			t.Fatalf("duplicated key %s", key)
+		}
+		keys[key] = true
+	}
+	if pfKey(9000, 9000, "", "SYNC") != pfKey(9000, 9000, _const.PortForwardTCP, "") {
+		t.Fatal("tcp port-forwards of other roles should share the key")
+	}
+}
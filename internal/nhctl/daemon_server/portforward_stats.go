@@ -0,0 +1,144 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/pfstats"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"time"
+)
+
+// idleStoppedStatus status of port-forward stopped for no traffic in its idle timeout
+const idleStoppedStatus = "IDLE STOPPED"
+
+var errIdleStopped = errors.New("No traffic in idle timeout")
+
+// ListPortForwardStats traffic of all running port-forwards
+func (p *PortForwardManager) ListPortForwardStats() []*daemon_common.PortForwardStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	result := make([]*daemon_common.PortForwardStats, 0)
+	for _, v := range p.pfList {
+		result = append(
+			result, &daemon_common.PortForwardStats{
+				NameSpace:  v.NameSpace,
+				AppName:    v.AppName,
+				SvcName:    v.SvcName,
+				SvcType:    v.SvcType,
+				Role:       v.Role,
+				LocalPort:  v.LocalPort,
+				RemotePort: v.RemotePort,
+				Protocol:   v.Protocol,
+				Stats:      v.Counter.Snapshot(),
+			},
+		)
+	}
+	return result
+}
+
+// countTraffic registers the traffic counter of port-forward, and stops the port-forward
+// if it is idle for startCmd.IdleTimeout. Counter of reverse port-forward is not registered,
+// its local port is served by local app, which may be forwarded by another port-forward
+func (p *PortForwardManager) countTraffic(
+	ctx context.Context, startCmd *command.PortForwardCommand,
+) *pfstats.Counter {
+	var counter *pfstats.Counter
+	if startCmd.Role == _const.ReversePortForwardRole {
+		counter = pfstats.NewCounter()
+	} else {
+		counter = pfstats.Register(startCmd.LocalPort, startCmd.Protocol)
+		go func() {
+			<-ctx.Done()
+			pfstats.Unregister(startCmd.LocalPort, startCmd.Protocol, counter)
+		}()
+	}
+
+	if startCmd.IdleTimeout == "" {
+		return counter
+	}
+	idleTimeout, err := time.ParseDuration(startCmd.IdleTimeout)
+	if err != nil || idleTimeout <= 0 {
+		log.Warnf("Invalid idle timeout %s of port-forward %d:%d", startCmd.IdleTimeout, startCmd.LocalPort, startCmd.RemotePort)
+		return counter
+	}
+
+	interval := 30 * time.Second
+	if idleTimeout < 2*interval {
+		interval = idleTimeout / 2
+	}
+	go func() {
+		defer utils.RecoverFromPanic()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if counter.Idle() < idleTimeout {
+					continue
+				}
+				log.Infof(
+					"Port-forward %d:%d is idle for %s, stopping it", startCmd.LocalPort, startCmd.RemotePort,
+					startCmd.IdleTimeout,
+				)
+				p.stopIdlePortForward(startCmd, counter)
+				return
+			}
+		}
+	}()
+	return counter
+}
+
+// stopIdlePortForward stops the port-forward counted by counter, its record is kept with idleStoppedStatus
+func (p *PortForwardManager) stopIdlePortForward(startCmd *command.PortForwardCommand, counter *pfstats.Counter) {
+	key := pfKey(startCmd.LocalPort, startCmd.RemotePort, startCmd.Protocol, startCmd.Role)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	// it may be restarted by another one
+	if pf, ok := p.pfList[key]; ok && pf.Counter == counter {
+		pf.Cancel(errIdleStopped)
+		delete(p.pfList, key)
+	}
+}
+
+// deleteStoppedFromDB deletes the stopped port-forward from db, but the one stopped for idle is kept
+// with idleStoppedStatus, so that it's still listed
+func (p *PortForwardManager) deleteStoppedFromDB(
+	ctx context.Context, nhController *controller.Controller, localPort, remotePort int, protocol, role string,
+) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if context.Cause(ctx) == errIdleStopped {
+		return nhController.UpdatePortForwardStatus(
+			localPort, remotePort, protocol, role, idleStoppedStatus, errIdleStopped.Error(),
+		)
+	}
+	return nhController.DeletePortForwardFromDB(localPort, remotePort, protocol, role)
+}
+
+// deleteIdleStoppedFromDB deletes the idle stopped record of port-forward, which is started again
+func (p *PortForwardManager) deleteIdleStoppedFromDB(
+	startCmd *command.PortForwardCommand, nhController *controller.Controller,
+) {
+	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pf, err := nhController.GetPortForward(localPort, remotePort, startCmd.Protocol, startCmd.Role)
+	if err != nil || pf.Status != idleStoppedStatus {
+		return
+	}
+	if err = nhController.DeletePortForwardFromDB(localPort, remotePort, startCmd.Protocol, startCmd.Role); err != nil {
+		log.LogE(err)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"context"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/pfstats"
+	"testing"
+)
+
+func TestStopIdlePortForward(t *testing.T) {
+	p := NewPortForwardManager()
+	startCmd := &command.PortForwardCommand{LocalPort: 18080, RemotePort: 80, Protocol: "udp"}
+	key := pfKey(startCmd.LocalPort, startCmd.RemotePort, startCmd.Protocol, startCmd.Role)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	counter := pfstats.NewCounter()
+	p.addRunningPortForward(
+		key, &daemon_common.PortForwardProfile{
+			Cancel: cancel, LocalPort: 18080, RemotePort: 80, Protocol: "udp", Counter: counter,
+		},
+	)
+
+	// counter of a port-forward stopped already, the restarted one is kept
+	p.stopIdlePortForward(startCmd, pfstats.NewCounter())
+	if _, ok := p.runningPortForward(key); !ok || ctx.Err() != nil {
+		t.Fatal("port-forward restarted should not be stopped")
+	}
+	if stats := p.ListPortForwardStats(); len(stats) != 1 || stats[0].Protocol != "udp" {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+
+	p.stopIdlePortForward(startCmd, counter)
+	if _, ok := p.runningPortForward(key); ok {
+		t.Fatal("idle port-forward should be removed")
+	}
+	if context.Cause(ctx) != errIdleStopped {
+		t.Fatalf("idle port-forward should be canceled for idle, got %v", context.Cause(ctx))
+	}
+}
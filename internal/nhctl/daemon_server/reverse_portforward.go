@@ -0,0 +1,203 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"nocalhost/internal/nhctl/app"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/pfstats"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing/ports"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"time"
+)
+
+// startReversePortForwardGoRoutine forwards startCmd.RemotePort of dev container back to startCmd.LocalPort,
+// it's tunnelled by ssh server of sidecar, and reconnected to the current dev pod if the tunnel is broken
+func (p *PortForwardManager) startReversePortForwardGoRoutine(
+	startCmd *command.PortForwardCommand, saveToDB bool, nocalhostApp *app.Application,
+	nhController *controller.Controller,
+) error {
+	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
+	protocol, role := _const.PortForwardTCP, _const.ReversePortForwardRole
+	key := pfKey(localPort, remotePort, protocol, role)
+
+	if saveToDB {
+		if existed, _ := nhController.CheckIfPortForwardExists(localPort, remotePort, protocol, role); existed {
+			return errors.New(fmt.Sprintf("Port forward %d:%d already exists", localPort, remotePort))
+		}
+		pf := &profile.DevPortForward{
+			LocalPort:       localPort,
+			RemotePort:      remotePort,
+			Role:            role,
+			Status:          "New",
+			Reason:          "Add",
+			PodName:         startCmd.PodName,
+			Updated:         time.Now().Format("2006-01-02 15:04:05"),
+			Sudo:            isSudo,
+			DaemonServerPid: os.Getpid(),
+			ServiceType:     startCmd.ServiceType,
+			IdleTimeout:     startCmd.IdleTimeout,
+		}
+		log.Logf("Saving reverse port-forward %d <- %d to db", localPort, remotePort)
+		p.lock.Lock()
+		err := nhController.AddPortForwardToDB(pf)
+		p.lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(context.TODO())
+	counter := p.countTraffic(ctx, startCmd)
+	p.addRunningPortForward(key, &daemon_common.PortForwardProfile{
+		Cancel:     cancel,
+		StopCh:     make(chan error, 1),
+		NameSpace:  startCmd.NameSpace,
+		SvcName:    startCmd.Service,
+		SvcType:    startCmd.ServiceType,
+		Role:       role,
+		AppName:    startCmd.AppName,
+		LocalPort:  localPort,
+		RemotePort: remotePort,
+		Counter:    counter,
+	})
+
+	updateStatus := func(status, reason string) {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if err := nhController.UpdatePortForwardStatus(
+			localPort, remotePort, protocol, role, status, reason,
+		); err != nil {
+			log.LogE(err)
+		}
+	}
+
+	go func() {
+		defer utils.RecoverFromPanic()
+
+		log.Logf("Reverse forwarding %d <- %d", localPort, remotePort)
+		stdout, err := openPortForwardLog(startCmd)
+		if err != nil {
+			log.LogE(err)
+		}
+		stream := genericclioptions.IOStreams{In: stdout, Out: stdout, ErrOut: stdout}
+
+		sleepBackOff := 5 * time.Second
+		for {
+			err := p.reverseForward(ctx, startCmd, nocalhostApp, nhController, stream, counter, func() {
+				sleepBackOff = 5 * time.Second
+				updateStatus("LISTEN", "listen")
+			})
+			if ctx.Err() != nil {
+				break
+			}
+			reconnectMsg := fmt.Sprintf("Reconnecting after %s seconds...", sleepBackOff.String())
+			if err != nil {
+				log.WarnE(err, fmt.Sprintf("Reverse port-forward %d <- %d occurs errors", localPort, remotePort))
+				reconnectMsg = err.Error() + ", " + reconnectMsg
+			}
+			updateStatus("RECONNECTING", reconnectMsg)
+			select {
+			case <-time.After(sleepBackOff):
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if sleepBackOff < 30*time.Second {
+				sleepBackOff += 5 * time.Second
+			}
+		}
+
+		log.Logf("Reverse port-forward %d <- %d done", localPort, remotePort)
+		err = p.deleteStoppedFromDB(ctx, nhController, localPort, remotePort, protocol, role)
+		if err != nil {
+			log.LogE(err)
+		}
+		_ = p.recordPortForward(
+			startCmd.NameSpace, startCmd.Nid, startCmd.AppName, func() bool {
+				return nhController.IsPortForwarding()
+			},
+		)
+		p.notifyStopped(key, err)
+	}()
+	return nil
+}
+
+// reverseForward port-forwards a random local port to ssh server of the dev pod, and serves the
+// reverse tunnel through it, until ctx done or either of them broken
+func (p *PortForwardManager) reverseForward(
+	ctx context.Context, startCmd *command.PortForwardCommand, nocalhostApp *app.Application,
+	nhController *controller.Controller, stream genericclioptions.IOStreams, counter *pfstats.Counter,
+	onReady func(),
+) error {
+	podName, err := nhController.GetDevModePodName()
+	if err != nil {
+		return err
+	}
+	sshPort, err := ports.GetAvailablePort()
+	if err != nil {
+		return err
+	}
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	errCh := make(chan error, 2)
+	defer close(stopCh)
+	go func() {
+		defer utils.RecoverFromPanic()
+		errCh <- nocalhostApp.PortForward(podName, sshPort, _const.SidecarSshPort, readyCh, stopCh, stream)
+	}()
+
+	select {
+	case <-readyCh:
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+		return nil
+	case <-time.After(60 * time.Second):
+		return errors.New("Waiting port-forward to ssh server of sidecar timeout")
+	}
+
+	reverseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer utils.RecoverFromPanic()
+		errCh <- utils.ReverseForward(
+			reverseCtx, utils.DefaultRoot,
+			fmt.Sprintf("127.0.0.1:%d", sshPort),
+			fmt.Sprintf("127.0.0.1:%d", startCmd.RemotePort),
+			fmt.Sprintf("127.0.0.1:%d", startCmd.LocalPort),
+			counter,
+		)
+	}()
+
+	// the tunnel is ready if it's not broken in a moment
+	select {
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+		return nil
+	case <-time.After(time.Second):
+		onReady()
+	}
+	select {
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"nocalhost/internal/nhctl/app"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"time"
+)
+
+// startServicePortForwardGoRoutine port-forward to startCmd.TargetService instead of a pod,
+// connections are balanced across ready endpoints, so no pod is needed to be re-targeted
+func (p *PortForwardManager) startServicePortForwardGoRoutine(
+	startCmd *command.PortForwardCommand, saveToDB bool, nocalhostApp *app.Application,
+	nhController *controller.Controller,
+) error {
+	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
+	key := pfKey(localPort, remotePort, _const.PortForwardTCP, "")
+
+	if saveToDB {
+		if existed, _ := nhController.CheckIfPortForwardExists(localPort, remotePort, _const.PortForwardTCP, ""); existed {
+			return errors.New(fmt.Sprintf("Port forward %d:%d already exists", localPort, remotePort))
+		}
+		if _, err := nhController.Client.GetService(startCmd.TargetService); err != nil {
+			return err
+		}
+
+		pf := &profile.DevPortForward{
+			LocalPort:       localPort,
+			RemotePort:      remotePort,
+			Role:            startCmd.Role,
+			Status:          "New",
+			Reason:          "Add",
+			Updated:         time.Now().Format("2006-01-02 15:04:05"),
+			Sudo:            isSudo,
+			DaemonServerPid: os.Getpid(),
+			ServiceType:     startCmd.ServiceType,
+			IdleTimeout:     startCmd.IdleTimeout,
+			TargetService:   startCmd.TargetService,
+		}
+		log.Logf("Saving port-forward %d:%d to svc/%s to db", localPort, remotePort, startCmd.TargetService)
+		p.lock.Lock()
+		err := nhController.AddPortForwardToDB(pf)
+		p.lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(context.TODO())
+	counter := p.countTraffic(ctx, startCmd)
+	p.addRunningPortForward(key, &daemon_common.PortForwardProfile{
+		Cancel:     cancel,
+		StopCh:     make(chan error, 1),
+		NameSpace:  startCmd.NameSpace,
+		SvcName:    startCmd.Service,
+		SvcType:    startCmd.ServiceType,
+		Role:       startCmd.Role,
+		AppName:    startCmd.AppName,
+		LocalPort:  localPort,
+		RemotePort: remotePort,
+		Counter:    counter,
+	})
+
+	go func() {
+		defer utils.RecoverFromPanic()
+
+		log.Logf("Forwarding %d:%d to svc/%s", localPort, remotePort, startCmd.TargetService)
+		stdout, err := openPortForwardLog(startCmd)
+		if err != nil {
+			log.LogE(err)
+		}
+		stream := genericclioptions.IOStreams{In: stdout, Out: stdout, ErrOut: stdout}
+
+		onChange := func(endpoints []clientgoutils.ServiceEndpoint) {
+			names := make([]string, 0, len(endpoints))
+			for _, e := range endpoints {
+				names = append(names, e.String())
+			}
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			if err := nhController.UpdatePortForwardEndpoints(localPort, remotePort, names); err != nil {
+				log.LogE(err)
+			}
+		}
+
+		sleepBackOff := 15 * time.Second
+		for {
+			stopCh := make(chan struct{})
+			errCh := make(chan error, 1)
+			go func() {
+				defer utils.RecoverFromPanic()
+				errCh <- nocalhostApp.ServicePortForward(
+					startCmd.TargetService, localPort, remotePort, nil, stopCh, stream, onChange,
+				)
+			}()
+
+			select {
+			case err := <-errCh:
+				reconnectMsg := fmt.Sprintf("Reconnecting after %s seconds...", sleepBackOff.String())
+				if err != nil {
+					log.WarnE(err, fmt.Sprintf("Port-forward %d:%d to svc/%s occurs errors", localPort, remotePort, startCmd.TargetService))
+					reconnectMsg = err.Error() + ", " + reconnectMsg
+				}
+				p.lock.Lock()
+				err = nhController.UpdatePortForwardStatus(
+					localPort, remotePort, _const.PortForwardTCP, "", "RECONNECTING", reconnectMsg,
+				)
+				p.lock.Unlock()
+				if err != nil {
+					log.LogE(err)
+				}
+				select {
+				case <-time.After(sleepBackOff):
+				case <-ctx.Done():
+					p.stopServicePortForward(ctx, startCmd, nhController, key)
+					return
+				}
+				if sleepBackOff < 60*time.Second {
+					sleepBackOff += 15 * time.Second
+				}
+			case <-ctx.Done():
+				close(stopCh)
+				p.stopServicePortForward(ctx, startCmd, nhController, key)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *PortForwardManager) stopServicePortForward(
+	ctx context.Context, startCmd *command.PortForwardCommand, nhController *controller.Controller, key string,
+) {
+	log.Logf("Port-forward %d:%d to svc/%s done", startCmd.LocalPort, startCmd.RemotePort, startCmd.TargetService)
+	err := p.deleteStoppedFromDB(ctx, nhController, startCmd.LocalPort, startCmd.RemotePort, _const.PortForwardTCP, "")
+	if err != nil {
+		log.LogE(err)
+	}
+	_ = p.recordPortForward(
+		startCmd.NameSpace, startCmd.Nid, startCmd.AppName, func() bool {
+			return nhController.IsPortForwarding()
+		},
+	)
+	p.notifyStopped(key, err)
+}
@@ -0,0 +1,149 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	"nocalhost/internal/nhctl/utils"
+	"sync"
+)
+
+// SyncEvent sync event of a service, streamed by daemon to IDEs
+type SyncEvent struct {
+	Namespace   string `json:"namespace"`
+	Application string `json:"application"`
+	Service     string `json:"service"`
+	ServiceType string `json:"serviceType"`
+	req.SyncEvent
+}
+
+// syncEventHub watches syncthing events of each syncing service once,
+// and fans them out to all subscribers
+type syncEventHub struct {
+	lock        sync.Mutex
+	watchers    map[string]context.CancelFunc
+	subscribers map[chan *SyncEvent]struct{}
+}
+
+var syncEvents = &syncEventHub{
+	watchers:    map[string]context.CancelFunc{},
+	subscribers: map[chan *SyncEvent]struct{}{},
+}
+
+// watch starts watching events of svc if not watched yet, the watcher stops itself
+// once syncthing is disconnected, and is started again after syncthing is reconnected
+func (h *syncEventHub) watch(svc *controller.Controller) {
+	key := toKey(svc)
+	h.lock.Lock()
+	if _, ok := h.watchers[key]; ok {
+		h.lock.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.watchers[key] = cancel
+	h.lock.Unlock()
+
+	go func() {
+		defer utils.RecoverFromPanic()
+		defer func() {
+			h.lock.Lock()
+			delete(h.watchers, key)
+			h.lock.Unlock()
+		}()
+		svc.NewSyncthingHttpClient(2).WatchSyncEvents(
+			ctx, func(event req.SyncEvent) {
+				h.publish(
+					&SyncEvent{
+						Namespace:   svc.NameSpace,
+						Application: svc.AppName,
+						Service:     svc.Name,
+						ServiceType: svc.Type.String(),
+						SyncEvent:   event,
+					},
+				)
+				if event.Type == req.SyncEventDisconnected {
+					cancel()
+				}
+			},
+		)
+	}()
+}
+
+func (h *syncEventHub) publish(event *SyncEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for ch := range h.subscribers {
+		// slow subscriber misses events rather than blocks others
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (h *syncEventHub) subscribe() chan *SyncEvent {
+	ch := make(chan *SyncEvent, 256)
+	h.lock.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.lock.Unlock()
+	return ch
+}
+
+func (h *syncEventHub) unsubscribe(ch chan *SyncEvent) {
+	h.lock.Lock()
+	delete(h.subscribers, ch)
+	h.lock.Unlock()
+}
+
+// handlingSyncEvents streams sync events as json lines, events can be filtered by query
+// namespace, application, name and type, all services' events are streamed if no filter
+func handlingSyncEvents(w http.ResponseWriter, r *http.Request) {
+	crossOriginFilter(w)
+
+	if r.Method != "GET" {
+		w.WriteHeader(405)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		fail(w, "Streaming is unsupported")
+		return
+	}
+
+	query := r.URL.Query()
+	match := func(event *SyncEvent) bool {
+		return (query.Get("namespace") == "" || query.Get("namespace") == event.Namespace) &&
+			(query.Get("application") == "" || query.Get("application") == event.Application) &&
+			(query.Get("name") == "" || query.Get("name") == event.Service) &&
+			(query.Get("type") == "" || query.Get("type") == event.ServiceType)
+	}
+
+	ch := syncEvents.subscribe()
+	defer syncEvents.unsubscribe(ch)
+
+	w.Header().Set("content-type", "application/x-ndjson")
+	w.WriteHeader(200)
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-ch:
+			if !match(event) {
+				continue
+			}
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -107,6 +107,22 @@ func toKey(controller2 *controller.Controller) string {
 	)
 }
 
+// ignoreRegenerateInterval regenerating ignore file walks the local sync dir to find large files,
+// so it's not done on every syncthing check
+const ignoreRegenerateInterval = 5 * time.Minute
+
+// namespace-nid-appName-serviceType-serviceName -> time of the last regenerating
+var ignoreRegenerated sync.Map
+
+// ignoreRegenerateDue returns true if ignore file of key has not been regenerated in ignoreRegenerateInterval
+func ignoreRegenerateDue(key string, now time.Time) bool {
+	if last, ok := ignoreRegenerated.Load(key); ok && now.Sub(last.(time.Time)) < ignoreRegenerateInterval {
+		return false
+	}
+	ignoreRegenerated.Store(key, now)
+	return true
+}
+
 type backoff struct {
 	times    int
 	lastTime time.Time
@@ -150,13 +166,17 @@ func reconnectedSyncthingIfNeeded() {
 			if !svc.IsProcessor() {
 				continue
 			}
+			// sync engine exec has no syncthing to recover
+			if svc.GetDevSyncEngine() == _const.ExecSyncEngine {
+				continue
+			}
 			// reconnect two times:
 			// pre each time, check syncthing connections, if remote device connection is connected, no needs to recover
 			// if remote device connection is not connected, but have this connection, just to do port-forward
 			// the first time: using old port-forward, just create a new syncthing process
 			//   detect syncthing service is available or not, if it's still not available
 			// the second time: redo port-forward, and create a new syncthing process
-			go func(svc *controller.Controller) {
+			go func(svc *controller.Controller, container string) {
 				defer utils.RecoverFromPanic()
 				var err error
 				for i := 0; i < 2; i++ {
@@ -174,6 +194,13 @@ func reconnectedSyncthingIfNeeded() {
 						return errors.New("needs to reconnect")
 					}); err == nil {
 						maps.Delete(toKey(svc))
+						// .gitignore or .dockerignore may change while developing
+						if ignoreRegenerateDue(toKey(svc), time.Now()) {
+							if err = svc.RegenerateSyncIgnoreIfChanged(container); err != nil {
+								log.WarnE(err, "Failed to regenerate ignored pattern")
+							}
+						}
+						syncEvents.watch(svc)
 						return
 					}
 					v, _ := maps.LoadOrStore(toKey(svc), &backoff{times: 0, lastTime: time.Now(), nextTime: time.Now()})
@@ -196,7 +223,7 @@ func reconnectedSyncthingIfNeeded() {
 							svc.AppMeta.Ns, svc.AppMeta.Application, svc.Name, svc.Type, err)
 					}
 				}
-			}(svc)
+			}(svc, svcProfile.OriginDevContainer)
 		}
 	}
 }
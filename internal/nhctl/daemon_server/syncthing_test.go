@@ -0,0 +1,28 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIgnoreRegenerateDue(t *testing.T) {
+	now := time.Now()
+	key := "default-nid-bookinfo-deployment-reviews"
+	if !ignoreRegenerateDue(key, now) {
+		t.Fatal("first regenerating should be due")
+	}
+	if ignoreRegenerateDue(key, now.Add(30*time.Second)) {
+		t.Fatal("regenerating should be throttled")
+	}
+	if !ignoreRegenerateDue("default-nid-bookinfo-deployment-ratings", now.Add(30*time.Second)) {
+		t.Fatal("services are throttled separately")
+	}
+	if !ignoreRegenerateDue(key, now.Add(ignoreRegenerateInterval)) {
+		t.Fatal("regenerating should be due after the interval")
+	}
+}
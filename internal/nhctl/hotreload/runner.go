@@ -0,0 +1,167 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+// Package hotreload supervises the run command in dev container, and restarts
+// or signals it after each completed sync batch
+package hotreload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"nocalhost/internal/nhctl/syncthing/ignore"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultDebounce = time.Second
+
+	stopTimeout = 10 * time.Second
+)
+
+// Executor runs cmd in dev container, and returns when cmd exits
+type Executor func(cmd []string, stdout, stderr io.Writer) error
+
+// Runner restarts Command after files matching Patterns are synced, all files match if Patterns is empty.
+// If Signal is specified, such as HUP, the running command is signaled instead of restarted
+type Runner struct {
+	Command  []string
+	Debounce time.Duration
+	Patterns []string
+	Signal   string
+	// PidFile in dev container recording pid of the running command, see PidFile
+	PidFile string
+	Exec    Executor
+	Stdout  io.Writer
+	Stderr  io.Writer
+	// Log writes the restart log
+	Log func(format string, a ...interface{})
+}
+
+// PidFile pid of the run command of container, each dev container has its own
+func PidFile(container string) string {
+	return fmt.Sprintf("/tmp/.nocalhost-hot-reload-%s.pid", container)
+}
+
+// Run starts Command and supervises it by sync events until ctx is done or events is closed
+func (r *Runner) Run(ctx context.Context, events <-chan req.SyncEvent) {
+	if r.Debounce <= 0 {
+		r.Debounce = DefaultDebounce
+	}
+	r.Log("Starting %s", strings.Join(r.Command, " "))
+	exited := r.start()
+	changed := map[string]struct{}{}
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			r.stop(exited)
+			return
+		case err := <-exited:
+			exited = nil
+			if err != nil {
+				r.Log("Command exited: %s, waiting for changes to restart", err.Error())
+			} else {
+				r.Log("Command exited, waiting for changes to restart")
+			}
+		case event, ok := <-events:
+			if !ok {
+				r.stop(exited)
+				return
+			}
+			switch event.Type {
+			case req.SyncEventFilePushed:
+				// patterns are in syncthing's syntax, and the first matched one decides,
+				// so that files can be excluded by negated patterns, such as !*_test.go
+				if len(r.Patterns) == 0 || ignore.Match(r.Patterns, event.Path) {
+					changed[event.Path] = struct{}{}
+				}
+			case req.SyncEventSynced:
+				// restart after the batch, and more batches in debounce are merged
+				if len(changed) > 0 {
+					debounce = time.After(r.Debounce)
+				}
+			}
+		case <-debounce:
+			debounce = nil
+			files := make([]string, 0, len(changed))
+			for f := range changed {
+				files = append(files, f)
+			}
+			sort.Strings(files)
+			changed = map[string]struct{}{}
+
+			if r.Signal != "" && exited != nil {
+				if err := r.kill(r.Signal); err != nil {
+					r.Log("Failed to signal %s: %s", r.Signal, err.Error())
+				} else {
+					r.Log("Signaled %s, %s", r.Signal, summary(files))
+				}
+				continue
+			}
+			r.stop(exited)
+			exited = r.start()
+			r.Log("Restarted, %s", summary(files))
+		}
+	}
+}
+
+// RunOnce runs Command until it exits, it's stopped if ctx is done
+func (r *Runner) RunOnce(ctx context.Context) error {
+	exited := r.start()
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+		r.stop(exited)
+		return nil
+	}
+}
+
+func (r *Runner) start() chan error {
+	exited := make(chan error, 1)
+	// pid is recorded to stop or signal it later, exec keeps the pid
+	cmd := append([]string{"sh", "-c", "echo $$ > " + r.PidFile + "; exec \"$@\"", "sh"}, r.Command...)
+	go func() {
+		exited <- r.Exec(cmd, r.Stdout, r.Stderr)
+	}()
+	return exited
+}
+
+// stop terminates the running command, and kills it if it does not exit in time
+func (r *Runner) stop(exited chan error) {
+	if exited == nil {
+		return
+	}
+	_ = r.kill("TERM")
+	select {
+	case <-exited:
+		return
+	case <-time.After(stopTimeout):
+	}
+	r.Log("Command does not exit in %s, killing it", stopTimeout.String())
+	_ = r.kill("KILL")
+	select {
+	case <-exited:
+	case <-time.After(stopTimeout):
+	}
+}
+
+func (r *Runner) kill(signal string) error {
+	return r.Exec(
+		[]string{"sh", "-c", "kill -" + strings.TrimPrefix(signal, "SIG") + " $(cat " + r.PidFile + ")"}, nil, nil,
+	)
+}
+
+func summary(files []string) string {
+	const max = 5
+	if len(files) > max {
+		return fmt.Sprintf("%d files changed: %s, ...", len(files), strings.Join(files[:max], ", "))
+	}
+	return fmt.Sprintf("%d files changed: %s", len(files), strings.Join(files, ", "))
+}
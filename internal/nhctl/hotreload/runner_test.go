@@ -0,0 +1,70 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package hotreload
+
+import (
+	"context"
+	"io"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunnerRestartsAfterSynced(t *testing.T) {
+	lock := sync.Mutex{}
+	starts, kills := 0, 0
+	stopped := make(chan struct{}, 10)
+	r := &Runner{
+		Command:  []string{"python", "app.py"},
+		Debounce: 10 * time.Millisecond,
+		Patterns: []string{"!*_test.py", "*.py"},
+		PidFile:  PidFile("reviews"),
+		Exec: func(cmd []string, stdout, stderr io.Writer) error {
+			lock.Lock()
+			if !strings.Contains(cmd[2], PidFile("reviews")) {
+				t.Errorf("pid file of the container is not used: %v", cmd)
+			}
+			if strings.HasPrefix(cmd[2], "kill") {
+				kills++
+				lock.Unlock()
+				stopped <- struct{}{}
+				return nil
+			}
+			starts++
+			lock.Unlock()
+			<-stopped
+			return nil
+		},
+		Log: func(format string, a ...interface{}) {},
+	}
+
+	events := make(chan req.SyncEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, events)
+		close(done)
+	}()
+
+	// excluded file does not trigger restart
+	events <- req.SyncEvent{Type: req.SyncEventFilePushed, Path: "app_test.py"}
+	events <- req.SyncEvent{Type: req.SyncEventSynced}
+	time.Sleep(50 * time.Millisecond)
+	events <- req.SyncEvent{Type: req.SyncEventFilePushed, Path: "src/app.py"}
+	events <- req.SyncEvent{Type: req.SyncEventSynced}
+	events <- req.SyncEvent{Type: req.SyncEventSynced}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	lock.Lock()
+	defer lock.Unlock()
+	if starts != 2 || kills != 2 {
+		t.Fatalf("expected 2 starts and 2 kills, got %d starts and %d kills", starts, kills)
+	}
+}
@@ -32,4 +32,14 @@ type DevStartOptions struct {
 
 	DevModeType string
 	MeshHeader  map[string]string
+	// MirrorPercent duplicate dev pod receives a shadow copy of the percentage of requests to the workload,
+	// responses still come from the workload, 0 means no mirroring
+	MirrorPercent int
+
+	// ExtraContainers enter DevMode along with Container in a single dev session,
+	// key is the container name, value is the local dir to sync to its workDir
+	ExtraContainers map[string]string
+
+	// Ordinal only the pod of the ordinal of a StatefulSet enters replace DevMode, nil means all pods
+	Ordinal *int
 }
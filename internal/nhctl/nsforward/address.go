@@ -0,0 +1,54 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package nsforward
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+)
+
+const (
+	ClusterDomain = "cluster.local"
+	// LocalhostAddress is used by all services if loopback aliases are unavailable,
+	// ports of services are allocated uniquely on it instead
+	LocalhostAddress = "127.0.0.1"
+)
+
+// AllocateAddress returns a loopback address of the service, which is stable for the same namespace and name,
+// addresses in used are skipped. Addresses are in 127.16.0.1 - 127.255.255.254
+func AllocateAddress(namespace, name string, used map[string]bool) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace + "/" + name))
+	n := h.Sum32()
+	for {
+		address := fmt.Sprintf("127.%d.%d.%d", 16+n%240, (n/240)%256, 1+(n/240/256)%254)
+		if !used[address] {
+			return address
+		}
+		n++
+	}
+}
+
+// HostNames names of the service to resolve to its local address
+func HostNames(namespace, name string) []string {
+	return []string{
+		fmt.Sprintf("%s.%s", name, namespace),
+		fmt.Sprintf("%s.%s.svc", name, namespace),
+		fmt.Sprintf("%s.%s.svc.%s", name, namespace, ClusterDomain),
+	}
+}
+
+// loopbackAliasUsable linux routes the whole 127.0.0.0/8 to loopback, but on macOS
+// an alias of lo0 must be added by root before it can be listened on
+func loopbackAliasUsable() bool {
+	listener, err := net.Listen("tcp", "127.16.0.1:0")
+	if err != nil {
+		return false
+	}
+	_ = listener.Close()
+	return true
+}
@@ -0,0 +1,374 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package nsforward
+
+import (
+	"context"
+	"fmt"
+	miekgdns "github.com/miekg/dns"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"net"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/vpn/dns"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options HostsFile is where the managed block is written, empty means not to write,
+// DNSAddress is where the embedded dns server listens on, such as 127.0.0.1:5353, empty means not to serve
+type Options struct {
+	HostsFile  string
+	DNSAddress string
+}
+
+// Forwarder forwards all tcp ports of services in a namespace to a loopback address per service,
+// services are watched, so new services are forwarded and deleted ones are stopped
+type Forwarder struct {
+	namespace  string
+	kubeconfig string
+	options    Options
+	client     *clientgoutils.ClientGoUtils
+	useAlias   bool
+
+	lock     sync.Mutex
+	services map[string]*service
+	hostsErr error
+	dns      *miekgdns.Server
+	cancel   context.CancelFunc
+}
+
+type service struct {
+	daemon_common.NamespaceForwardService
+	spec      []corev1.ServicePort
+	stopCh    chan struct{}
+	listeners map[int]net.Listener // closed as soon as stopped, so ports can be listened on again
+}
+
+func NewForwarder(kubeconfig, namespace string, options Options) (*Forwarder, error) {
+	client, err := clientgoutils.NewClientGoUtils(kubeconfig, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &Forwarder{
+		namespace:  namespace,
+		kubeconfig: kubeconfig,
+		options:    options,
+		client:     client,
+		useAlias:   loopbackAliasUsable(),
+		services:   map[string]*service{},
+	}, nil
+}
+
+// Start forwards existing services before returning, and keeps watching services in background
+func (f *Forwarder) Start() error {
+	list, err := f.client.ClientSet.CoreV1().Services(f.namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if !f.useAlias {
+		log.Warnf("Loopback aliases are unavailable, services of %s are forwarded to %s", f.namespace, LocalhostAddress)
+	}
+
+	if f.options.DNSAddress != "" {
+		f.dns = dns.NewHostsDNSServer("udp", f.options.DNSAddress, f.Lookup)
+		listening := make(chan struct{})
+		f.dns.NotifyStartedFunc = func() { close(listening) }
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- f.dns.ListenAndServe()
+		}()
+		select {
+		case <-listening:
+		case err = <-errCh:
+			return errors.Wrap(err, fmt.Sprintf("Failed to serve dns on %s", f.options.DNSAddress))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	f.cancel = cancel
+	f.sync(list.Items)
+	go f.watch(ctx, list.ResourceVersion)
+	return nil
+}
+
+// Stop stops forwarding all services and removes the managed block from hosts file
+func (f *Forwarder) Stop() {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	if f.dns != nil {
+		_ = f.dns.Shutdown()
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for name := range f.services {
+		f.remove(name)
+	}
+	f.writeHosts()
+}
+
+// Lookup returns the local address of hostname, nil if it's not a name of forwarded services
+func (f *Forwarder) Lookup(name string) net.IP {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, s := range f.services {
+		for _, h := range s.Hostnames {
+			if h == name {
+				return net.ParseIP(s.Address)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Forwarder) Status() *daemon_common.NamespaceForwardStatus {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	status := &daemon_common.NamespaceForwardStatus{
+		Namespace:  f.namespace,
+		KubeConfig: f.kubeconfig,
+		HostsFile:  f.options.HostsFile,
+		DNSAddress: f.options.DNSAddress,
+		Services:   f.sortedServices(),
+	}
+	if f.hostsErr != nil {
+		status.HostsError = f.hostsErr.Error()
+	}
+	return status
+}
+
+func (f *Forwarder) sortedServices() []*daemon_common.NamespaceForwardService {
+	result := make([]*daemon_common.NamespaceForwardService, 0, len(f.services))
+	for _, s := range f.services {
+		svc := s.NamespaceForwardService
+		result = append(result, &svc)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// watch keeps services up to date until ctx done
+func (f *Forwarder) watch(ctx context.Context, resourceVersion string) {
+	api := f.client.ClientSet.CoreV1().Services(f.namespace)
+	for {
+		w, err := api.Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+		if err == nil {
+			resourceVersion = f.handleEvents(ctx, w, resourceVersion)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+		// events may be missed, resync all services
+		list, err := api.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.WarnE(errors.Wrap(err, ""), fmt.Sprintf("Failed to list services of %s", f.namespace))
+			continue
+		}
+		resourceVersion = list.ResourceVersion
+		f.sync(list.Items)
+	}
+}
+
+func (f *Forwarder) handleEvents(ctx context.Context, w watch.Interface, resourceVersion string) string {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion
+			}
+			svc, ok := e.Object.(*corev1.Service)
+			if !ok {
+				return resourceVersion
+			}
+			resourceVersion = svc.ResourceVersion
+			f.lock.Lock()
+			if e.Type == watch.Deleted {
+				f.remove(svc.Name)
+			} else {
+				f.add(svc)
+			}
+			f.writeHosts()
+			f.lock.Unlock()
+		}
+	}
+}
+
+// sync forwards services in list, and stops the others
+func (f *Forwarder) sync(list []corev1.Service) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	names := map[string]bool{}
+	for i := range list {
+		names[list[i].Name] = true
+		f.add(&list[i])
+	}
+	for name := range f.services {
+		if !names[name] {
+			f.remove(name)
+		}
+	}
+	f.writeHosts()
+}
+
+// add forwards tcp ports of svc, it's restarted if ports of svc are changed, f.lock must be held
+func (f *Forwarder) add(svc *corev1.Service) {
+	ports := make([]corev1.ServicePort, 0)
+	for _, p := range svc.Spec.Ports {
+		if p.Protocol != corev1.ProtocolUDP && p.Protocol != corev1.ProtocolSCTP {
+			ports = append(ports, p)
+		}
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName || len(ports) == 0 {
+		f.remove(svc.Name)
+		return
+	}
+
+	address := LocalhostAddress
+	if old, ok := f.services[svc.Name]; ok {
+		if equalServicePorts(old.spec, ports) {
+			return
+		}
+		address = old.Address
+		f.remove(svc.Name)
+	} else if f.useAlias {
+		used := map[string]bool{}
+		for _, s := range f.services {
+			used[s.Address] = true
+		}
+		address = AllocateAddress(f.namespace, svc.Name, used)
+	}
+
+	s := &service{
+		NamespaceForwardService: daemon_common.NamespaceForwardService{
+			Name:    svc.Name,
+			Address: address,
+		},
+		spec:      ports,
+		stopCh:    make(chan struct{}),
+		listeners: map[int]net.Listener{},
+	}
+	for _, p := range ports {
+		listener, err := listen(address, int(p.Port))
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to forward port %d of svc/%s", p.Port, svc.Name))
+			continue
+		}
+		localPort := listener.Addr().(*net.TCPAddr).Port
+		s.Ports = append(s.Ports, daemon_common.NamespaceForwardPort{Name: p.Name, Port: int(p.Port), LocalPort: localPort})
+		s.listeners[int(p.Port)] = listener
+		go f.forward(s, listener, int(p.Port))
+	}
+	if Publishable(address, len(ports), s.Ports) {
+		s.Hostnames = HostNames(f.namespace, svc.Name)
+	}
+	f.services[svc.Name] = s
+	log.Infof("Forwarding svc/%s to %s %v", svc.Name, address, s.Ports)
+}
+
+// Publishable hostnames of the service are published only if it owns the address and all its ports,
+// otherwise they would resolve to another service sharing the address, or to a wrong port
+func Publishable(address string, servicePorts int, ports []daemon_common.NamespaceForwardPort) bool {
+	if address == LocalhostAddress || len(ports) != servicePorts {
+		return false
+	}
+	for _, p := range ports {
+		if p.LocalPort != p.Port {
+			return false
+		}
+	}
+	return true
+}
+
+// remove stops forwarding service name, f.lock must be held
+func (f *Forwarder) remove(name string) {
+	if s, ok := f.services[name]; ok {
+		close(s.stopCh)
+		for _, listener := range s.listeners {
+			_ = listener.Close()
+		}
+		delete(f.services, name)
+		log.Infof("Stop forwarding svc/%s", name)
+	}
+}
+
+// forward serves listener until s is stopped, it's retried on the same local port if failed
+func (f *Forwarder) forward(s *service, listener net.Listener, port int) {
+	address := listener.Addr().String()
+	for {
+		err := f.client.ForwardServiceWithListener(
+			s.Name, listener, port, nil, s.stopCh, genericclioptions.IOStreams{}, nil,
+		)
+		select {
+		case <-s.stopCh:
+			return
+		case <-time.After(5 * time.Second):
+		}
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Forwarding %s to svc/%s:%d occurs errors, retrying", address, s.Name, port))
+		}
+		if listener, err = net.Listen("tcp", address); err != nil {
+			log.WarnE(errors.Wrap(err, ""), fmt.Sprintf("Failed to listen on %s", address))
+			return
+		}
+		f.lock.Lock()
+		select {
+		case <-s.stopCh:
+			_ = listener.Close()
+			f.lock.Unlock()
+			return
+		default:
+			s.listeners[port] = listener
+		}
+		f.lock.Unlock()
+	}
+}
+
+// listen listens on port of address, or a random port if port is unavailable, such as
+// privileged ports for non-root users or the port is in use
+func listen(address string, port int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", address, port))
+	if err == nil {
+		return listener, nil
+	}
+	listener, err = net.Listen("tcp", address+":0")
+	return listener, errors.Wrap(err, "")
+}
+
+func equalServicePorts(a, b []corev1.ServicePort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Port != b[i].Port || a[i].TargetPort != b[i].TargetPort {
+			return false
+		}
+	}
+	return true
+}
+
+// writeHosts writes hostnames of forwarded services to hosts file, f.lock must be held
+func (f *Forwarder) writeHosts() {
+	if f.options.HostsFile == "" {
+		return
+	}
+	f.hostsErr = WriteHosts(f.options.HostsFile, f.namespace, PublishedServices(f.sortedServices()))
+	if f.hostsErr != nil && !strings.Contains(f.hostsErr.Error(), "permission denied") {
+		log.WarnE(f.hostsErr, fmt.Sprintf("Failed to write %s", f.options.HostsFile))
+	}
+}
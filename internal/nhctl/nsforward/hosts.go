@@ -0,0 +1,87 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package nsforward
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/daemon_common"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultHostsFile hosts file of the system, root or administrator is needed to write it
+func DefaultHostsFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+func blockMarkers(namespace string) (string, string) {
+	return fmt.Sprintf("# nocalhost port-forward namespace %s begin", namespace),
+		fmt.Sprintf("# nocalhost port-forward namespace %s end", namespace)
+}
+
+// PublishedServices services whose hostnames are published
+func PublishedServices(services []*daemon_common.NamespaceForwardService) []*daemon_common.NamespaceForwardService {
+	result := make([]*daemon_common.NamespaceForwardService, 0, len(services))
+	for _, s := range services {
+		if len(s.Hostnames) > 0 {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// RenderHosts replaces the managed block of namespace in content by hostnames of services,
+// the block is removed if there are no services, lines out of the block are kept
+func RenderHosts(content, namespace string, services []*daemon_common.NamespaceForwardService) string {
+	begin, end := blockMarkers(namespace)
+	lines := make([]string, 0)
+	inBlock := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		switch {
+		case strings.TrimSpace(line) == begin:
+			inBlock = true
+		case strings.TrimSpace(line) == end:
+			inBlock = false
+		case !inBlock:
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		lines = lines[:0]
+	}
+
+	if len(services) > 0 {
+		lines = append(lines, begin)
+		for _, s := range services {
+			lines = append(lines, s.Address+" "+strings.Join(s.Hostnames, " "))
+		}
+		lines = append(lines, end)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// WriteHosts writes the managed block of namespace to hosts file
+func WriteHosts(path, namespace string, services []*daemon_common.NamespaceForwardService) error {
+	bys, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "")
+	}
+	content := RenderHosts(string(bys), namespace, services)
+	if content == string(bys) {
+		return nil
+	}
+	return errors.Wrap(ioutil.WriteFile(path, []byte(content), 0644), "")
+}
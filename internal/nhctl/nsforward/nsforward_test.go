@@ -0,0 +1,80 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package nsforward
+
+import (
+	"net"
+	"nocalhost/internal/nhctl/daemon_common"
+	"testing"
+)
+
+func TestAllocateAddress(t *testing.T) {
+	address := AllocateAddress("my-ns", "orders", nil)
+	if ip := net.ParseIP(address); ip == nil || !ip.IsLoopback() || address == LocalhostAddress {
+		t.Fatalf("%s is not a loopback alias", address)
+	}
+	if again := AllocateAddress("my-ns", "orders", map[string]bool{}); again != address {
+		t.Fatalf("address is not stable, %s != %s", again, address)
+	}
+	if other := AllocateAddress("my-ns", "orders", map[string]bool{address: true}); other == address {
+		t.Fatalf("used address %s is allocated again", address)
+	}
+}
+
+func TestRenderHosts(t *testing.T) {
+	services := []*daemon_common.NamespaceForwardService{
+		{Name: "orders", Address: "127.16.0.2", Hostnames: HostNames("my-ns", "orders")},
+	}
+	origin := "127.0.0.1 localhost\n"
+
+	content := RenderHosts(origin, "my-ns", services)
+	expected := origin +
+		"# nocalhost port-forward namespace my-ns begin\n" +
+		"127.16.0.2 orders.my-ns orders.my-ns.svc orders.my-ns.svc.cluster.local\n" +
+		"# nocalhost port-forward namespace my-ns end\n"
+	if content != expected {
+		t.Fatalf("unexpected hosts:\n%s", content)
+	}
+
+	// the block is replaced instead of appended
+	if again := RenderHosts(content, "my-ns", services); again != expected {
+		t.Fatalf("unexpected hosts:\n%s", again)
+	}
+	// blocks of other namespaces are kept
+	other := RenderHosts(content, "other", services)
+	if RenderHosts(RenderHosts(other, "other", nil), "my-ns", nil) != origin {
+		t.Fatalf("unexpected hosts:\n%s", other)
+	}
+	if RenderHosts("", "my-ns", nil) != "" {
+		t.Fatal("unexpected hosts of empty content")
+	}
+}
+
+func TestPublishable(t *testing.T) {
+	ports := []daemon_common.NamespaceForwardPort{{Port: 80, LocalPort: 80}, {Port: 8080, LocalPort: 8080}}
+	if !Publishable("127.16.0.2", 2, ports) {
+		t.Fatal("service owning its address and ports should be published")
+	}
+	if Publishable(LocalhostAddress, 2, ports) {
+		t.Fatal("service sharing the address should not be published")
+	}
+	if Publishable("127.16.0.2", 3, ports) {
+		t.Fatal("service with ports not listened on should not be published")
+	}
+	moved := []daemon_common.NamespaceForwardPort{{Port: 80, LocalPort: 51234}, {Port: 8080, LocalPort: 8080}}
+	if Publishable("127.16.0.2", 2, moved) {
+		t.Fatal("service with ports moved to others should not be published")
+	}
+
+	services := []*daemon_common.NamespaceForwardService{
+		{Name: "orders", Address: "127.16.0.2", Hostnames: HostNames("my-ns", "orders")},
+		{Name: "payments", Address: LocalhostAddress, Ports: moved},
+	}
+	published := PublishedServices(services)
+	if len(published) != 1 || published[0].Name != "orders" {
+		t.Fatalf("unexpected published services %v", published)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package pfstats
+
+import (
+	"net"
+	_const "nocalhost/internal/nhctl/const"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats traffic of a port-forward since it's started, In is from remote to local, Out is from local to remote
+type Stats struct {
+	ActiveConnections int64     `json:"activeConnections" yaml:"activeConnections"`
+	TotalConnections  int64     `json:"totalConnections" yaml:"totalConnections"`
+	BytesIn           int64     `json:"bytesIn" yaml:"bytesIn"`
+	BytesOut          int64     `json:"bytesOut" yaml:"bytesOut"`
+	Errors            int64     `json:"errors" yaml:"errors"`
+	LastActivity      time.Time `json:"lastActivity" yaml:"lastActivity"`
+}
+
+// Counter counts traffic of a port-forward, methods of nil Counter do nothing,
+// so forwarders not managed by daemon need no counter
+type Counter struct {
+	active, total, in, out, errors int64
+	lastActivity                   int64
+}
+
+// counterKey tcp and udp port-forwards may listen on the same local port
+type counterKey struct {
+	localPort int
+	protocol  string
+}
+
+var (
+	lock     sync.Mutex
+	counters = map[counterKey]*Counter{}
+)
+
+func keyOf(localPort int, protocol string) counterKey {
+	if protocol == "" {
+		protocol = _const.PortForwardTCP
+	}
+	return counterKey{localPort: localPort, protocol: protocol}
+}
+
+// NewCounter creates a counter which is not registered, it's passed to the forwarder directly
+func NewCounter() *Counter {
+	return &Counter{lastActivity: time.Now().UnixNano()}
+}
+
+// Register creates the counter of local port in protocol, forwarders of the port count traffic to it,
+// empty protocol is tcp
+func Register(localPort int, protocol string) *Counter {
+	lock.Lock()
+	defer lock.Unlock()
+	c := NewCounter()
+	counters[keyOf(localPort, protocol)] = c
+	return c
+}
+
+// Unregister removes the counter of local port in protocol
+func Unregister(localPort int, protocol string, c *Counter) {
+	lock.Lock()
+	defer lock.Unlock()
+	if k := keyOf(localPort, protocol); counters[k] == c {
+		delete(counters, k)
+	}
+}
+
+// Lookup returns the counter of local port in protocol, nil if it's not registered
+func Lookup(localPort int, protocol string) *Counter {
+	lock.Lock()
+	defer lock.Unlock()
+	return counters[keyOf(localPort, protocol)]
+}
+
+func (c *Counter) touch() {
+	atomic.StoreInt64(&c.lastActivity, time.Now().UnixNano())
+}
+
+// Opened a connection is accepted
+func (c *Counter) Opened() {
+	if c == nil {
+		return
+	}
+	atomic.AddInt64(&c.active, 1)
+	atomic.AddInt64(&c.total, 1)
+	c.touch()
+}
+
+// Closed a connection is closed
+func (c *Counter) Closed() {
+	if c == nil {
+		return
+	}
+	atomic.AddInt64(&c.active, -1)
+	c.touch()
+}
+
+// In bytes from remote to local
+func (c *Counter) In(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.in, int64(n))
+	c.touch()
+}
+
+// Out bytes from local to remote
+func (c *Counter) Out(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.out, int64(n))
+	c.touch()
+}
+
+// Error forwarding a connection failed
+func (c *Counter) Error() {
+	if c == nil {
+		return
+	}
+	atomic.AddInt64(&c.errors, 1)
+}
+
+// Idle how long no connection is active and no byte is transferred
+func (c *Counter) Idle() time.Duration {
+	if c == nil || atomic.LoadInt64(&c.active) > 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, atomic.LoadInt64(&c.lastActivity)))
+}
+
+// Snapshot current stats
+func (c *Counter) Snapshot() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	return Stats{
+		ActiveConnections: atomic.LoadInt64(&c.active),
+		TotalConnections:  atomic.LoadInt64(&c.total),
+		BytesIn:           atomic.LoadInt64(&c.in),
+		BytesOut:          atomic.LoadInt64(&c.out),
+		Errors:            atomic.LoadInt64(&c.errors),
+		LastActivity:      time.Unix(0, atomic.LoadInt64(&c.lastActivity)),
+	}
+}
+
+type countedConn struct {
+	net.Conn
+	c *Counter
+}
+
+func (cc *countedConn) Read(b []byte) (int, error) {
+	n, err := cc.Conn.Read(b)
+	cc.c.Out(n)
+	return n, err
+}
+
+func (cc *countedConn) Write(b []byte) (int, error) {
+	n, err := cc.Conn.Write(b)
+	cc.c.In(n)
+	return n, err
+}
+
+// Wrap counts bytes read from local conn as out, and bytes written to it as in
+func (c *Counter) Wrap(conn net.Conn) net.Conn {
+	if c == nil {
+		return conn
+	}
+	return &countedConn{Conn: conn, c: c}
+}
@@ -0,0 +1,55 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package pfstats
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	c := Register(18080, "")
+	defer Unregister(18080, "tcp", c)
+	if Lookup(18080, "tcp") != c {
+		t.Fatal("counter is not registered")
+	}
+	udp := Register(18080, "udp")
+	if Lookup(18080, "udp") != udp || Lookup(18080, "tcp") != c {
+		t.Fatal("tcp and udp counters of the same port should not overwrite each other")
+	}
+	Unregister(18080, "udp", udp)
+	if Lookup(18080, "udp") != nil || Lookup(18080, "") != c {
+		t.Fatal("only udp counter should be unregistered")
+	}
+
+	local, remote := net.Pipe()
+	go func() {
+		_, _ = remote.Write([]byte("hello"))
+		_ = remote.Close()
+	}()
+	conn := c.Wrap(local)
+	c.Opened()
+	if c.Idle() != 0 {
+		t.Errorf("active port-forward is idle")
+	}
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Fatal(err)
+	}
+	c.Closed()
+
+	s := c.Snapshot()
+	if s.ActiveConnections != 0 || s.TotalConnections != 1 || s.BytesOut != 5 || s.BytesIn != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	var nilCounter *Counter
+	nilCounter.Opened()
+	nilCounter.In(1)
+	if nilCounter.Snapshot().BytesIn != 0 {
+		t.Errorf("nil counter counts")
+	}
+}
@@ -47,6 +47,25 @@ type ApplicationConfig struct {
 	Env            []*Env             `json:"env" yaml:"env"`
 	EnvFrom        EnvFrom            `json:"envFrom,omitempty" yaml:"envFrom,omitempty"`
 	ServiceConfigs []*ServiceConfigV2 `json:"services" yaml:"services,omitempty"`
+	DevGroups      []*DevGroup        `json:"devGroups,omitempty" yaml:"devGroups,omitempty"`
+
+	// DevModeTTL default idle timeout of DevMode for all services, such as 4h
+	DevModeTTL string `validate:"Duration" json:"devModeTTL,omitempty" yaml:"devModeTTL,omitempty"`
+}
+
+// DevGroup a named set of services entering DevMode in one transaction,
+// if any of them fails, all of them will be rolled back
+type DevGroup struct {
+	Name     string             `validate:"required" json:"name" yaml:"name"`
+	Services []*DevGroupService `validate:"dive" json:"services" yaml:"services"`
+}
+
+type DevGroupService struct {
+	Name      string `validate:"required" json:"name" yaml:"name"`
+	Type      string `json:"serviceType" yaml:"serviceType"`
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+	// local dir to sync, use the associated dir if not specified
+	LocalSync string `json:"localSync,omitempty" yaml:"localSync,omitempty"`
 }
 
 type HubConfig struct {
@@ -72,13 +91,19 @@ type ContainerDevConfig struct {
 	Command               *DevCommands           `json:"command" yaml:"command"`
 	DebugConfig           *DebugConfig           `json:"debug" yaml:"debug"`
 	HotReload             bool                   `json:"hotReload" yaml:"hotReload"`
+	HotReloadConfig       *HotReloadConfig       `json:"hotReloadConfig,omitempty" yaml:"hotReloadConfig,omitempty"`
 	UseDevContainer       bool                   `json:"useDevContainer,omitempty" yaml:"useDevContainer,omitempty"`
 	Sync                  *SyncConfig            `json:"sync" yaml:"sync"`
 	Env                   []*Env                 `json:"env" yaml:"env"`
 	EnvFrom               *EnvFrom               `json:"envFrom,omitempty" yaml:"envFrom,omitempty"`
 	PortForward           []string               `validate:"dive,PortForward" json:"portForward" yaml:"portForward"`
-	SidecarImage          string                 `json:"sidecarImage,omitempty" yaml:"sidecarImage,omitempty"`
-	Patches               []base.PatchItem       `json:"patches,omitempty" yaml:"patches,omitempty"`
+	// ReversePortForward such as 9000:9000, localhost:9000 of dev container is forwarded to local port 9000.
+	// It's tunnelled by ssh server of sidecar, which runs as root with the default root password
+	ReversePortForward []string         `validate:"dive,PortForward" json:"reversePortForward,omitempty" yaml:"reversePortForward,omitempty"`
+	SidecarImage       string           `json:"sidecarImage,omitempty" yaml:"sidecarImage,omitempty"`
+	Patches            []base.PatchItem `json:"patches,omitempty" yaml:"patches,omitempty"`
+	// DevModeTTL DevMode will be ended automatically after being idle for the duration, such as 4h
+	DevModeTTL string `validate:"Duration" json:"devModeTTL,omitempty" yaml:"devModeTTL,omitempty"`
 }
 
 type DevCommands struct {
@@ -89,12 +114,53 @@ type DevCommands struct {
 	HotReloadDebug []string `json:"hotReloadDebug,omitempty" yaml:"hotReloadDebug,omitempty"`
 }
 
+// HotReloadConfig how nhctl dev run --watch reloads the run command after files synced
+type HotReloadConfig struct {
+	// Debounce merges sync batches in the duration into one reload, default is 1s
+	Debounce string `validate:"Duration" json:"debounce,omitempty" yaml:"debounce,omitempty"`
+	// Patterns of changed files triggering reload, in the syntax of ignoreFilePattern
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+	// Signal is sent to the run command instead of restarting it, such as HUP
+	Signal string `json:"signal,omitempty" yaml:"signal,omitempty"`
+}
+
+// SyncTuning limits of file sync for slow links, such as VPN
+type SyncTuning struct {
+	// MaxSendKbps rate limit of sending to dev container, 0 is unlimited
+	MaxSendKbps int `validate:"min=0" json:"maxSendKbps,omitempty" yaml:"maxSendKbps,omitempty"`
+	// MaxRecvKbps rate limit of receiving from dev container, 0 is unlimited
+	MaxRecvKbps int `validate:"min=0" json:"maxRecvKbps,omitempty" yaml:"maxRecvKbps,omitempty"`
+	// Compression always, metadata or never, default is metadata
+	Compression     string `validate:"SyncCompression" json:"compression,omitempty" yaml:"compression,omitempty"`
+	RescanIntervalS int    `validate:"min=0" json:"rescanIntervalS,omitempty" yaml:"rescanIntervalS,omitempty"`
+	FsWatcherDelayS int    `validate:"min=0" json:"fsWatcherDelayS,omitempty" yaml:"fsWatcherDelayS,omitempty"`
+	// MaxFileSize files larger than it are not synced, such as 100Mi
+	MaxFileSize string `validate:"Quantity" json:"maxFileSize,omitempty" yaml:"maxFileSize,omitempty"`
+}
+
+// SyncMapping syncs a local dir to a dir of dev container, with its own type and ignored pattern
+type SyncMapping struct {
+	// Local dir, relative to the local sync dir if it's not absolute
+	Local string `validate:"required" json:"local" yaml:"local"`
+	// Remote absolute dir in dev container, it's mounted by an emptyDir, so contents of the image
+	// under it are hidden, use a dir not existing in the image or one whose contents are all synced
+	Remote            string   `validate:"required,startswith=/" json:"remote" yaml:"remote"`
+	Type              string   `validate:"SyncType" json:"type,omitempty" yaml:"type,omitempty"`
+	IgnoreFilePattern []string `json:"ignoreFilePattern,omitempty" yaml:"ignoreFilePattern,omitempty"`
+}
+
 type SyncConfig struct {
-	Type              string   `validate:"SyncType" json:"type" yaml:"type"`
-	Mode              string   `validate:"SyncMode" json:"mode,omitempty" yaml:"mode,omitempty"`
-	DeleteProtection  *bool    `json:"deleteProtection,omitempty" yaml:"deleteProtection,omitempty"`
-	FilePattern       []string `json:"filePattern" yaml:"filePattern"`
-	IgnoreFilePattern []string `json:"ignoreFilePattern" yaml:"ignoreFilePattern"`
+	Type              string      `validate:"SyncType" json:"type" yaml:"type"`
+	Mode              string      `validate:"SyncMode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	Engine            string      `validate:"SyncEngine" json:"engine,omitempty" yaml:"engine,omitempty"`
+	DeleteProtection  *bool       `json:"deleteProtection,omitempty" yaml:"deleteProtection,omitempty"`
+	FilePattern       []string    `json:"filePattern" yaml:"filePattern"`
+	IgnoreFilePattern []string    `json:"ignoreFilePattern" yaml:"ignoreFilePattern"`
+	IgnoreFrom        []string    `validate:"dive,IgnoreFrom" json:"ignoreFrom,omitempty" yaml:"ignoreFrom,omitempty"`
+	ReversePaths      []string    `validate:"dive,ReversePath" json:"reversePaths,omitempty" yaml:"reversePaths,omitempty"`
+	Tuning            *SyncTuning `json:"tuning,omitempty" yaml:"tuning,omitempty"`
+	// Mappings sync more local dirs to dev container besides local sync dir to workDir
+	Mappings []*SyncMapping `validate:"dive" json:"mappings,omitempty" yaml:"mappings,omitempty"`
 }
 
 type DebugConfig struct {
@@ -193,6 +259,18 @@ func isSvcConfigInHubMatch(svcConfig *ServiceConfigV2, container, image string)
 	return false
 }
 
+func (c *ApplicationConfig) GetDevGroup(name string) *DevGroup {
+	if c == nil {
+		return nil
+	}
+	for _, group := range c.DevGroups {
+		if group != nil && group.Name == name {
+			return group
+		}
+	}
+	return nil
+}
+
 func (c *ApplicationConfig) LoadManifests(tmpDir *fp.FilePathEnhance) []string {
 	if c == nil {
 		return []string{}
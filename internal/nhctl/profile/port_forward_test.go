@@ -77,3 +77,21 @@ func TestMacAddress(t *testing.T) {
 	all := strings.ReplaceAll(s, ":", "")
 	fmt.Println(all)
 }
+
+func TestDevPortForwardIs(t *testing.T) {
+	tcp := &DevPortForward{LocalPort: 8125, RemotePort: 8125}
+	udp := &DevPortForward{LocalPort: 8125, RemotePort: 8125, Protocol: "udp"}
+	reverse := &DevPortForward{LocalPort: 8125, RemotePort: 8125, Role: "REVERSE"}
+	if !tcp.Is(8125, 8125, "", "") || !tcp.Is(8125, 8125, "tcp", "") || tcp.Is(8125, 8125, "udp", "") {
+		t.Error("port-forward without protocol should be tcp only")
+	}
+	if !udp.Is(8125, 8125, "udp", "") || udp.Is(8125, 8125, "tcp", "") || udp.Is(8125, 8126, "udp", "") {
+		t.Error("udp port-forward should match udp only")
+	}
+	if tcp.Is(8125, 8125, "tcp", "REVERSE") || reverse.Is(8125, 8125, "tcp", "") {
+		t.Error("reverse port-forward should not match a tcp one of the same ports")
+	}
+	if !reverse.Is(8125, 8125, "", "REVERSE") || !(&DevPortForward{Role: "SYNC"}).Is(0, 0, "tcp", "") {
+		t.Error("port-forward should match its role")
+	}
+}
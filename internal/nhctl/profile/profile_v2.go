@@ -13,6 +13,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"net"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/dbutils"
 	"nocalhost/internal/nhctl/nocalhost_path"
 	"os"
@@ -30,6 +31,8 @@ const (
 	DefaultWorkDir   = "/home/nocalhost-dev"
 	DuplicateDevMode = DevModeType("duplicate")
 	ReplaceDevMode   = DevModeType("replace")
+	EphemeralDevMode = DevModeType("ephemeral")
+	LocalDevMode     = DevModeType("local")
 	NoneDevMode      = DevModeType("")
 )
 
@@ -44,6 +47,18 @@ func (d DevModeType) IsDuplicateDevMode() bool {
 	return d == DuplicateDevMode
 }
 
+// IsEphemeralDevMode ephemeral DevMode attaches dev container and sidecar to a running pod
+// as ephemeral containers, the owning workload will never be modified
+func (d DevModeType) IsEphemeralDevMode() bool {
+	return d == EphemeralDevMode
+}
+
+// IsLocalDevMode local DevMode runs the workload as a process on the developer machine,
+// in-cluster traffic of the workload is reversed to it through vpn
+func (d DevModeType) IsLocalDevMode() bool {
+	return d == LocalDevMode
+}
+
 func (d DevModeType) ToString() string {
 	if d == "" {
 		return string(ReplaceDevMode)
@@ -296,6 +311,13 @@ type SvcProfileV2 struct {
 	// recorded the container that enter the devmode
 	// notice: exit devmode will not set this value to null
 	OriginDevContainer string `json:"originDevContainer" yaml:"originDevContainer"`
+
+	// recorded the extra containers that enter the devmode along with OriginDevContainer,
+	// key is the container name, value is the local dir syncing to it
+	ExtraDevContainers map[string]string `json:"extraDevContainers" yaml:"extraDevContainers,omitempty"`
+
+	// recorded the sync engine of the dev container while entering devmode
+	SyncEngine string `json:"syncEngine" yaml:"syncEngine,omitempty"`
 }
 
 type ContainerProfileV2 struct {
@@ -317,6 +339,30 @@ type DevPortForward struct {
 	Sudo            bool              `json:"sudo" yaml:"sudo"`
 	DaemonServerPid int               `json:"daemonserverpid" yaml:"daemonserverpid"`
 	ServiceType     string            `json:"servicetype" yaml:"servicetype"`
+	Protocol        string            `json:"protocol,omitempty" yaml:"protocol,omitempty"` // empty is tcp
+	// TargetService port-forward to the service instead of PodName, Endpoints are its ready endpoints
+	TargetService string   `json:"targetService,omitempty" yaml:"targetService,omitempty"`
+	Endpoints     []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	// IdleTimeout the port-forward is stopped after no traffic for the duration
+	IdleTimeout string `json:"idleTimeout,omitempty" yaml:"idleTimeout,omitempty"`
+}
+
+// GetProtocol tcp or udp
+func (d *DevPortForward) GetProtocol() string {
+	if d.Protocol == "" {
+		return _const.PortForwardTCP
+	}
+	return d.Protocol
+}
+
+// Is returns true if d forwards localPort to remotePort in protocol, empty protocol is tcp.
+// A reverse port-forward only matches role REVERSE, it may use the same ports as a tcp one
+func (d *DevPortForward) Is(localPort, remotePort int, protocol, role string) bool {
+	if protocol == "" {
+		protocol = _const.PortForwardTCP
+	}
+	return d.LocalPort == localPort && d.RemotePort == remotePort && d.GetProtocol() == protocol &&
+		(d.Role == _const.ReversePortForwardRole) == (role == _const.ReversePortForwardRole)
 }
 
 func (s *SvcProfileV2) GetName() string {
@@ -0,0 +1,522 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+// Package syncengine engines syncing local files to dev container other than syncthing
+package syncengine
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mitchellh/go-ps"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/syncthing/daemon"
+	"nocalhost/internal/nhctl/syncthing/ignore"
+	"nocalhost/internal/nhctl/syncthing/terminate"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExecSyncPidFile    = "exec-sync.pid"
+	ExecSyncStatusFile = "exec-sync.status"
+	ExecSyncLogFile    = "exec-sync.log"
+	// ExecSyncIndexFile files synced from local, so that files deleted while the engine is stopped
+	// are still deleted from dev container
+	ExecSyncIndexFile = "exec-sync.index"
+
+	// DefaultExecSyncInterval how often local files are scanned
+	DefaultExecSyncInterval = 2 * time.Second
+	// DefaultExecSyncBatchSize max bytes of files pushed by one exec
+	DefaultExecSyncBatchSize = 8 << 20
+	// deleteBatchSize max files removed by one exec
+	deleteBatchSize = 200
+
+	ExecIdle    = "idle"
+	ExecSyncing = "syncing"
+	ExecError   = "error"
+)
+
+// Executor runs cmd in dev container, stdin can be nil
+type Executor func(cmd []string, stdin io.Reader, stdout io.Writer) error
+
+// Status of exec engine, written to the status file after each round
+type Status struct {
+	Status  string    `json:"status"`
+	Msg     string    `json:"msg"`
+	Pending int       `json:"pending"`
+	Updated time.Time `json:"updated"`
+}
+
+type fileState struct {
+	ModTime int64  `json:"modTime"`
+	Size    int64  `json:"size"`
+	Hash    string `json:"hash"`
+}
+
+// ExecEngine pushes local changes to dev container through kubernetes exec api,
+// files are compared by sha256 and changed files are streamed by tar in batches,
+// so neither sidecar nor extra ports are needed
+type ExecEngine struct {
+	LocalDir       string
+	RemoteDir      string
+	IgnoredPattern []string
+	IgnoreFrom     []string // ignore files of LocalDir which patterns are derived from
+	Interval       time.Duration
+	BatchSize      int
+	// Home dir of pid, status and log file
+	Home string
+	// Args of nhctl serving the engine in background
+	Args     []string
+	Executor Executor
+
+	local   map[string]fileState
+	remote  map[string]string
+	derived []string
+	// stamps of ignore files when derived was computed
+	ignoreStamps map[string]fileState
+}
+
+// Run starts nhctl with Args in background, which calls Serve
+func (e *ExecEngine) Run(ctx context.Context) error {
+	self, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err = os.MkdirAll(e.Home, 0700); err != nil {
+		return errors.Wrap(err, "")
+	}
+	logFile, err := os.OpenFile(
+		filepath.Join(e.Home, ExecSyncLogFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600,
+	)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(self, e.Args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = daemon.NewSysProcAttr()
+	if err = cmd.Start(); err != nil {
+		return errors.Wrap(err, "Failed to start exec sync engine")
+	}
+	log.Debugf("exec sync engine pid-%d running", cmd.Process.Pid)
+	return errors.Wrap(
+		ioutil.WriteFile(filepath.Join(e.Home, ExecSyncPidFile), []byte(strconv.Itoa(cmd.Process.Pid)), 0600), "",
+	)
+}
+
+// Serve syncs files until ctx is done
+func (e *ExecEngine) Serve(ctx context.Context) error {
+	if e.Interval <= 0 {
+		e.Interval = DefaultExecSyncInterval
+	}
+	if e.BatchSize <= 0 {
+		e.BatchSize = DefaultExecSyncBatchSize
+	}
+	e.local = e.loadIndex()
+	if err := e.deriveIgnore(nil); err != nil {
+		return err
+	}
+
+	for {
+		if e.remote == nil {
+			if err := e.loadRemoteIndex(); err != nil {
+				e.writeStatus(ExecError, err.Error(), 0)
+				log.WarnE(err, "Failed to load remote files")
+			}
+		}
+		if e.remote != nil {
+			if err := e.syncOnce(); err != nil {
+				e.writeStatus(ExecError, err.Error(), 0)
+				log.WarnE(err, "Failed to sync files")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.Interval):
+		}
+	}
+}
+
+// loadRemoteIndex hashes files in dev container, so that files not changed are never pushed
+func (e *ExecEngine) loadRemoteIndex() error {
+	remote, err := HashRemoteDir(e.Executor, e.RemoteDir)
+	if err != nil {
+		return err
+	}
+	e.remote = remote
+	return nil
+}
+
+func (e *ExecEngine) syncOnce() error {
+	current, err := e.scan()
+	if err != nil {
+		return err
+	}
+	// ignore files changed, files are scanned again with the new rules
+	if stamps := e.stampIgnoreFiles(current); !reflect.DeepEqual(stamps, e.ignoreStamps) {
+		if err = e.deriveIgnore(stamps); err != nil {
+			return err
+		}
+		if current, err = e.scan(); err != nil {
+			return err
+		}
+	}
+
+	changed := make([]string, 0)
+	for p, state := range current {
+		if e.remote[p] != state.Hash {
+			changed = append(changed, p)
+		}
+	}
+	// only files removed locally are removed, files generated in dev container are kept
+	deleted := make([]string, 0)
+	for p := range e.local {
+		if _, ok := current[p]; ok || e.ignored(p) {
+			continue
+		}
+		if _, ok := e.remote[p]; ok {
+			deleted = append(deleted, p)
+		}
+	}
+	if len(changed) == 0 && len(deleted) == 0 {
+		e.updateIndex(current)
+		e.writeStatus(ExecIdle, "Files are in sync", 0)
+		return nil
+	}
+	sort.Strings(changed)
+	sort.Strings(deleted)
+
+	e.writeStatus(ExecSyncing, "Syncing files", len(changed)+len(deleted))
+	if err = e.push(changed, current); err != nil {
+		return err
+	}
+	if err = e.delete(deleted); err != nil {
+		return err
+	}
+	log.Infof("%d files pushed, %d files deleted", len(changed), len(deleted))
+
+	e.updateIndex(current)
+	e.writeStatus(ExecIdle, "Files are in sync", 0)
+	return nil
+}
+
+// scan hashes local files, hash is recalculated only if size or modification time changed
+func (e *ExecEngine) scan() (map[string]fileState, error) {
+	current := map[string]fileState{}
+	err := filepath.Walk(
+		e.LocalDir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			rel, err := filepath.Rel(e.LocalDir, p)
+			if err != nil || rel == "." {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if e.ignored(rel) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			state := fileState{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+			if cached, ok := e.local[rel]; ok && cached.ModTime == state.ModTime && cached.Size == state.Size {
+				state.Hash = cached.Hash
+			} else if state.Hash, err = HashFile(p); err != nil {
+				if os.IsNotExist(errors.Cause(err)) {
+					return nil
+				}
+				return err
+			}
+			current[rel] = state
+			return nil
+		},
+	)
+	return current, errors.Wrap(err, "")
+}
+
+// push streams files to dev container by tar, one exec for each batch
+func (e *ExecEngine) push(files []string, current map[string]fileState) error {
+	for len(files) > 0 {
+		pushed, consumed, err := e.pushBatch(files)
+		if err != nil {
+			return err
+		}
+		for _, p := range pushed {
+			e.remote[p] = current[p].Hash
+		}
+		files = files[consumed:]
+	}
+	return nil
+}
+
+// pushBatch streams files by one exec until BatchSize bytes are written, files are read while
+// streaming, so a batch is never held in memory. Files pushed and the number of files consumed are returned
+func (e *ExecEngine) pushBatch(files []string) ([]string, int, error) {
+	pr, pw := io.Pipe()
+	pushed := make([]string, 0)
+	consumed := 0
+	writeErr := make(chan error, 1)
+	go func() {
+		tw := tar.NewWriter(pw)
+		var size int64
+		var err error
+		for _, p := range files {
+			if size >= int64(e.BatchSize) {
+				break
+			}
+			var added bool
+			var n int64
+			if added, n, err = addToTar(tw, filepath.Join(e.LocalDir, filepath.FromSlash(p)), p); err != nil {
+				break
+			}
+			consumed++
+			if added {
+				pushed = append(pushed, p)
+				size += n
+			}
+		}
+		if err == nil {
+			err = errors.Wrap(tw.Close(), "")
+		}
+		_ = pw.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	err := e.Executor([]string{"tar", "xf", "-", "-C", e.RemoteDir}, pr, nil)
+	// unblocks the writer if the executor exits without reading all
+	_ = pr.Close()
+	if wErr := <-writeErr; err == nil && wErr != nil {
+		err = errors.Wrap(wErr, "Failed to stream files")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return pushed, consumed, nil
+}
+
+func (e *ExecEngine) delete(files []string) error {
+	for start := 0; start < len(files); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(files) {
+			end = len(files)
+		}
+		quoted := make([]string, 0, end-start)
+		for _, p := range files[start:end] {
+			quoted = append(quoted, shellQuote(p))
+		}
+		script := fmt.Sprintf("cd %s && rm -f -- %s", shellQuote(e.RemoteDir), strings.Join(quoted, " "))
+		if err := e.Executor([]string{"sh", "-c", script}, nil, nil); err != nil {
+			return err
+		}
+		for _, p := range files[start:end] {
+			delete(e.remote, p)
+		}
+	}
+	return nil
+}
+
+// deriveIgnore patterns from IgnoreFrom, stamps are the ones of ignore files derived from,
+// nil means stamping them now
+func (e *ExecEngine) deriveIgnore(stamps map[string]fileState) error {
+	if len(e.IgnoreFrom) == 0 {
+		return nil
+	}
+	derived, err := ignore.Derive(e.LocalDir, e.IgnoreFrom)
+	if err != nil {
+		return err
+	}
+	e.derived = derived
+	if stamps == nil {
+		current, err := e.scan()
+		if err != nil {
+			return err
+		}
+		stamps = e.stampIgnoreFiles(current)
+	}
+	e.ignoreStamps = stamps
+	return nil
+}
+
+// stampIgnoreFiles size and modification time of ignore files, both scanned ones
+// and the ones of LocalDir which may be ignored by themselves
+func (e *ExecEngine) stampIgnoreFiles(current map[string]fileState) map[string]fileState {
+	if len(e.IgnoreFrom) == 0 {
+		return nil
+	}
+	stamps := map[string]fileState{}
+	for p, state := range current {
+		for _, f := range e.IgnoreFrom {
+			if path.Base(p) == f {
+				stamps[p] = fileState{ModTime: state.ModTime, Size: state.Size}
+			}
+		}
+	}
+	for _, f := range e.IgnoreFrom {
+		if info, err := os.Stat(filepath.Join(e.LocalDir, f)); err == nil {
+			stamps[f] = fileState{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+func (e *ExecEngine) ignored(rel string) bool {
+	if ignore.Match(e.derived, rel) {
+		return true
+	}
+	for _, pattern := range e.IgnoredPattern {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadIndex files synced by the engine last time, empty if the engine has never run
+func (e *ExecEngine) loadIndex() map[string]fileState {
+	index := map[string]fileState{}
+	bys, err := ioutil.ReadFile(filepath.Join(e.Home, ExecSyncIndexFile))
+	if err != nil {
+		return index
+	}
+	if err = json.Unmarshal(bys, &index); err != nil {
+		log.WarnE(errors.Wrap(err, ""), "Invalid index of exec sync engine")
+		return map[string]fileState{}
+	}
+	return index
+}
+
+// updateIndex the index file is only rewritten if files synced have changed
+func (e *ExecEngine) updateIndex(current map[string]fileState) {
+	same := len(current) == len(e.local)
+	for p, state := range current {
+		if !same {
+			break
+		}
+		same = e.local[p] == state
+	}
+	e.local = current
+	if same {
+		return
+	}
+	bys, _ := json.Marshal(current)
+	if err := ioutil.WriteFile(filepath.Join(e.Home, ExecSyncIndexFile), bys, 0600); err != nil {
+		log.LogE(errors.Wrap(err, ""))
+	}
+}
+
+func (e *ExecEngine) writeStatus(status, msg string, pending int) {
+	bys, _ := json.Marshal(&Status{Status: status, Msg: msg, Pending: pending, Updated: time.Now()})
+	if err := ioutil.WriteFile(filepath.Join(e.Home, ExecSyncStatusFile), bys, 0600); err != nil {
+		log.LogE(errors.Wrap(err, ""))
+	}
+}
+
+// ReadStatus status of exec engine whose home is home
+func ReadStatus(home string) (*Status, error) {
+	bys, err := ioutil.ReadFile(filepath.Join(home, ExecSyncStatusFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	status := &Status{}
+	return status, errors.Wrap(json.Unmarshal(bys, status), "")
+}
+
+// FindProcess pid of exec engine whose home is home, 0 if not running
+func FindProcess(home string) int {
+	bys, err := ioutil.ReadFile(filepath.Join(home, ExecSyncPidFile))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(string(bys))
+	if err != nil {
+		return 0
+	}
+	process, err := ps.FindProcess(pid)
+	if err != nil || process == nil {
+		return 0
+	}
+	// pid may be reused by other process
+	if self, err := os.Executable(); err == nil && process.Executable() != filepath.Base(self) {
+		return 0
+	}
+	return pid
+}
+
+// Stop exec engine whose home is home
+func Stop(home string) error {
+	defer func() {
+		_ = os.Remove(filepath.Join(home, ExecSyncPidFile))
+		_ = os.Remove(filepath.Join(home, ExecSyncStatusFile))
+	}()
+	if pid := FindProcess(home); pid != 0 {
+		return errors.Wrap(terminate.Terminate(pid, true), "")
+	}
+	return nil
+}
+
+// addToTar returns false if the file has gone, and the size of the file written
+func addToTar(tw *tar.Writer, p, name string) (bool, int64, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, 0, nil
+		}
+		return false, 0, errors.Wrap(err, "")
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, 0, errors.Wrap(err, "")
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return false, 0, errors.Wrap(err, "")
+	}
+	header.Name = name
+	if err = tw.WriteHeader(header); err != nil {
+		return false, 0, errors.Wrap(err, "")
+	}
+	// file may grow after stat, only the stat size is written
+	if _, err = io.CopyN(tw, f, info.Size()); err != nil {
+		return false, 0, errors.Wrap(err, "")
+	}
+	return true, info.Size(), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,202 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package syncengine
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecEngineSyncOnce(t *testing.T) {
+	local := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(local, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0700)
+		if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.go", "package main")
+	write("pkg/a.go", "package pkg")
+	write("node_modules/x.js", "x")
+
+	pushed := make([]string, 0)
+	removed := ""
+	e := &ExecEngine{
+		LocalDir:       local,
+		RemoteDir:      "/home/nocalhost-dev",
+		IgnoredPattern: []string{"node_modules"},
+		BatchSize:      DefaultExecSyncBatchSize,
+		Home:           t.TempDir(),
+		Executor: func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+			if cmd[0] == "tar" {
+				tr := tar.NewReader(stdin)
+				for {
+					header, err := tr.Next()
+					if err == io.EOF {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					pushed = append(pushed, header.Name)
+				}
+			}
+			removed += cmd[len(cmd)-1]
+			return nil
+		},
+		local:  map[string]fileState{},
+		remote: map[string]string{},
+	}
+	// main.go is already in dev container
+	e.remote["main.go"], _ = HashFile(filepath.Join(local, "main.go"))
+
+	if err := e.syncOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed) != 1 || pushed[0] != "pkg/a.go" {
+		t.Fatalf("only pkg/a.go should be pushed, got %v", pushed)
+	}
+
+	pushed = pushed[:0]
+	_ = os.Remove(filepath.Join(local, "main.go"))
+	if err := e.syncOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed) != 0 || !strings.Contains(removed, "'main.go'") {
+		t.Fatalf("main.go should be removed only, pushed %v, removed %s", pushed, removed)
+	}
+}
+
+func TestExecEngineDeletedWhileStopped(t *testing.T) {
+	local, home := t.TempDir(), t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		if err := ioutil.WriteFile(filepath.Join(local, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remote := map[string]string{}
+	removed := ""
+	batches := 0
+	newEngine := func() *ExecEngine {
+		return &ExecEngine{
+			LocalDir:  local,
+			RemoteDir: "/home/nocalhost-dev",
+			BatchSize: 1, // one file per exec
+			Home:      home,
+			Executor: func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+				if cmd[0] == "tar" {
+					batches++
+					tr := tar.NewReader(stdin)
+					for {
+						header, err := tr.Next()
+						if err == io.EOF {
+							return nil
+						}
+						if err != nil {
+							return err
+						}
+						remote[header.Name] = ""
+					}
+				}
+				removed += cmd[len(cmd)-1]
+				return nil
+			},
+			local:  map[string]fileState{},
+			remote: map[string]string{},
+		}
+	}
+
+	e := newEngine()
+	if err := e.syncOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if len(remote) != 3 || batches != 3 {
+		t.Fatalf("3 files should be pushed by 3 batches, got %v by %d batches", remote, batches)
+	}
+
+	// b.go is deleted while the engine is stopped
+	_ = os.Remove(filepath.Join(local, "b.go"))
+	e = newEngine()
+	e.local = e.loadIndex()
+	for p := range remote {
+		e.remote[p], _ = HashFile(filepath.Join(local, p))
+	}
+	e.remote["b.go"] = "hash"
+	e.remote["generated.log"] = "hash"
+	if err := e.syncOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(removed, "'b.go'") || strings.Contains(removed, "generated.log") {
+		t.Fatalf("only b.go should be removed, got %s", removed)
+	}
+}
+
+func TestExecEngineReloadIgnore(t *testing.T) {
+	local := t.TempDir()
+	for name, content := range map[string]string{".gitignore": "*.log\n", "a.log": "a", "b.txt": "b"} {
+		if err := ioutil.WriteFile(filepath.Join(local, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pushed := make([]string, 0)
+	e := &ExecEngine{
+		LocalDir:   local,
+		RemoteDir:  "/home/nocalhost-dev",
+		IgnoreFrom: []string{".gitignore"},
+		BatchSize:  DefaultExecSyncBatchSize,
+		Home:       t.TempDir(),
+		Executor: func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+			if cmd[0] != "tar" {
+				return nil
+			}
+			tr := tar.NewReader(stdin)
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				pushed = append(pushed, header.Name)
+			}
+		},
+		local:  map[string]fileState{},
+		remote: map[string]string{},
+	}
+	if err := e.deriveIgnore(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.syncOnce(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pushed {
+		if p == "a.log" {
+			t.Fatalf("a.log should be ignored, pushed %v", pushed)
+		}
+	}
+
+	// b.txt becomes ignored and a.log is synced without restarting the engine
+	pushed = pushed[:0]
+	if err := ioutil.WriteFile(filepath.Join(local, ".gitignore"), []byte("*.txt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.syncOnce(); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(pushed, ",")
+	if !strings.Contains(got, "a.log") || strings.Contains(got, "b.txt") {
+		t.Fatalf("a.log should be pushed after .gitignore changed, pushed %v", pushed)
+	}
+}
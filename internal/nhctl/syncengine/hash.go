@@ -0,0 +1,57 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package syncengine
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// HashRemoteDir sha256 of files in dir of dev container, keyed by slash path relative to dir,
+// dir is created if it does not exist
+func HashRemoteDir(executor Executor, dir string) (map[string]string, error) {
+	script := fmt.Sprintf(
+		"mkdir -p %s && cd %s && (find . -type f -exec sha256sum {} + 2>/dev/null || true)",
+		shellQuote(dir), shellQuote(dir),
+	)
+	out := &bytes.Buffer{}
+	if err := executor([]string{"sh", "-c", script}, nil, out); err != nil {
+		return nil, err
+	}
+
+	remote := map[string]string{}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		// <hash>  ./<path>
+		fields := strings.SplitN(scanner.Text(), "  ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		remote[strings.TrimPrefix(fields[1], "./")] = fields[0]
+	}
+	return remote, errors.Wrap(scanner.Err(), "")
+}
+
+// HashFile sha256 of local file, in the same format as sha256sum
+func HashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
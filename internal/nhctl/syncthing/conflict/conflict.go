@@ -0,0 +1,33 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+// Package conflict inspecting and merging conflicted files of syncthing
+package conflict
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// syncthing names conflict copy as <name>.sync-conflict-<date>-<time>-<short id of modifier><ext>
+var conflictCopyRegex = regexp.MustCompile(`^(.*)\.sync-conflict-\d{8}-\d{6}-([A-Z0-9]{7})(.*)$`)
+
+// ParseConflictCopy returns the file conflict copy p belongs to and short id of the device modified the copy,
+// ok is false if p is not a conflict copy
+func ParseConflictCopy(p string) (original, modifiedBy string, ok bool) {
+	matches := conflictCopyRegex.FindStringSubmatch(filepath.Base(p))
+	if matches == nil {
+		return "", "", false
+	}
+	return filepath.Join(filepath.Dir(p), matches[1]+matches[3]), matches[2], true
+}
+
+// ShortDeviceID short id used by syncthing in names of conflict copies
+func ShortDeviceID(id string) string {
+	if len(id) < 7 {
+		return id
+	}
+	return id[:7]
+}
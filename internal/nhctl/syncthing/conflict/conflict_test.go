@@ -0,0 +1,41 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package conflict
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	from := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+	to := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n")
+
+	expected := "--- local\n+++ remote\n" +
+		"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+		"@@ -8,3 +8,4 @@\n h\n i\n j\n+k\n"
+	if diff := UnifiedDiff("local", "remote", from, to); diff != expected {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+	if diff := UnifiedDiff("local", "remote", from, from); diff != "" {
+		t.Fatalf("same content should have no diff, got:\n%s", diff)
+	}
+}
+
+func TestMergeWithMarkers(t *testing.T) {
+	merged := MergeWithMarkers("local", "remote", []byte("a\nb\nc\n"), []byte("a\nB\nc\n"))
+	expected := "a\n<<<<<<< local\nb\n=======\nB\n>>>>>>> remote\nc\n"
+	if string(merged) != expected {
+		t.Fatalf("unexpected merge:\n%s", merged)
+	}
+}
+
+func TestParseConflictCopy(t *testing.T) {
+	original, modifiedBy, ok := ParseConflictCopy("pkg/main.sync-conflict-20210913-101010-MDPJNTF.go")
+	if !ok || original != "pkg/main.go" || modifiedBy != "MDPJNTF" {
+		t.Fatalf("unexpected result: %s %s %v", original, modifiedBy, ok)
+	}
+	if _, _, ok = ParseConflictCopy("pkg/main.go"); ok {
+		t.Fatal("pkg/main.go is not a conflict copy")
+	}
+}
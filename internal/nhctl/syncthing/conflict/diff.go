@@ -0,0 +1,197 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package conflict
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3
+	// texts too large are treated as totally replaced, instead of computing lcs
+	maxDiffCells = 16 << 20
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// IsBinary content containing NUL is regarded as binary, which can not be diffed by lines
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// UnifiedDiff diff of from and to in unified format, empty if they are the same
+func UnifiedDiff(fromName, toName string, from, to []byte) string {
+	if IsBinary(from) || IsBinary(to) {
+		if bytes.Equal(from, to) {
+			return ""
+		}
+		return fmt.Sprintf("Binary files %s and %s differ\n", fromName, toName)
+	}
+
+	ops := diffLines(splitLines(from), splitLines(to))
+	// line numbers of from and to before each op
+	fromPos, toPos := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if op.kind != '+' {
+			fromPos[i+1]++
+		}
+		if op.kind != '-' {
+			toPos[i+1]++
+		}
+	}
+
+	buf := &strings.Builder{}
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// changes closer than 2*diffContext lines are in the same hunk
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+
+		hunkStart, hunkEnd := start-diffContext, end+diffContext
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+		if buf.Len() == 0 {
+			buf.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+		}
+		buf.WriteString(
+			fmt.Sprintf(
+				"@@ -%d,%d +%d,%d @@\n",
+				fromPos[hunkStart]+1, fromPos[hunkEnd]-fromPos[hunkStart],
+				toPos[hunkStart]+1, toPos[hunkEnd]-toPos[hunkStart],
+			),
+		)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			buf.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return buf.String()
+}
+
+// MergeWithMarkers lines both have are kept, lines differ are wrapped by conflict markers like git does
+func MergeWithMarkers(fromName, toName string, from, to []byte) []byte {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			buf.WriteString(ops[i].line + "\n")
+			i++
+			continue
+		}
+		fromLines, toLines := make([]string, 0), make([]string, 0)
+		for ; i < len(ops) && ops[i].kind != ' '; i++ {
+			if ops[i].kind == '-' {
+				fromLines = append(fromLines, ops[i].line)
+			} else {
+				toLines = append(toLines, ops[i].line)
+			}
+		}
+		buf.WriteString("<<<<<<< " + fromName + "\n")
+		for _, l := range fromLines {
+			buf.WriteString(l + "\n")
+		}
+		buf.WriteString("=======\n")
+		for _, l := range toLines {
+			buf.WriteString(l + "\n")
+		}
+		buf.WriteString(">>>>>>> " + toName + "\n")
+	}
+	return buf.Bytes()
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// diffLines shortest edit script from a to b, by longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	ops := make([]diffOp, 0, n+m)
+	if n*m > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, diffOp{kind: '-', line: l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{kind: '+', line: l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] length of lcs of a[i:] and b[j:]
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{kind: '-', line: a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{kind: '+', line: b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,336 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+// Package ignore derives syncthing ignore patterns from .gitignore and .dockerignore
+package ignore
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	GitIgnoreFile    = ".gitignore"
+	DockerIgnoreFile = ".dockerignore"
+)
+
+// rules of one ignore file, already in syncthing's order: the first matched pattern decides
+type rules struct {
+	depth    int
+	patterns []string
+}
+
+// Derive syncthing patterns of ignore files in dir, the first matched pattern decides as syncthing requires.
+// Nested .gitignore files are found recursively, and take precedence over the ones of their parent dirs,
+// only .dockerignore in dir is used
+func Derive(dir string, from []string) ([]string, error) {
+	var useGit, useDocker bool
+	for _, f := range from {
+		switch f {
+		case GitIgnoreFile:
+			useGit = true
+		case DockerIgnoreFile:
+			useDocker = true
+		}
+	}
+
+	var docker []string
+	if useDocker {
+		lines, err := readLines(filepath.Join(dir, DockerIgnoreFile))
+		if err != nil {
+			return nil, err
+		}
+		docker = FromDockerIgnore(lines)
+	}
+
+	all := make([]rules, 0)
+	if useGit {
+		if err := walkGitIgnore(dir, "", docker, &all); err != nil {
+			return nil, err
+		}
+	}
+	// deeper .gitignore takes precedence
+	sort.SliceStable(all, func(i, j int) bool { return all[i].depth > all[j].depth })
+
+	result := make([]string, 0)
+	for _, r := range all {
+		result = append(result, r.patterns...)
+	}
+	return append(result, docker...), nil
+}
+
+// walkGitIgnore collects .gitignore of dir and its sub dirs, dirs ignored by inherited are skipped
+func walkGitIgnore(root, rel string, inherited []string, all *[]rules) error {
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+	lines, err := readLines(filepath.Join(dir, GitIgnoreFile))
+	if err != nil {
+		return err
+	}
+	patterns := inherited
+	if len(lines) > 0 {
+		own := FromGitIgnore(rel, lines)
+		*all = append(*all, rules{depth: depthOf(rel), patterns: own})
+		patterns = append(append([]string{}, own...), inherited...)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == ".git" {
+			continue
+		}
+		child := path.Join(rel, entry.Name())
+		if Match(patterns, child) {
+			continue
+		}
+		if err = walkGitIgnore(root, child, patterns, all); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromGitIgnore converts lines of the .gitignore in dir base (relative to sync root) to syncthing patterns
+func FromGitIgnore(base string, lines []string) []string {
+	result := make([]string, 0)
+	// the last matched pattern decides in gitignore, so lines are reversed
+	for i := len(lines) - 1; i >= 0; i-- {
+		p, negate, ok := parseLine(lines[i])
+		if !ok {
+			continue
+		}
+		p = strings.TrimSuffix(p, "/")
+
+		var converted []string
+		switch {
+		case strings.HasPrefix(p, "**/"):
+			p = strings.TrimPrefix(p, "**/")
+			converted = unanchored(base, p)
+		case strings.Contains(p, "/"):
+			// a slash at the beginning or middle anchors the pattern to dir of .gitignore
+			converted = []string{"/" + path.Join(base, strings.TrimPrefix(p, "/"))}
+		default:
+			converted = unanchored(base, p)
+		}
+		for _, c := range converted {
+			if negate {
+				c = "!" + c
+			}
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// FromDockerIgnore converts lines of .dockerignore to syncthing patterns, they're always relative to the root
+func FromDockerIgnore(lines []string) []string {
+	result := make([]string, 0)
+	for i := len(lines) - 1; i >= 0; i-- {
+		p, negate, ok := parseLine(lines[i])
+		if !ok {
+			continue
+		}
+		p = path.Clean(strings.TrimPrefix(p, "/"))
+		if p == "." {
+			continue
+		}
+		p = "/" + p
+		if negate {
+			p = "!" + p
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// ParseFile patterns of syncthing ignore file, comments and blank lines are skipped
+func ParseFile(p string) ([]string, error) {
+	lines, err := readLines(p)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(lines), nil
+}
+
+// Parse patterns of syncthing ignore file lines, prefixes (?d) and (?i) are dropped
+func Parse(lines []string) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for strings.HasPrefix(line, "(?d)") || strings.HasPrefix(line, "(?i)") {
+			line = line[4:]
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// Match returns true if rel is ignored by syncthing patterns, a dir ignored also ignores its contents
+func Match(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		negate := strings.HasPrefix(p, "!")
+		if toRegexp(strings.TrimPrefix(p, "!")).MatchString(rel) {
+			return !negate
+		}
+	}
+	return false
+}
+
+// LargeFiles anchored patterns of files in dir larger than max bytes, files ignored by skipped are not walked
+func LargeFiles(dir string, max int64, skipped []string) ([]string, error) {
+	result := make([]string, 0)
+	err := filepath.Walk(
+		dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil || rel == "." {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if Match(skipped, rel) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Mode().IsRegular() && info.Size() > max {
+				result = append(result, "/"+escape(rel))
+			}
+			return nil
+		},
+	)
+	return result, err
+}
+
+// escape special chars of syncthing pattern
+func escape(p string) string {
+	buf := &strings.Builder{}
+	for _, c := range p {
+		if strings.ContainsRune(`\*?[]{}!`, c) {
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
+
+func unanchored(base, p string) []string {
+	if base == "" {
+		return []string{p}
+	}
+	return []string{"/" + base + "/" + p, "/" + base + "/**/" + p}
+}
+
+// parseLine returns the pattern of the line, ok is false for blank line and comment
+func parseLine(line string) (p string, negate, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	if !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false, false
+	}
+	if strings.HasPrefix(line, "!") {
+		negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if line == "" || line == "/" {
+		return "", false, false
+	}
+	return line, negate, true
+}
+
+var regexpCache sync.Map
+
+// toRegexp pattern with leading slash is anchored to the root, otherwise matches at any depth,
+// and contents of the matched dir are matched too
+func toRegexp(p string) *regexp.Regexp {
+	if r, ok := regexpCache.Load(p); ok {
+		return r.(*regexp.Regexp)
+	}
+	pattern := p
+	buf := &strings.Builder{}
+	if strings.HasPrefix(p, "/") {
+		buf.WriteString("^")
+		p = p[1:]
+	} else {
+		buf.WriteString("^(.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				buf.WriteString(".*")
+				i++
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			if end := strings.IndexByte(p[i:], ']'); end > 0 {
+				buf.WriteString(p[i : i+end+1])
+				i += end
+			} else {
+				buf.WriteString(`\[`)
+			}
+		case '\\':
+			if i+1 < len(p) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(string(p[i])))
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString("(/.*)?$")
+	r, err := regexp.Compile(buf.String())
+	if err != nil {
+		r = regexp.MustCompile("^" + regexp.QuoteMeta(p) + "$")
+	}
+	regexpCache.Store(pattern, r)
+	return r
+}
+
+func depthOf(rel string) int {
+	if rel == "" {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+func readLines(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,72 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package ignore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(p), 0700)
+		if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(GitIgnoreFile, "# deps\nnode_modules/\n*.log\n!keep.log\n/build\n")
+	write("web/"+GitIgnoreFile, "dist\n!debug.log\n")
+	write(DockerIgnoreFile, "target\n")
+	write("node_modules/a/"+GitIgnoreFile, "never-read\n")
+
+	patterns, err := Derive(dir, []string{GitIgnoreFile, DockerIgnoreFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rel, ignored := range map[string]bool{
+		"node_modules":       true,
+		"web/node_modules/x": true,
+		"app.log":            true,
+		"keep.log":           false,
+		"build/out":          true,
+		"web/build":          false,
+		"web/dist/app.js":    true,
+		"dist":               false,
+		"web/debug.log":      false,
+		"web/src/debug.log":  false,
+		"target/app.jar":     true,
+		"main.go":            false,
+		"never-read":         false,
+	} {
+		if Match(patterns, rel) != ignored {
+			t.Errorf("%s should be ignored: %v, patterns: %v", rel, ignored, patterns)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	patterns := Parse(
+		[]string{
+			"// Ignored pattern block", "#disableParseFromGitIgnore", "", "(?d)node_modules", "/build",
+			"!**", "**",
+		},
+	)
+	for rel, ignored := range map[string]bool{
+		"node_modules":     true,
+		"web/node_modules": true,
+		"build/out":        true,
+		"main.go":          false,
+	} {
+		if Match(patterns, rel) != ignored {
+			t.Errorf("%s should be ignored: %v, patterns: %v", rel, ignored, patterns)
+		}
+	}
+}
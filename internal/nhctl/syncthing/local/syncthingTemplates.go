@@ -8,9 +8,9 @@ package local
 // follow text is the default configuration template for syncthing local
 const LocalSyncConfigXML = `<configuration version="32">
 {{ range .Folders }}
-<folder id="nh-{{ .Name }}" label="{{ .Name }}" path="{{ .LocalPath }}" type="{{ $.Type }}" 
+<folder id="nh-{{ .Name }}" label="{{ .Name }}" path="{{ .LocalPath }}" type="{{ if .Type }}{{ .Type }}{{ else }}{{ $.Type }}{{ end }}" 
 rescanIntervalS="{{ $.RescanInterval }}" fsWatcherEnabled="true" 
-fsWatcherDelayS="1" ignorePerms="false" autoNormalize="true">
+fsWatcherDelayS="{{ $.FileWatcherDelay }}" ignorePerms="false" autoNormalize="true">
 	<filesystemType>basic</filesystemType>
 	<device id="SJTYMUE-DI3REKX-JCLCRXU-F6UJHCG-XQGHAZJ-5O5D3JR-LALGSBC-TJ4I4QO" introducedBy=""></device>
 	<device id="{{$.RemoteDeviceID}}" introducedBy=""></device>
@@ -42,13 +42,13 @@ skipIntroductionRemovals="false" introducedBy="">
 	<maxRecvKbps>0</maxRecvKbps>
 	<maxRequestKiB>0</maxRequestKiB>
 </device>
-<device id="{{.RemoteDeviceID}}" name="remote" compression="metadata" 
+<device id="{{.RemoteDeviceID}}" name="remote" compression="{{.Compression}}" 
 introducer="false" skipIntroductionRemovals="false" introducedBy="">
 	<address>tcp://{{.RemoteAddress}}</address>
 	<paused>false</paused>
 	<autoAcceptFolders>false</autoAcceptFolders>
-	<maxSendKbps>0</maxSendKbps>
-	<maxRecvKbps>0</maxRecvKbps>
+	<maxSendKbps>{{.MaxSendKbps}}</maxSendKbps>
+	<maxRecvKbps>{{.MaxRecvKbps}}</maxRecvKbps>
 	<maxRequestKiB>0</maxRequestKiB>
 </device>
 <gui enabled="true" tls="false" debugging="false">
@@ -85,7 +85,7 @@ introducer="false" skipIntroductionRemovals="false" introducedBy="">
 	<keepTemporariesH>24</keepTemporariesH>
 	<cacheIgnoredFiles>false</cacheIgnoredFiles>
 	<progressUpdateIntervalS>2</progressUpdateIntervalS>
-	<limitBandwidthInLan>false</limitBandwidthInLan>
+	<limitBandwidthInLan>{{ if or .MaxSendKbps .MaxRecvKbps }}true{{ else }}false{{ end }}</limitBandwidthInLan>
 	<minHomeDiskFree unit="%">1</minHomeDiskFree>
 	<releasesURL></releasesURL>
 	<overwriteRemoteDeviceNamesOnConnect>false</overwriteRemoteDeviceNamesOnConnect>
@@ -102,8 +102,8 @@ introducer="false" skipIntroductionRemovals="false" introducedBy="">
 const RemoteSyncConfigXML = `<configuration version="32">
 {{ range .Folders }}
 <folder id="nh-{{ .Name }}" label="{{ .Name }}" path="{{ .RemotePath }}" 
-type="sendreceive" rescanIntervalS="{{ $.RescanInterval }}" fsWatcherEnabled="true" 
-fsWatcherDelayS="1" ignorePerms="false" autoNormalize="true">
+type="{{ if .RemoteType }}{{ .RemoteType }}{{ else }}sendreceive{{ end }}" rescanIntervalS="{{ $.RescanInterval }}" fsWatcherEnabled="true" 
+fsWatcherDelayS="{{ $.FileWatcherDelay }}" ignorePerms="false" autoNormalize="true">
 	<filesystemType>basic</filesystemType>
 	<device id="SJTYMUE-DI3REKX-JCLCRXU-F6UJHCG-XQGHAZJ-5O5D3JR-LALGSBC-TJ4I4QO" introducedBy=""></device>
 	<device id="MDPJNTF-OSPJC65-LZNCQGD-3AWRUW6-BYJULSS-GOCA2TU-5DWWBNC-TKM4VQ5" introducedBy=""></device>
@@ -126,12 +126,12 @@ fsWatcherDelayS="1" ignorePerms="false" autoNormalize="true">
 </folder>
 {{ end }}
 <device id="SJTYMUE-DI3REKX-JCLCRXU-F6UJHCG-XQGHAZJ-5O5D3JR-LALGSBC-TJ4I4QO" name="local" 
-compression="metadata" introducer="false" skipIntroductionRemovals="false" introducedBy="">
+compression="{{.Compression}}" introducer="false" skipIntroductionRemovals="false" introducedBy="">
 	<address>dynamic</address>
 	<paused>false</paused>
 	<autoAcceptFolders>false</autoAcceptFolders>
-	<maxSendKbps>0</maxSendKbps>
-	<maxRecvKbps>0</maxRecvKbps>
+	<maxSendKbps>{{.MaxRecvKbps}}</maxSendKbps>
+	<maxRecvKbps>{{.MaxSendKbps}}</maxRecvKbps>
 	<maxRequestKiB>0</maxRequestKiB>
 </device>
 <device id="MDPJNTF-OSPJC65-LZNCQGD-3AWRUW6-BYJULSS-GOCA2TU-5DWWBNC-TKM4VQ5" name="remote" 
@@ -180,7 +180,7 @@ compression="metadata" introducer="false" skipIntroductionRemovals="false" intro
 	<keepTemporariesH>24</keepTemporariesH>
 	<cacheIgnoredFiles>false</cacheIgnoredFiles>
 	<progressUpdateIntervalS>2</progressUpdateIntervalS>
-	<limitBandwidthInLan>false</limitBandwidthInLan>
+	<limitBandwidthInLan>{{ if or .MaxSendKbps .MaxRecvKbps }}true{{ else }}false{{ end }}</limitBandwidthInLan>
 	<minHomeDiskFree unit="%">1</minHomeDiskFree>
 	<releasesURL></releasesURL>
 	<overwriteRemoteDeviceNamesOnConnect>false</overwriteRemoteDeviceNamesOnConnect>
@@ -210,9 +210,15 @@ const IgnoredFileTemplate = `
 // While enabled, the configuration of "ignoredPattern" or "syncedPattern" will not take effect.
 {{.enableParseFromGitIgnore}}
 
+// Paths synced by other folders, they're ignored whether parsing the gitignore or not, default is ""
+{{.excludedPattern}}
+
 // Ignored pattern block, the priority of ignored pattern is highest, default is ""
 {{.ignoredPattern}}
 
+// Pattern block derived from .gitignore or .dockerignore, default is ""
+{{.derivedPattern}}
+
 // Synced pattern block, default is "!**"
 {{.syncedPattern}}
 
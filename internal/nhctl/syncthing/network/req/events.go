@@ -20,7 +20,14 @@ func (p *SyncthingHttpClient) Events(since int64) ([]event, error) {
 type EventType string
 
 const (
-	EventFolderCompletion EventType = "FolderCompletion"
+	EventFolderCompletion    EventType = "FolderCompletion"
+	EventStateChanged        EventType = "StateChanged"
+	EventLocalChangeDetected EventType = "LocalChangeDetected"
+	EventItemFinished        EventType = "ItemFinished"
+	EventDeviceConnected     EventType = "DeviceConnected"
+	EventDeviceDisconnected  EventType = "DeviceDisconnected"
+	EventFolderErrors        EventType = "FolderErrors"
+	EventFolderSummary       EventType = "FolderSummary"
 )
 
 type event struct {
@@ -35,4 +42,26 @@ type data struct {
 	Completion float64 `json:"completion"`
 	Device     string  `json:"device"`
 	Folder     string  `json:"folder"`
+
+	// StateChanged
+	From string `json:"from"`
+	To   string `json:"to"`
+	// LocalChangeDetected
+	Path string `json:"path"`
+	// ItemFinished
+	Item   string `json:"item"`
+	Action string `json:"action"`
+	// ItemFinished and DeviceDisconnected
+	Error string `json:"error"`
+	// DeviceConnected and DeviceDisconnected
+	ID string `json:"id"`
+	// FolderErrors
+	Errors []fileError `json:"errors"`
+	// FolderSummary
+	Summary Model `json:"summary"`
+}
+
+type fileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
 }
@@ -0,0 +1,55 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package req
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// NeedFile file whose global version is newer than local one
+type NeedFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Deleted  bool      `json:"deleted"`
+}
+
+type needResponse struct {
+	Progress []NeedFile `json:"progress"`
+	Queued   []NeedFile `json:"queued"`
+	Rest     []NeedFile `json:"rest"`
+}
+
+// FolderNeed files local device needs, for send only folder, they are the files changed remotely
+func (p *SyncthingHttpClient) FolderNeed() ([]NeedFile, error) {
+	resp, err := p.get("rest/db/need?perpage=10000&folder=" + p.folderName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res needResponse
+	if err := json.Unmarshal(resp, &res); err != nil {
+		return nil, err
+	}
+
+	files := make([]NeedFile, 0, len(res.Progress)+len(res.Queued)+len(res.Rest))
+	files = append(files, res.Progress...)
+	files = append(files, res.Queued...)
+	return append(files, res.Rest...), nil
+}
+
+// ScanSub rescans sub paths of folder, so that changes of them are synced immediately
+func (p *SyncthingHttpClient) ScanSub(subs ...string) error {
+	query := url.Values{}
+	query.Set("folder", p.folderName)
+	for _, sub := range subs {
+		query.Add("sub", sub)
+	}
+	_, err := p.Post("rest/db/scan?"+query.Encode(), "")
+	return err
+}
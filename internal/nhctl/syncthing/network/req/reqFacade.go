@@ -114,6 +114,16 @@ type SyncthingStatus struct {
 	Tips      string     `json:"tips,omitempty"`
 	OutOfSync string     `json:"outOfSync,omitempty"`
 	Gui       string     `json:"gui,omitempty"`
+	// Folders status of sync mapping folders
+	Folders []*FolderSyncStatus `json:"folders,omitempty"`
+}
+
+// FolderSyncStatus status of a sync mapping folder
+type FolderSyncStatus struct {
+	Folder string `json:"folder"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+	*SyncthingStatus
 }
 
 type StatusEnum string
@@ -0,0 +1,209 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package req
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"nocalhost/internal/nhctl/syncthing/conflict"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SyncEventType string
+
+const (
+	SyncEventScanStarted  SyncEventType = "scanStarted"
+	SyncEventFilePushed   SyncEventType = "filePushed"
+	SyncEventFilePulled   SyncEventType = "filePulled"
+	SyncEventConflict     SyncEventType = "conflict"
+	SyncEventConnected    SyncEventType = "connected"
+	SyncEventDisconnected SyncEventType = "disconnected"
+	SyncEventIdle         SyncEventType = "idle"
+	SyncEventSynced       SyncEventType = "synced"
+	SyncEventError        SyncEventType = "error"
+
+	// long poll timeout of syncthing events api
+	eventsPollTimeoutSecond = 60
+)
+
+// watchedEvents syncthing events translated to SyncEvent, LocalChangeDetected
+// is not returned by events api unless it's specified
+var watchedEvents = []EventType{
+	EventStateChanged, EventLocalChangeDetected, EventItemFinished,
+	EventDeviceConnected, EventDeviceDisconnected, EventFolderErrors, EventFolderSummary,
+}
+
+// SyncEvent file sync event for IDEs and scripts, Path is relative to the folder
+type SyncEvent struct {
+	Id     int64         `json:"id"`
+	Time   string        `json:"time"`
+	Type   SyncEventType `json:"type"`
+	Folder string        `json:"folder,omitempty"`
+	Path   string        `json:"path,omitempty"`
+	Action string        `json:"action,omitempty"`
+	Msg    string        `json:"msg,omitempty"`
+}
+
+// EventsWait long polls events after since, returns when any event happens or timeout
+func (p *SyncthingHttpClient) EventsWait(since int64, timeoutSecond int, types ...EventType) ([]event, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since, 10))
+	query.Set("timeout", strconv.Itoa(timeoutSecond))
+	if len(types) > 0 {
+		names := make([]string, 0, len(types))
+		for _, t := range types {
+			names = append(names, string(t))
+		}
+		query.Set("events", strings.Join(names, ","))
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("http://%s/rest/events?%s", p.guiHost, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(r, timeoutSecond+p.reqTimeoutSecond)
+	if err != nil {
+		return nil, err
+	}
+	var eventList []event
+	if err = json.Unmarshal(resp, &eventList); err != nil {
+		return nil, err
+	}
+	return eventList, nil
+}
+
+// WatchSyncEvents streams sync events to fn until ctx is done, events happened before watching are skipped.
+// Errors of syncthing api are retried, and reported to fn as disconnected once
+func (p *SyncthingHttpClient) WatchSyncEvents(ctx context.Context, fn func(SyncEvent)) {
+	since := int64(-1)
+	reachable := true
+	idle := folderIdle{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		timeout := eventsPollTimeoutSecond
+		if since < 0 {
+			// only fetch the id of latest event
+			timeout = 0
+		}
+		events, err := p.EventsWait(since, timeout, watchedEvents...)
+		if err != nil {
+			if reachable {
+				reachable = false
+				fn(SyncEvent{Time: time.Now().Format(time.RFC3339Nano), Type: SyncEventDisconnected, Msg: err.Error()})
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second * 2):
+			}
+			continue
+		}
+		reachable = true
+
+		for _, e := range events {
+			// summary is sent after each scan or pull, only the one turning folder idle means synced
+			if !idle.observe(e) {
+				continue
+			}
+			if since >= 0 {
+				for _, se := range ToSyncEvents(e) {
+					fn(se)
+				}
+			}
+		}
+		if len(events) > 0 {
+			since = events[len(events)-1].Id
+		} else if since < 0 {
+			since = 0
+		}
+	}
+}
+
+// ToSyncEvents translates syncthing event to sync events, one event of FolderErrors
+// may contain errors of several files
+func ToSyncEvents(e event) []SyncEvent {
+	se := SyncEvent{Id: e.Id, Time: e.Time, Folder: e.Data.Folder}
+	switch e.EventType {
+	case EventStateChanged:
+		switch e.Data.To {
+		case "scanning":
+			se.Type = SyncEventScanStarted
+		case "idle":
+			se.Type = SyncEventIdle
+		case "error":
+			se.Type = SyncEventError
+			se.Msg = e.Data.Error
+		default:
+			return nil
+		}
+	case EventLocalChangeDetected:
+		se.Type, se.Path, se.Action = SyncEventFilePushed, e.Data.Path, e.Data.Action
+	case EventItemFinished:
+		se.Type, se.Path, se.Action = SyncEventFilePulled, e.Data.Item, e.Data.Action
+		if e.Data.Error != "" {
+			se.Type, se.Msg = SyncEventError, e.Data.Error
+		}
+	case EventFolderSummary:
+		// folder is idle and needs nothing, changes of the batch have been synced
+		if !e.Data.Summary.isIdle() || e.Data.Summary.NeedFiles > 0 {
+			return nil
+		}
+		se.Type = SyncEventSynced
+	case EventDeviceConnected:
+		se.Type, se.Msg = SyncEventConnected, e.Data.ID
+	case EventDeviceDisconnected:
+		se.Type, se.Msg = SyncEventDisconnected, e.Data.Error
+	case EventFolderErrors:
+		result := make([]SyncEvent, 0, len(e.Data.Errors))
+		for _, fe := range e.Data.Errors {
+			result = append(
+				result, SyncEvent{
+					Id: e.Id, Time: e.Time, Type: SyncEventError, Folder: e.Data.Folder, Path: fe.Path, Msg: fe.Error,
+				},
+			)
+		}
+		return result
+	default:
+		return nil
+	}
+
+	// conflict copy created by syncthing, whether it's created locally or pulled from remote
+	if se.Path != "" && se.Type != SyncEventError {
+		if original, _, ok := conflict.ParseConflictCopy(se.Path); ok {
+			se.Type, se.Msg = SyncEventConflict, original
+		}
+	}
+	return []SyncEvent{se}
+}
+
+// folderIdle whether folders were idle in their last FolderSummary or StateChanged
+type folderIdle map[string]bool
+
+// observe returns false for FolderSummary not turning the folder idle, repeated idle summaries are dropped
+func (f folderIdle) observe(e event) bool {
+	switch e.EventType {
+	case EventStateChanged:
+		if e.Data.To != "idle" {
+			f[e.Data.Folder] = false
+		}
+	case EventFolderSummary:
+		idle := e.Data.Summary.isIdle() && e.Data.Summary.NeedFiles == 0
+		was, ok := f[e.Data.Folder]
+		f[e.Data.Folder] = idle
+		return idle && (!ok || !was)
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package req
+
+import "testing"
+
+func TestToSyncEvents(t *testing.T) {
+	cases := []struct {
+		e        event
+		expected []SyncEventType
+	}{
+		{event{EventType: EventStateChanged, Data: data{From: "idle", To: "scanning"}}, []SyncEventType{SyncEventScanStarted}},
+		{event{EventType: EventStateChanged, Data: data{From: "scanning", To: "syncing"}}, nil},
+		{event{EventType: EventLocalChangeDetected, Data: data{Path: "src/app.py"}}, []SyncEventType{SyncEventFilePushed}},
+		{
+			event{EventType: EventItemFinished, Data: data{Item: "src/app.sync-conflict-20211010-101010-ABCDEFG.py"}},
+			[]SyncEventType{SyncEventConflict},
+		},
+		{event{EventType: EventItemFinished, Data: data{Item: "a", Error: "denied"}}, []SyncEventType{SyncEventError}},
+		{event{EventType: EventFolderSummary, Data: data{Summary: Model{State: "idle"}}}, []SyncEventType{SyncEventSynced}},
+		{event{EventType: EventFolderSummary, Data: data{Summary: Model{State: "syncing", NeedFiles: 2}}}, nil},
+		{
+			event{EventType: EventFolderErrors, Data: data{Errors: []fileError{{Path: "a"}, {Path: "b"}}}},
+			[]SyncEventType{SyncEventError, SyncEventError},
+		},
+	}
+	for _, c := range cases {
+		result := ToSyncEvents(c.e)
+		if len(result) != len(c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.e.EventType, c.expected, result)
+		}
+		for i := range result {
+			if result[i].Type != c.expected[i] {
+				t.Fatalf("%s: expected %v, got %v", c.e.EventType, c.expected, result)
+			}
+		}
+	}
+}
+
+func TestFolderIdleObserve(t *testing.T) {
+	summary := func(state string) event {
+		return event{EventType: EventFolderSummary, Data: data{Folder: "nh-1", Summary: Model{State: state}}}
+	}
+	idle := folderIdle{}
+	if !idle.observe(summary("idle")) {
+		t.Fatal("first idle summary should be kept")
+	}
+	if idle.observe(summary("idle")) {
+		t.Fatal("repeated idle summary should be dropped")
+	}
+	if !idle.observe(event{EventType: EventStateChanged, Data: data{Folder: "nh-1", From: "idle", To: "scanning"}}) {
+		t.Fatal("events other than summary should be kept")
+	}
+	if !idle.observe(summary("idle")) {
+		t.Fatal("summary turning folder idle after scanning should be kept")
+	}
+}
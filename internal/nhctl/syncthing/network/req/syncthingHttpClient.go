@@ -37,6 +37,13 @@ func NewSyncthingHttpClient(
 	}
 }
 
+// WithFolder client of another folder of the same syncthing
+func (s *SyncthingHttpClient) WithFolder(folderName string) *SyncthingHttpClient {
+	clone := *s
+	clone.folderName = folderName
+	return &clone
+}
+
 // Get performs an HTTP GET and returns the bytes and/or an error. Any non-200
 // return code is returned as an error.
 func (s *SyncthingHttpClient) get(path string) ([]byte, error) {
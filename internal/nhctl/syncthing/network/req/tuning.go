@@ -0,0 +1,76 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package req
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Tuning limits of file sync which take effect without restarting syncthing
+type Tuning struct {
+	MaxSendKbps     int
+	MaxRecvKbps     int
+	Compression     string
+	RescanIntervalS int
+	FsWatcherDelayS int
+}
+
+// ApplyTuning updates config of running syncthing, rate limits and compression of remote device,
+// and scan settings of all nocalhost folders
+func (p *SyncthingHttpClient) ApplyTuning(t Tuning) error {
+	resp, err := p.get("rest/system/config")
+	if err != nil {
+		return err
+	}
+	// unknown fields are kept as they are
+	config := map[string]interface{}{}
+	if err = json.Unmarshal(resp, &config); err != nil {
+		return err
+	}
+
+	devices, _ := config["devices"].([]interface{})
+	for _, d := range devices {
+		device, ok := d.(map[string]interface{})
+		if !ok || device["deviceID"] != p.remoteDevice {
+			continue
+		}
+		device["maxSendKbps"] = t.MaxSendKbps
+		device["maxRecvKbps"] = t.MaxRecvKbps
+		if t.Compression != "" {
+			device["compression"] = t.Compression
+		}
+	}
+
+	folders, _ := config["folders"].([]interface{})
+	for _, f := range folders {
+		folder, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, _ := folder["id"].(string); !strings.HasPrefix(id, "nh-") {
+			continue
+		}
+		if t.RescanIntervalS > 0 {
+			folder["rescanIntervalS"] = t.RescanIntervalS
+		}
+		if t.FsWatcherDelayS > 0 {
+			folder["fsWatcherDelayS"] = t.FsWatcherDelayS
+		}
+	}
+
+	// port-forward of dev container is a lan connection
+	if options, ok := config["options"].(map[string]interface{}); ok {
+		options["limitBandwidthInLan"] = t.MaxSendKbps > 0 || t.MaxRecvKbps > 0
+	}
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = p.Post("rest/system/config", string(body))
+	return err
+}
@@ -24,6 +24,7 @@ import (
 
 	ps "github.com/mitchellh/go-ps"
 
+	"nocalhost/internal/nhctl/syncthing/ignore"
 	"nocalhost/internal/nhctl/syncthing/local"
 	"nocalhost/internal/nhctl/syncthing/terminate"
 	"nocalhost/pkg/nhctl/log"
@@ -54,7 +55,7 @@ const (
 	localDeviceID         = "SJTYMUE-DI3REKX-JCLCRXU-F6UJHCG-XQGHAZJ-5O5D3JR-LALGSBC-TJ4I4QO"
 
 	// DefaultFileWatcherDelay how much to wait before starting a sync after a file change
-	DefaultFileWatcherDelay = 5
+	DefaultFileWatcherDelay = 1
 
 	// may result bug due to syncthing config changing
 	DefaultFolderName = "nh-1"
@@ -76,6 +77,12 @@ type Folder struct {
 	RemotePath   string `yaml:"remotePath"`
 	Retries      int    `yaml:"-"`
 	SentStIgnore bool   `yaml:"-"`
+	// Type of local folder, Syncthing.Type is used if empty
+	Type string `yaml:"-"`
+	// RemoteType of remote folder, sendreceive is used if empty
+	RemoteType string `yaml:"-"`
+	// Ignore patterns of .stignore of the folder, folders with nil Ignore use the shared .nhignore
+	Ignore []string `yaml:"-"`
 }
 
 //Ignores represents the .stignore file
@@ -118,6 +125,19 @@ type Syncthing struct {
 
 	// resolve ignore/sync from gitignore
 	EnableParseFromGitIgnore bool `yaml:"-"`
+
+	// derive ignored pattern from .gitignore or .dockerignore of local sync dir
+	IgnoreFrom []string `yaml:"-"`
+
+	// ExcludedPaths synced by other folders, they're ignored in every sync mode
+	ExcludedPaths []string `yaml:"-"`
+
+	// rate limits from the view of local, 0 is unlimited
+	MaxSendKbps int    `yaml:"-"`
+	MaxRecvKbps int    `yaml:"-"`
+	Compression string `yaml:"-"`
+	// files larger than MaxFileSize bytes are ignored, 0 is unlimited
+	MaxFileSize int64 `yaml:"-"`
 }
 
 //IsSubPathFolder checks if a sync folder is a subpath of another sync folder
@@ -170,6 +190,19 @@ func (s *Syncthing) initConfig() error {
 		return fmt.Errorf("failed to create %s: %s", s.LocalHome, err)
 	}
 
+	// receive only folder may not exist locally until files are generated remotely
+	for _, folder := range s.Folders {
+		if folder.Type == _const.ReceiveOnlySyncType {
+			if err := os.MkdirAll(folder.LocalPath, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %s", folder.LocalPath, err)
+			}
+		}
+	}
+
+	if _, err := s.writeFolderIgnores(); err != nil {
+		return err
+	}
+
 	if err := s.UpdateConfig(); err != nil {
 		return err
 	}
@@ -199,9 +232,88 @@ func (s *Syncthing) UpdateConfig() error {
 	return nil
 }
 
+// writeFolderIgnores writes .stignore of folders having their own ignore patterns,
+// returns names of folders whose .stignore changed
+func (s *Syncthing) writeFolderIgnores() ([]string, error) {
+	changed := make([]string, 0)
+	for _, folder := range s.Folders {
+		if folder.Ignore == nil {
+			continue
+		}
+		large, err := s.largeFiles(folder, folder.Ignore)
+		if err != nil {
+			return nil, err
+		}
+		content := []byte(strings.Join(append(append([]string{}, folder.Ignore...), large...), "\n"))
+		ignoreFilePath := filepath.Join(folder.LocalPath, ".stignore")
+		if origin, err := ioutil.ReadFile(ignoreFilePath); err == nil && bytes.Equal(origin, content) {
+			continue
+		}
+		if err = ioutil.WriteFile(ignoreFilePath, content, _const.DefaultNewFilePermission); err != nil {
+			return nil, fmt.Errorf("failed to write .stignore of folder %s: %w", folder.Name, err)
+		}
+		changed = append(changed, folder.Name)
+	}
+	return changed, nil
+}
+
+// largeFiles anchored patterns of files of folder larger than MaxFileSize, files ignored by skipped are not walked,
+// they're written to the ignore file of the folder, since patterns of other folders are relative to their own root
+func (s *Syncthing) largeFiles(folder *Folder, skipped []string) ([]string, error) {
+	// syncthing has no limit of file size, large files are ignored one by one
+	if s.MaxFileSize <= 0 || folder.Type == _const.ReceiveOnlySyncType {
+		return nil, nil
+	}
+	large, err := ignore.LargeFiles(folder.LocalPath, s.MaxFileSize, skipped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find files larger than %d bytes: %w", s.MaxFileSize, err)
+	}
+	return large, nil
+}
+
 // Generate s.LocalHome/.nhignore by file sync option
 func (s *Syncthing) generateIgnoredFileConfig() (string, error) {
 	var ignoreFilePath = filepath.Join(s.LocalHome, IgnoredFIle)
+
+	content, err := s.ignoredFileConfig()
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(ignoreFilePath, content, _const.DefaultNewFilePermission); err != nil {
+		return "", fmt.Errorf("failed to generate .nhignore configuration: %w", err)
+	}
+
+	return ignoreFilePath, nil
+}
+
+// RegenerateIgnoredFileConfig regenerates s.LocalHome/.nhignore and .stignore of folders having their own,
+// returns names of folders whose ignore file changed, syncthing reloads them while scanning
+func (s *Syncthing) RegenerateIgnoredFileConfig() ([]string, error) {
+	var ignoreFilePath = filepath.Join(s.LocalHome, IgnoredFIle)
+
+	changed, err := s.writeFolderIgnores()
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.ignoredFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	if origin, err := ioutil.ReadFile(ignoreFilePath); err == nil && bytes.Equal(origin, content) {
+		return changed, nil
+	}
+	if err := ioutil.WriteFile(ignoreFilePath, content, _const.DefaultNewFilePermission); err != nil {
+		return nil, fmt.Errorf("failed to generate .nhignore configuration: %w", err)
+	}
+	for _, folder := range s.Folders {
+		if folder.Ignore == nil {
+			changed = append(changed, folder.Name)
+		}
+	}
+	return changed, nil
+}
+
+func (s *Syncthing) ignoredFileConfig() ([]byte, error) {
 	var syncedPatternAdaption = make([]string, len(s.SyncedPattern))
 	var enableParseFromGitIgnore = DisableParseFromGitIgnore
 
@@ -254,22 +366,48 @@ func (s *Syncthing) generateIgnoredFileConfig() (string, error) {
 		log.Infof("SyncedPattern: \n" + syncedPattern)
 	}
 
+	derivedPattern := ""
+	if len(s.IgnoreFrom) > 0 && len(s.Folders) > 0 {
+		derived, err := ignore.Derive(s.Folders[0].LocalPath, s.IgnoreFrom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive ignored pattern from %v: %w", s.IgnoreFrom, err)
+		}
+		derivedPattern = strings.Join(derived, "\n")
+	}
+
+	excludedPattern := make([]string, 0, len(s.ExcludedPaths))
+	for _, p := range s.ExcludedPaths {
+		excludedPattern = append(excludedPattern, "/"+strings.Trim(p, "/"))
+	}
+
+	// patterns of the shared file are relative to the local sync dir, so are the large files
+	if len(s.Folders) > 0 && s.Folders[0].Ignore == nil {
+		skipped := append(append([]string{}, ignoredPatternAdaption...), excludedPattern...)
+		if derivedPattern != "" {
+			skipped = append(skipped, strings.Split(derivedPattern, "\n")...)
+		}
+		large, err := s.largeFiles(s.Folders[0], skipped)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range large {
+			derivedPattern += "\n" + p
+		}
+	}
+
 	var values = map[string]string{
 		"enableParseFromGitIgnore": enableParseFromGitIgnore,
+		"excludedPattern":          strings.Join(excludedPattern, "\n"),
 		"ignoredPattern":           ignoredPattern,
+		"derivedPattern":           derivedPattern,
 		"syncedPattern":            syncedPattern,
 	}
 
 	buf := new(bytes.Buffer)
 	if err := ignoredFileTemplate.Execute(buf, values); err != nil {
-		return "", fmt.Errorf("failed to write .nhignore configuration template: %w", err)
-	}
-
-	if err := ioutil.WriteFile(ignoreFilePath, buf.Bytes(), _const.DefaultNewFilePermission); err != nil {
-		return "", fmt.Errorf("failed to generate .nhignore configuration: %w", err)
+		return nil, fmt.Errorf("failed to write .nhignore configuration template: %w", err)
 	}
-
-	return ignoreFilePath, nil
+	return buf.Bytes(), nil
 }
 
 // Run local syncthing server
@@ -0,0 +1,101 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package syncthing
+
+import (
+	"io/ioutil"
+	"nocalhost/internal/nhctl/const"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExcludedPathsIgnoredInGitIgnoreMode(t *testing.T) {
+	s := &Syncthing{
+		EnableParseFromGitIgnore: true,
+		IgnoredPattern:           []string{"*.log"},
+		ExcludedPaths:            []string{"gen"},
+	}
+	content, err := s.ignoredFileConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(content), "\n")
+	found := false
+	for _, line := range lines {
+		if line == "*.log" {
+			t.Fatal("ignored pattern should not take effect in gitIgnore mode")
+		}
+		found = found || line == "/gen"
+	}
+	if !found {
+		t.Fatalf("/gen should be ignored in gitIgnore mode, got:\n%s", content)
+	}
+}
+
+func TestWriteFolderIgnores(t *testing.T) {
+	shared, reverse := t.TempDir(), t.TempDir()
+	s := &Syncthing{
+		Folders: []*Folder{
+			{Name: "1", LocalPath: shared},
+			{Name: "reverse-1", LocalPath: reverse, Type: _const.ReceiveOnlySyncType, Ignore: []string{"*.tmp"}},
+		},
+	}
+	if changed, err := s.writeFolderIgnores(); err != nil || len(changed) != 1 || changed[0] != "reverse-1" {
+		t.Fatalf("only .stignore of reverse-1 should be written, got %v, err: %v", changed, err)
+	}
+	if changed, _ := s.writeFolderIgnores(); len(changed) != 0 {
+		t.Fatalf(".stignore not changed should not be rewritten, got %v", changed)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(shared, ".stignore")); err == nil {
+		t.Fatal("folder using the shared .nhignore should not have its own .stignore")
+	}
+	content, err := ioutil.ReadFile(filepath.Join(reverse, ".stignore"))
+	if err != nil || string(content) != "*.tmp" {
+		t.Fatalf("unexpected .stignore of reverse folder %q, err: %v", content, err)
+	}
+}
+
+func TestLargeFilesPerFolder(t *testing.T) {
+	root, mapping := t.TempDir(), t.TempDir()
+	write := func(p string, size int) {
+		if err := ioutil.WriteFile(p, make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "model.bin"), 2048)
+	write(filepath.Join(mapping, "data.bin"), 2048)
+	write(filepath.Join(mapping, "small.txt"), 10)
+
+	s := &Syncthing{
+		LocalHome:   t.TempDir(),
+		MaxFileSize: 1024,
+		Folders: []*Folder{
+			{Name: "1", LocalPath: root},
+			{Name: "map-1", LocalPath: mapping, Ignore: []string{}},
+		},
+	}
+	changed, err := s.RegenerateIgnoredFileConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(changed, ",") != "map-1,1" {
+		t.Fatalf("unexpected changed folders %v", changed)
+	}
+
+	shared, _ := ioutil.ReadFile(filepath.Join(s.LocalHome, IgnoredFIle))
+	if !strings.Contains(string(shared), "/model.bin") || strings.Contains(string(shared), "data.bin") {
+		t.Fatalf("large files of mapping should not leak into the shared ignore file:\n%s", shared)
+	}
+	own, _ := ioutil.ReadFile(filepath.Join(mapping, ".stignore"))
+	if string(own) != "/data.bin" {
+		t.Fatalf("unexpected .stignore of mapping %q", own)
+	}
+
+	if changed, _ = s.RegenerateIgnoredFileConfig(); len(changed) != 0 {
+		t.Fatalf("nothing should change, got %v", changed)
+	}
+}
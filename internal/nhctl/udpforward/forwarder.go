@@ -0,0 +1,89 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package udpforward
+
+import (
+	"context"
+	"io"
+	"net"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/pfstats"
+	"sync"
+)
+
+// Forward sends datagrams received by conn to the tunnel, and replies from the tunnel back to
+// the local peers, each local address is a peer of the relay. It returns when ctx is done or
+// either conn or tunnel is broken, conn is closed before return
+func Forward(ctx context.Context, conn net.PacketConn, tunnel io.ReadWriter) error {
+	var (
+		lock    sync.Mutex
+		peers   = map[string]uint32{}
+		addrs   = map[uint32]net.Addr{}
+		errCh   = make(chan error, 2)
+		buf     = make([]byte, maxDatagram)
+		counter uint32
+		stats   *pfstats.Counter
+	)
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		stats = pfstats.Lookup(addr.Port, _const.PortForwardUDP)
+	}
+
+	go func() {
+		for {
+			peer, payload, err := ReadFrame(tunnel)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			lock.Lock()
+			addr, ok := addrs[peer]
+			lock.Unlock()
+			if !ok {
+				continue
+			}
+			if _, err = conn.WriteTo(payload, addr); err != nil {
+				errCh <- err
+				return
+			}
+			stats.In(len(payload))
+		}
+	}()
+
+	go func() {
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			lock.Lock()
+			peer, ok := peers[addr.String()]
+			if !ok {
+				counter++
+				peer = counter
+				peers[addr.String()] = peer
+				addrs[peer] = addr
+			}
+			lock.Unlock()
+			if err = WriteFrame(tunnel, peer, buf[:n]); err != nil {
+				errCh <- err
+				return
+			}
+			stats.Out(n)
+		}
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errCh:
+	}
+	_ = conn.Close()
+	if c, ok := tunnel.(io.Closer); ok {
+		_ = c.Close()
+	}
+	return err
+}
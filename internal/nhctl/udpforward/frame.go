@@ -0,0 +1,45 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package udpforward
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// frameHeaderLen 4 bytes of peer id and 2 bytes of payload length
+const frameHeaderLen = 6
+
+// maxDatagram max payload of udp over ipv4
+const maxDatagram = 65507
+
+// WriteFrame writes one datagram of peer to the stream tunnel, boundaries of datagrams are kept by framing
+func WriteFrame(w io.Writer, peer uint32, payload []byte) error {
+	if len(payload) > maxDatagram {
+		return errors.New("datagram is too large")
+	}
+	buf := make([]byte, frameHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(buf, peer)
+	binary.BigEndian.PutUint16(buf[4:], uint16(len(payload)))
+	copy(buf[frameHeaderLen:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadFrame reads one datagram written by WriteFrame
+func ReadFrame(r io.Reader) (uint32, []byte, error) {
+	header := make([]byte, frameHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[4:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint32(header), payload, nil
+}
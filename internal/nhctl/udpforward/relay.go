@@ -0,0 +1,98 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package udpforward
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// PeerIdleTimeout socket of a peer is closed after no reply received for the duration
+var PeerIdleTimeout = 2 * time.Minute
+
+// Relay is the in-pod side of the tunnel, datagrams of each peer are sent to target from
+// the peer's own socket, so replies are routed back to the right local address
+func Relay(ctx context.Context, target string, tunnel io.ReadWriter) error {
+	var (
+		lock      sync.Mutex
+		writeLock sync.Mutex
+		peers     = map[uint32]net.Conn{}
+		errCh     = make(chan error, 1)
+	)
+
+	closeAll := func() {
+		lock.Lock()
+		defer lock.Unlock()
+		for id, conn := range peers {
+			_ = conn.Close()
+			delete(peers, id)
+		}
+	}
+	defer closeAll()
+
+	reply := func(peer uint32, conn net.Conn) {
+		buf := make([]byte, maxDatagram)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(PeerIdleTimeout))
+			n, err := conn.Read(buf)
+			if err != nil {
+				lock.Lock()
+				if peers[peer] == conn {
+					delete(peers, peer)
+				}
+				lock.Unlock()
+				_ = conn.Close()
+				return
+			}
+			writeLock.Lock()
+			err = WriteFrame(tunnel, peer, buf[:n])
+			writeLock.Unlock()
+			if err != nil {
+				select {
+				case errCh <- err:
+				default:
+				}
+				return
+			}
+		}
+	}
+
+	go func() {
+		for {
+			peer, payload, err := ReadFrame(tunnel)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			lock.Lock()
+			conn, ok := peers[peer]
+			if !ok {
+				if conn, err = net.Dial("udp", target); err != nil {
+					lock.Unlock()
+					continue
+				}
+				peers[peer] = conn
+				go reply(peer, conn)
+			}
+			lock.Unlock()
+			// udp is lossy, failure of one datagram does not break the tunnel
+			_, _ = conn.Write(payload)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,123 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package udpforward
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/nocalhost_path"
+)
+
+// RelayPath where the relay is installed in container, the relay is a linux nhctl serving `nhctl udp-relay`
+const RelayPath = "/tmp/.nocalhost-udp-relay"
+
+// Executor runs cmd in container, stdin and stdout are streamed through the exec api
+type Executor func(cmd []string, stdin io.Reader, stdout io.Writer) error
+
+// RelayCommand the command serving the in-pod side of the tunnel to udp port of container
+func RelayCommand(port int) []string {
+	return []string{RelayPath, "udp-relay", "--port", strconv.Itoa(port)}
+}
+
+// RelayArch GOARCH of container detected by uname -m, only amd64 and arm64 are supported
+func RelayArch(exec Executor) (string, error) {
+	out := &bytes.Buffer{}
+	if err := exec([]string{"uname", "-m"}, nil, out); err != nil {
+		return "", errors.Wrap(err, "Failed to detect arch of container")
+	}
+	switch machine := strings.TrimSpace(out.String()); machine {
+	case "x86_64", "amd64":
+		return "amd64", nil
+	case "aarch64", "arm64":
+		return "arm64", nil
+	default:
+		return "", errors.New(
+			fmt.Sprintf("UDP port-forward does not support container of arch %s, only amd64 and arm64 are supported",
+				machine),
+		)
+	}
+}
+
+// LocalRelayBinary linux nhctl of arch copied into container as the relay, it's nhctl itself on linux of
+// the same arch, or nhctl-linux-<arch> placed in bin dir of nhctl home on other platforms
+func LocalRelayBinary(arch string) (string, error) {
+	if runtime.GOOS == "linux" && runtime.GOARCH == arch {
+		p, err := os.Executable()
+		return p, errors.Wrap(err, "")
+	}
+	p := filepath.Join(nocalhost_path.GetNhctlHomeDir(), _const.DefaultBinDirName, "nhctl-linux-"+arch)
+	if _, err := os.Stat(p); err != nil {
+		return "", errors.New(
+			fmt.Sprintf("UDP port-forward needs linux %s nhctl as relay, please download it to %s", arch, p),
+		)
+	}
+	return p, nil
+}
+
+// EnsureRelay copies the relay into container if it is not installed
+func EnsureRelay(exec Executor) error {
+	if exec([]string{"test", "-x", RelayPath}, nil, nil) == nil {
+		return nil
+	}
+	arch, err := RelayArch(exec)
+	if err != nil {
+		return err
+	}
+	binary, err := LocalRelayBinary(arch)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(binary)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer f.Close()
+	tmp := RelayPath + ".tmp"
+	return errors.Wrap(
+		exec(
+			[]string{"sh", "-c", fmt.Sprintf("cat > %s && chmod +x %s && mv %s %s", tmp, tmp, tmp, RelayPath)},
+			f, nil,
+		), "Failed to install udp relay",
+	)
+}
+
+type tunnel struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+}
+
+func (t *tunnel) Close() error {
+	for _, c := range t.closers {
+		_ = c.Close()
+	}
+	return nil
+}
+
+// Dial starts the relay of port in container, and returns the tunnel streamed by exec api,
+// the tunnel is broken when the exec exits
+func Dial(exec Executor, port int) io.ReadWriteCloser {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	go func() {
+		err := exec(RelayCommand(port), stdinR, stdoutW)
+		if err == nil {
+			err = io.EOF
+		}
+		_ = stdinR.CloseWithError(err)
+		_ = stdoutW.CloseWithError(err)
+	}()
+	return &tunnel{Reader: stdoutR, Writer: stdinW, closers: []io.Closer{stdinW, stdoutR}}
+}
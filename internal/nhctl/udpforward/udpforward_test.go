@@ -0,0 +1,116 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package udpforward
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFrame(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for i, p := range []string{"hello", "", "world"} {
+		if err := WriteFrame(buf, uint32(i), []byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, p := range []string{"hello", "", "world"} {
+		peer, payload, err := ReadFrame(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if peer != uint32(i) || string(payload) != p {
+			t.Errorf("frame %d: got %d %q", i, peer, payload)
+		}
+	}
+}
+
+type pipeTunnel struct {
+	io.Reader
+	io.Writer
+}
+
+func TestForwardAndRelay(t *testing.T) {
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteTo(append([]byte("echo "), buf[:n]...), addr)
+		}
+	}()
+
+	local, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upR, upW := io.Pipe()
+	downR, downW := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = Relay(ctx, echo.LocalAddr().String(), pipeTunnel{upR, downW}) }()
+	go func() { _ = Forward(ctx, local, pipeTunnel{downR, upW}) }()
+
+	for _, msg := range []string{"a", "b"} {
+		client, err := net.Dial("udp", local.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = client.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 1024)
+		n, err := client.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != "echo "+msg {
+			t.Errorf("got %q", buf[:n])
+		}
+		_ = client.Close()
+	}
+}
+
+func TestRelayArch(t *testing.T) {
+	uname := func(machine string) Executor {
+		return func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+			_, err := io.WriteString(stdout, machine+"\n")
+			return err
+		}
+	}
+	for machine, expected := range map[string]string{"x86_64": "amd64", "aarch64": "arm64", "arm64": "arm64"} {
+		if arch, err := RelayArch(uname(machine)); err != nil || arch != expected {
+			t.Errorf("%s: expected %s, got %s, err: %v", machine, expected, arch, err)
+		}
+	}
+	if _, err := RelayArch(uname("s390x")); err == nil {
+		t.Error("unsupported arch should fail")
+	}
+	if err := EnsureRelay(
+		func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+			if cmd[0] == "uname" {
+				_, err := io.WriteString(stdout, "ppc64le\n")
+				return err
+			}
+			// relay is not installed
+			return io.EOF
+		},
+	); err == nil {
+		t.Error("relay should not be installed into container of unsupported arch")
+	}
+}
@@ -7,9 +7,11 @@ package utils
 
 import (
 	"context"
+	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 	"io"
 	"net"
+	"nocalhost/internal/nhctl/pfstats"
 	"nocalhost/pkg/nhctl/log"
 	"time"
 )
@@ -62,6 +64,59 @@ func Reverse(account *Account, sshEndpoint, remoteEndpoint, localEndpoint string
 	}
 }
 
+// ReverseForward is like Reverse, but it keeps serving if local endpoint is unavailable,
+// until ctx done or ssh connection broken. Traffic is counted to counter, which may be nil
+func ReverseForward(
+	ctx context.Context, account *Account, sshEndpoint, remoteEndpoint, localEndpoint string,
+	counter *pfstats.Counter,
+) error {
+	sshConfig := &ssh.ClientConfig{
+		User:            account.Username,
+		Auth:            []ssh.AuthMethod{ssh.Password(account.Password)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second * 60,
+	}
+	sshConn, err := ssh.Dial("tcp", sshEndpoint, sshConfig)
+	if err != nil {
+		return errors.Wrap(err, "fail to create ssh connection")
+	}
+	defer sshConn.Close()
+
+	listener, err := sshConn.Listen("tcp", remoteEndpoint)
+	if err != nil {
+		return errors.Wrap(err, "fail to listen remote endpoint")
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	log.Infof("Forwarding to %s <- %s", localEndpoint, remoteEndpoint)
+	for {
+		remoteConn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "")
+		}
+		localConn, err := net.Dial("tcp", localEndpoint)
+		if err != nil {
+			log.Warnf("Dial local endpoint %s error, err: %v", localEndpoint, err)
+			counter.Error()
+			_ = remoteConn.Close()
+			continue
+		}
+		counter.Opened()
+		go func() {
+			copyStream(remoteConn, counter.Wrap(localConn))
+			counter.Closed()
+			_ = remoteConn.Close()
+			_ = localConn.Close()
+		}()
+	}
+}
+
 func copyStream(remoteConn net.Conn, localConn net.Conn) {
 	cancel, cancelFunc := context.WithCancel(context.Background())
 	go func() {
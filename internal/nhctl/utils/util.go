@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"io"
 	"io/ioutil"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/syncthing/ports"
 	"nocalhost/pkg/nhctl/log"
 	"nocalhost/pkg/nhctl/tools"
@@ -231,9 +232,20 @@ func ReplaceCodingcorpString(old string) string {
 	return re3.ReplaceAllString(old, "nocalhost-docker.pkg.coding.net")
 }
 
-// portStr is like 8080:80, :80 or 80
+// GetPortForwardProtocol splits the protocol prefix of portStr, such as udp:8125:8125, tcp is the default
+func GetPortForwardProtocol(portStr string) (string, string) {
+	for _, protocol := range []string{_const.PortForwardUDP, _const.PortForwardTCP} {
+		if strings.HasPrefix(strings.ToLower(portStr), protocol+":") {
+			return protocol, portStr[len(protocol)+1:]
+		}
+	}
+	return _const.PortForwardTCP, portStr
+}
+
+// portStr is like 8080:80, :80 or 80, with an optional protocol prefix such as udp:8125:8125
 func GetPortForwardForString(portStr string) (int, int, error) {
 	var err error
+	_, portStr = GetPortForwardProtocol(portStr)
 	s := strings.Split(portStr, ":")
 
 	switch len(s) {
@@ -9,6 +9,7 @@ import (
 	miekgdns "github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/cache"
+	"net"
 	"strings"
 	"time"
 )
@@ -82,3 +83,38 @@ func (s *server) ServeDNS(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
 		}
 	}
 }
+
+// hostsServer answers A records of names resolved by lookup, other names are NXDOMAIN
+type hostsServer struct {
+	lookup func(name string) net.IP
+}
+
+// NewHostsDNSServer creates dns server resolving names by lookup instead of forwarding to kube-dns,
+// for resolving local addresses of services without vpn, caller should start and shutdown it
+func NewHostsDNSServer(network, address string, lookup func(name string) net.IP) *miekgdns.Server {
+	return &miekgdns.Server{Addr: address, Net: network, Handler: &hostsServer{lookup: lookup}}
+}
+
+func (s *hostsServer) ServeDNS(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+	m := new(miekgdns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	m.Rcode = miekgdns.RcodeNameError
+	for _, question := range r.Question {
+		ip := s.lookup(strings.ToLower(strings.TrimSuffix(question.Name, ".")))
+		if ip == nil {
+			continue
+		}
+		// the name exists even if it has no AAAA record
+		m.Rcode = miekgdns.RcodeSuccess
+		if question.Qtype == miekgdns.TypeA && ip.To4() != nil {
+			m.Answer = append(m.Answer, &miekgdns.A{
+				Hdr: miekgdns.RR_Header{Name: question.Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 5},
+				A:   ip.To4(),
+			})
+		}
+	}
+	if err := w.WriteMsg(m); err != nil {
+		log.Warnln(err)
+	}
+}
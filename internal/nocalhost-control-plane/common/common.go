@@ -20,4 +20,6 @@ const (
 	MeshHeaderVal  = controller.AnnotationMeshHeaderValue
 	MeshDevType    = controller.AnnotationMeshTypeDev
 	MeshOriginType = controller.AnnotationMeshTypeOrigin
+
+	MeshMirrorPercent = controller.AnnotationMeshMirrorPercent
 )
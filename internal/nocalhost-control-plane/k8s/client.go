@@ -18,7 +18,8 @@ var (
 	Namespace  string
 )
 
-func init() {
+// Init initializes the in-cluster client, it panics out of cluster
+func Init() {
 	var err error
 	RestConfig, err = config.GetConfig()
 	if err != nil {
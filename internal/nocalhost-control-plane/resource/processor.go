@@ -18,6 +18,8 @@ import (
 	"nocalhost/internal/nocalhost-control-plane/common"
 	"nocalhost/internal/nocalhost-control-plane/k8s"
 	"nocalhost/internal/nocalhost-control-plane/pkg/util"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +36,8 @@ type meshInfo struct {
 	// headerKey+headerValue --> route info
 	dev    map[headerPair]*routeInfo
 	origin *routeInfo
+	// dev pod name --> mirror info, each dev pod in mirror mode receives its own shadow copies of requests to origin
+	mirror map[string]*mirrorInfo
 }
 
 type headerPair struct {
@@ -47,6 +51,12 @@ type routeInfo struct {
 	endpoints sets.String
 }
 
+type mirrorInfo struct {
+	percent  uint32
+	port     sets.Int32
+	endpoint string
+}
+
 type Processor struct {
 	mu          sync.RWMutex
 	PodInformer corev1.PodInformer
@@ -55,6 +65,7 @@ type Processor struct {
 }
 
 func (s *Processor) Start(ctx context.Context) {
+	k8s.Init()
 	s.Log.Debugf("start snapshot")
 	s.Log.Debugf("start pod informer")
 	factory := informers.NewSharedInformerFactoryWithOptions(k8s.ClientSet, time.Second*10, informers.WithNamespace(k8s.Namespace))
@@ -125,34 +136,61 @@ func listPods(uuid string) (devPods []v1.Pod, originPods []v1.Pod, err error) {
 
 func (s *Processor) getMeshInfo(uuid string) (meshInfo, error) {
 	s.Log.Debugf("get mesh info by uuid: %s", uuid)
-	info := meshInfo{
-		uuid: uuid,
-		dev:  map[headerPair]*routeInfo{},
+	devPods, originPods, err := listPods(uuid)
+	if err != nil {
+		return newMeshInfo(uuid), err
+	}
+	info := buildMeshInfo(uuid, devPods, originPods)
+
+	var sb strings.Builder
+	sb.WriteString("dev:\n")
+	for pair, r := range info.dev {
+		sb.WriteString(fmt.Sprintf("%s=%s, pod name: %#v\n", pair.headerKey, pair.headerVal, *r))
+	}
+	sb.WriteString("mirror:\n")
+	for name, m := range info.mirror {
+		sb.WriteString(fmt.Sprintf("%d%%, pod name: %s, %#v\n", m.percent, name, *m))
+	}
+	sb.WriteString("origin:\n")
+	sb.WriteString(fmt.Sprintf("%#v", *info.origin))
+	s.Log.Debugf("the mesh info: \n%s", sb.String())
+	return info, nil
+}
+
+func newMeshInfo(uuid string) meshInfo {
+	return meshInfo{
+		uuid:   uuid,
+		dev:    map[headerPair]*routeInfo{},
+		mirror: map[string]*mirrorInfo{},
 		origin: &routeInfo{
 			name:      sets.NewString(),
 			port:      sets.NewInt32(),
 			endpoints: sets.NewString(),
 		},
 	}
+}
 
-	devPods, originPods, err := listPods(info.uuid)
-	if err != nil {
-		return info, err
-	}
+func buildMeshInfo(uuid string, devPods []v1.Pod, originPods []v1.Pod) meshInfo {
+	info := newMeshInfo(uuid)
 	for _, pod := range devPods {
 		if pod.DeletionTimestamp != nil {
 			continue
 		}
-		h := headerPair{
-			headerKey: pod.Annotations[common.MeshHeaderKey],
-			headerVal: pod.Annotations[common.MeshHeaderVal],
-		}
 		ports := sets.NewInt32()
 		for _, container := range pod.Spec.Containers {
 			for _, port := range container.Ports {
 				ports.Insert(port.ContainerPort)
 			}
 		}
+		if percent, err := strconv.ParseUint(pod.Annotations[common.MeshMirrorPercent], 10, 32); err == nil &&
+			percent > 0 {
+			info.mirror[pod.Name] = &mirrorInfo{percent: uint32(percent), port: ports, endpoint: pod.Status.PodIP}
+			continue
+		}
+		h := headerPair{
+			headerKey: pod.Annotations[common.MeshHeaderKey],
+			headerVal: pod.Annotations[common.MeshHeaderVal],
+		}
 		if v, found := info.dev[h]; found {
 			v.name.Insert(pod.Name)
 			v.endpoints.Insert(pod.Status.PodIP)
@@ -180,16 +218,29 @@ func (s *Processor) getMeshInfo(uuid string) (meshInfo, error) {
 		info.origin.endpoints.Insert(pod.Status.PodIP)
 		info.origin.port.Insert(ports.List()...)
 	}
+	return info
+}
 
-	var sb strings.Builder
-	sb.WriteString("dev:\n")
-	for pair, r := range info.dev {
-		sb.WriteString(fmt.Sprintf("%s=%s, pod name: %#v\n", pair.headerKey, pair.headerVal, *r))
+// buildMirrors every dev pod in mirror mode has its own cluster, so the requests are mirrored to
+// each of them by its own percent, instead of being balanced among pods with the same percent
+func buildMirrors(info meshInfo, port int32) (
+	[]types.Resource, []types.Resource, []*route.RouteAction_RequestMirrorPolicy) {
+	clusters := make([]types.Resource, 0)
+	endpoints := make([]types.Resource, 0)
+	var mirrors []*route.RouteAction_RequestMirrorPolicy
+	names := make([]string, 0, len(info.mirror))
+	for name := range info.mirror {
+		names = append(names, name)
 	}
-	sb.WriteString("origin:\n")
-	sb.WriteString(fmt.Sprintf("%#v", *info.origin))
-	s.Log.Debugf("the mesh info: \n%s", sb.String())
-	return info, nil
+	sort.Strings(names)
+	for _, name := range names {
+		m := info.mirror[name]
+		mirrorPort := fmt.Sprintf("mirror-%s-%v", name, port)
+		clusters = append(clusters, buildCluster(mirrorPort))
+		endpoints = append(endpoints, buildEndpoint(mirrorPort, []string{m.endpoint}, uint32(port)))
+		mirrors = append(mirrors, ToMirrorPolicy(mirrorPort, m.percent))
+	}
+	return clusters, endpoints, mirrors
 }
 
 func (s *Processor) parse(ctx context.Context, uuid string) error {
@@ -216,7 +267,11 @@ func (s *Processor) parse(ctx context.Context, uuid string) error {
 				endpoints = append(endpoints, buildEndpoint(headerPort, routes.endpoints.List(), uint32(port)))
 				rr = append(rr, ToRoute(headerPort, map[string]string{pair.headerKey: pair.headerVal}))
 			}
-			rr = append(rr, defaultRoute(common.PassthroughCluster))
+			// requests served by origin are mirrored to dev pods in mirror mode
+			mirrorClusters, mirrorEndpoints, mirrors := buildMirrors(info, port)
+			clusters = append(clusters, mirrorClusters...)
+			endpoints = append(endpoints, mirrorEndpoints...)
+			rr = append(rr, withMirrorPolicies(defaultRoute(common.PassthroughCluster), mirrors))
 			routers = append(routers, &route.RouteConfiguration{
 				Name: unique,
 				VirtualHosts: []*route.VirtualHost{
@@ -295,6 +350,40 @@ func (s *Processor) parse(ctx context.Context, uuid string) error {
 			}
 		}
 	}
+
+	// dev pods in mirror mode only serve the shadow copies locally
+	for name, m := range info.mirror {
+		listeners := make([]types.Resource, 0)
+		routers := make([]types.Resource, 0)
+		for _, port := range m.port.List() {
+			unique := fmt.Sprintf("%s-%v", name, port)
+			listeners = append(listeners, buildListener(unique, uint32(port)))
+			routers = append(routers, &route.RouteConfiguration{
+				Name: unique,
+				VirtualHosts: []*route.VirtualHost{
+					{
+						Name:    "local_service",
+						Domains: []string{"*"},
+						Routes:  []*route.Route{defaultRoute(common.PassthroughCluster)},
+					},
+				},
+			})
+		}
+
+		s.Log.Infof("parse snapshot for: %s", name)
+		snapshot, err := cache.NewSnapshot(time.Now().String(), map[resource.Type][]types.Resource{
+			resource.ListenerType: listeners,
+			resource.RouteType:    routers,
+		})
+		if err != nil {
+			s.Log.Errorf("%v", err)
+			continue
+		}
+		if err := s.Snapshot.SetSnapshot(ctx, name, snapshot); err != nil {
+			s.Log.Errorf("%v", err)
+			continue
+		}
+	}
 	return nil
 }
 
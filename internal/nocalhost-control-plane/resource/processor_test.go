@@ -0,0 +1,58 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package resource
+
+import (
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"nocalhost/internal/nocalhost-control-plane/common"
+	"testing"
+)
+
+func newMeshPod(name, ip string, annotations map[string]string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "app", Ports: []v1.ContainerPort{{ContainerPort: 8080}}}},
+		},
+		Status: v1.PodStatus{PodIP: ip},
+	}
+}
+
+func TestBuildMirrors(t *testing.T) {
+	devPods := []v1.Pod{
+		newMeshPod("alice", "10.0.0.1", map[string]string{common.MeshMirrorPercent: "50"}),
+		newMeshPod("bob", "10.0.0.2", map[string]string{common.MeshMirrorPercent: "50"}),
+		newMeshPod("carol", "10.0.0.3", map[string]string{common.MeshHeaderKey: "user", common.MeshHeaderVal: "carol"}),
+	}
+	originPods := []v1.Pod{newMeshPod("origin", "10.0.0.4", nil)}
+
+	info := buildMeshInfo("uuid", devPods, originPods)
+	if len(info.mirror) != 2 || len(info.dev) != 1 || info.origin.name.Len() != 1 {
+		t.Fatalf("unexpected mesh info, mirror: %v, dev: %v", info.mirror, info.dev)
+	}
+
+	clusters, endpoints, mirrors := buildMirrors(info, 8080)
+	if len(clusters) != 2 || len(endpoints) != 2 || len(mirrors) != 2 {
+		t.Fatalf("each mirror pod should have its own cluster, got %d clusters", len(clusters))
+	}
+	expected := map[string]string{"mirror-alice-8080": "10.0.0.1", "mirror-bob-8080": "10.0.0.2"}
+	for i, m := range mirrors {
+		if _, ok := expected[m.Cluster]; !ok {
+			t.Fatalf("unexpected mirror cluster %s", m.Cluster)
+		}
+		if m.RuntimeFraction.DefaultValue.Numerator != 50 {
+			t.Fatalf("unexpected percent %d of %s", m.RuntimeFraction.DefaultValue.Numerator, m.Cluster)
+		}
+		assignment := endpoints[i].(*endpoint.ClusterLoadAssignment)
+		lbEndpoints := assignment.Endpoints[0].LbEndpoints
+		address := lbEndpoints[0].GetEndpoint().Address.GetSocketAddress().Address
+		if assignment.ClusterName != m.Cluster || len(lbEndpoints) != 1 || address != expected[m.Cluster] {
+			t.Fatalf("cluster %s should only have endpoint %s", m.Cluster, expected[m.Cluster])
+		}
+	}
+}
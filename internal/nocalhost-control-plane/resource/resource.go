@@ -19,6 +19,7 @@ import (
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	httpv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
 	v32 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes/wrappers"
@@ -208,6 +209,26 @@ func defaultRoute(clusterName string) *route.Route {
 		},
 	}
 }
+
+// withMirrorPolicies requests matching the route are also sent to the mirror clusters,
+// responses from mirror clusters are ignored
+func withMirrorPolicies(r *route.Route, policies []*route.RouteAction_RequestMirrorPolicy) *route.Route {
+	r.GetRoute().RequestMirrorPolicies = policies
+	return r
+}
+
+func ToMirrorPolicy(clusterName string, percent uint32) *route.RouteAction_RequestMirrorPolicy {
+	return &route.RouteAction_RequestMirrorPolicy{
+		Cluster: clusterName,
+		RuntimeFraction: &core.RuntimeFractionalPercent{
+			DefaultValue: &typev3.FractionalPercent{
+				Numerator:   percent,
+				Denominator: typev3.FractionalPercent_HUNDRED,
+			},
+		},
+	}
+}
+
 func buildEndpoint(clusterName string, address []string, port uint32) *endpoint.ClusterLoadAssignment {
 	var lbEndpoints []*endpoint.LbEndpoint
 	for _, add := range address {
@@ -6,7 +6,6 @@
 package clientgoutils
 
 import (
-	"bytes"
 	"fmt"
 	// "k8s.io/cli-runtime/pkg/kustomize"
 	// "sigs.k8s.io/kustomize/pkg/fs"
@@ -167,3 +167,25 @@ func Execute(
 	}
 	return spdyExecutor.Stream(ops)
 }
+
+// ExecWithStream runs command in container without tty, stdin and stdout are streamed,
+// stdin can be nil if the command reads nothing
+func (c *ClientGoUtils) ExecWithStream(podName, containerName string, command []string,
+	stdin io.Reader, stdout, stderr io.Writer) error {
+	req := c.ClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(c.namespace).
+		SubResource("exec")
+	req.VersionedParams(
+		&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    stderr != nil,
+			TTY:       false,
+		}, scheme.ParameterCodec,
+	)
+	return errors.Wrap(Execute("POST", req.URL(), c.restConfig, stdin, stdout, stderr, false, nil), "")
+}
@@ -98,3 +98,9 @@ func (c *ClientGoUtils) UpdatePod(pod *corev1.Pod) (*corev1.Pod, error) {
 	pod2, err := c.GetPodClient().Update(c.ctx, pod, metav1.UpdateOptions{})
 	return pod2, errors.Wrap(err, "")
 }
+
+// UpdateEphemeralContainers updates the ephemeralcontainers subresource of a running pod
+func (c *ClientGoUtils) UpdateEphemeralContainers(pod *corev1.Pod) (*corev1.Pod, error) {
+	pod2, err := c.GetPodClient().UpdateEphemeralContainers(c.ctx, pod.Name, pod, metav1.UpdateOptions{})
+	return pod2, errors.Wrap(err, "")
+}
@@ -14,6 +14,8 @@ import (
 	"k8s.io/client-go/tools/portforward"
 	"net"
 	"net/http"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/pfstats"
 	"sort"
 	"strconv"
 	"strings"
@@ -330,6 +332,11 @@ func (pf *PortForwarder) nextRequestID() int {
 func (pf *PortForwarder) handleConnection(conn net.Conn, port ForwardedPort) {
 	defer conn.Close()
 
+	counter := pfstats.Lookup(int(port.Local), _const.PortForwardTCP)
+	counter.Opened()
+	defer counter.Closed()
+	conn = counter.Wrap(conn)
+
 	if pf.out != nil {
 		fmt.Fprintf(pf.out, "Handling connection for %d\n", port.Local)
 	}
@@ -346,6 +353,7 @@ firstCreateStream:
 	var err error
 	errorStream, err := pf.tryToCreateStream(&headers)
 	if err != nil {
+		counter.Error()
 		runtime.HandleError(fmt.Errorf("error creating error stream for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
@@ -372,6 +380,7 @@ firstCreateStream:
 		if defaultRetry > 0 {
 			goto firstCreateStream
 		}
+		counter.Error()
 		runtime.HandleError(fmt.Errorf("error creating forwarding stream for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
@@ -410,6 +419,7 @@ firstCreateStream:
 	// always expect something on errorChan (it may be nil)
 	err = <-errorChan
 	if err != nil {
+		counter.Error()
 		if strings.Contains(err.Error(), "failed to find socat") {
 			select {
 			case pf.errChan <- err:
@@ -20,6 +20,20 @@ import (
 // storageClassName: nil to use default storageClassName
 func (c *ClientGoUtils) CreatePVC(
 	name string, labels map[string]string, annotations map[string]string, quantityStr string, storageClassName *string,
+) (*v1.PersistentVolumeClaim, error) {
+	persistentVolumeClaim, err := GeneratePVC(name, labels, annotations, quantityStr, storageClassName)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ClientSet.CoreV1().PersistentVolumeClaims(c.namespace).Create(
+		c.ctx, persistentVolumeClaim, metav1.CreateOptions{},
+	)
+}
+
+// GeneratePVC generates a pvc without creating it
+func GeneratePVC(
+	name string, labels map[string]string, annotations map[string]string, quantityStr string, storageClassName *string,
 ) (*v1.PersistentVolumeClaim, error) {
 	q, err := resource.ParseQuantity(quantityStr)
 	if err != nil {
@@ -41,10 +55,7 @@ func (c *ClientGoUtils) CreatePVC(
 	persistentVolumeClaim.Name = name
 	persistentVolumeClaim.Labels = labels
 	persistentVolumeClaim.Annotations = annotations
-
-	return c.ClientSet.CoreV1().PersistentVolumeClaims(c.namespace).Create(
-		c.ctx, persistentVolumeClaim, metav1.CreateOptions{},
-	)
+	return persistentVolumeClaim, nil
 }
 
 func (c *ClientGoUtils) DeletePVC(name string) error {
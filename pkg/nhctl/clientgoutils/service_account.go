@@ -8,6 +8,7 @@ package clientgoutils
 import (
 	"context"
 	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,3 +33,15 @@ func (c *ClientGoUtils) CreateServiceAccountINE(name string) (*corev1.ServiceAcc
 func (c *ClientGoUtils) GetServiceAccount(name string) (*corev1.ServiceAccount, error) {
 	return c.ClientSet.CoreV1().ServiceAccounts(c.namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
+
+// CreateServiceAccountToken requests a bound token of the service account, just like the token projected into pods
+func (c *ClientGoUtils) CreateServiceAccountToken(name string, expirationSeconds int64) (string, error) {
+	tr := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}
+	tr, err := c.ClientSet.CoreV1().ServiceAccounts(c.namespace).CreateToken(c.ctx, name, tr, metav1.CreateOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	return tr.Status.Token, nil
+}
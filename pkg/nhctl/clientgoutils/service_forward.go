@@ -0,0 +1,393 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/pfstats"
+	"nocalhost/pkg/nhctl/log"
+)
+
+// ServiceEndpoint a ready pod behind the service, Port is the target port of the pod
+type ServiceEndpoint struct {
+	Pod  string
+	Port int
+}
+
+func (e ServiceEndpoint) String() string {
+	return fmt.Sprintf("%s:%d", e.Pod, e.Port)
+}
+
+// serviceForwarder balances local connections across ready endpoints of a service by round robin,
+// endpoints are watched, and pods are dropped as soon as they are not ready
+type serviceForwarder struct {
+	client    *ClientGoUtils
+	service   string
+	localPort int
+	port      corev1.ServicePort
+	onChange  func([]ServiceEndpoint)
+	errOut    io.Writer
+
+	lock      sync.Mutex
+	endpoints []ServiceEndpoint
+	next      int
+	conns     map[string]httpstream.Connection // spdy connection of each pod
+	requestID int
+}
+
+// ForwardService forwards localPort to port of service, new connections are balanced across ready
+// endpoints and failed over to others when an endpoint disappears. onChange is called with ready endpoints
+// whenever they are changed. It blocks until stopChan closed or the service deleted
+func (c *ClientGoUtils) ForwardService(
+	service string, localPort, port int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams,
+	onChange func([]ServiceEndpoint),
+) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", localPort))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	return c.ForwardServiceWithListener(service, listener, port, readyChan, stopChan, g, onChange)
+}
+
+// ForwardServiceWithListener is the same as ForwardService, but connections are accepted from listener,
+// which is closed when it returns
+func (c *ClientGoUtils) ForwardServiceWithListener(
+	service string, listener net.Listener, port int, readyChan, stopChan chan struct{},
+	g genericclioptions.IOStreams, onChange func([]ServiceEndpoint),
+) error {
+	defer listener.Close()
+	svc, err := c.GetService(service)
+	if err != nil {
+		return err
+	}
+	localPort := 0
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		localPort = addr.Port
+	}
+	f := &serviceForwarder{
+		client:    c,
+		service:   service,
+		localPort: localPort,
+		onChange:  onChange,
+		errOut:    g.ErrOut,
+		conns:     map[string]httpstream.Connection{},
+	}
+	found := false
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) == port && p.Protocol != corev1.ProtocolUDP {
+			f.port = p
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("Service %s has no tcp port %d", service, port))
+	}
+
+	defer f.closeAll()
+
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- f.watchEndpoints(stopChan)
+	}()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.handleConnection(conn)
+		}
+	}()
+
+	if g.Out != nil {
+		_, _ = fmt.Fprintf(g.Out, "Forwarding from %s -> svc/%s:%d\n", listener.Addr(), service, port)
+	}
+	if readyChan != nil {
+		close(readyChan)
+	}
+	select {
+	case <-stopChan:
+		return nil
+	case err = <-watchErr:
+		return err
+	}
+}
+
+// watchEndpoints keeps ready endpoints up to date until stopChan closed or the service deleted
+func (f *serviceForwarder) watchEndpoints(stopChan chan struct{}) error {
+	api := f.client.ClientSet.CoreV1().Endpoints(f.client.namespace)
+	for {
+		endpoints, err := api.Get(f.client.ctx, f.service, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "")
+		}
+		if err == nil {
+			f.update(endpoints)
+		}
+
+		w, err := api.Watch(
+			f.client.ctx, metav1.ListOptions{
+				FieldSelector: fields.OneTermEqualSelector("metadata.name", f.service).String(),
+			},
+		)
+		if err != nil {
+			select {
+			case <-stopChan:
+				return nil
+			case <-time.After(5 * time.Second):
+				continue
+			}
+		}
+	events:
+		for {
+			select {
+			case <-stopChan:
+				w.Stop()
+				return nil
+			case e, ok := <-w.ResultChan():
+				if !ok {
+					break events
+				}
+				if endpoints, ok := e.Object.(*corev1.Endpoints); ok {
+					if e.Type == "DELETED" {
+						endpoints = &corev1.Endpoints{}
+					}
+					f.update(endpoints)
+				}
+			}
+		}
+	}
+}
+
+func (f *serviceForwarder) update(endpoints *corev1.Endpoints) {
+	ready := make([]ServiceEndpoint, 0)
+	for _, subset := range endpoints.Subsets {
+		port := 0
+		for _, p := range subset.Ports {
+			if p.Name == f.port.Name || len(subset.Ports) == 1 {
+				port = int(p.Port)
+				break
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		for _, address := range subset.Addresses {
+			if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+				ready = append(ready, ServiceEndpoint{Pod: address.TargetRef.Name, Port: port})
+			}
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Pod < ready[j].Pod })
+
+	f.lock.Lock()
+	if equalEndpoints(f.endpoints, ready) {
+		f.lock.Unlock()
+		return
+	}
+	f.endpoints = ready
+	pods := map[string]bool{}
+	for _, e := range ready {
+		pods[e.Pod] = true
+	}
+	for pod, conn := range f.conns {
+		if !pods[pod] {
+			_ = conn.Close()
+			delete(f.conns, pod)
+		}
+	}
+	f.lock.Unlock()
+
+	log.Infof("Endpoints of svc/%s: %v", f.service, ready)
+	if f.onChange != nil {
+		f.onChange(ready)
+	}
+}
+
+func equalEndpoints(a, b []ServiceEndpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pick returns the next endpoint by round robin, with its spdy connection
+func (f *serviceForwarder) pick() (ServiceEndpoint, httpstream.Connection, int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.endpoints) == 0 {
+		return ServiceEndpoint{}, nil, 0, errors.New(fmt.Sprintf("No ready endpoints of svc/%s", f.service))
+	}
+	e := f.endpoints[f.next%len(f.endpoints)]
+	f.next++
+	f.requestID++
+	if conn, ok := f.conns[e.Pod]; ok {
+		return e, conn, f.requestID, nil
+	}
+	conn, err := f.dial(e.Pod)
+	if err != nil {
+		return e, nil, 0, err
+	}
+	f.conns[e.Pod] = conn
+	return e, conn, f.requestID, nil
+}
+
+func (f *serviceForwarder) dial(pod string) (httpstream.Connection, error) {
+	url := f.client.ClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(f.client.namespace).
+		Name(pod).
+		SubResource("portforward").URL()
+	transport, upgrader, err := spdy.RoundTripperFor(f.client.restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	return conn, errors.Wrap(err, "")
+}
+
+// drop closes the connection of pod if it is broken, the pod is tried again by next connection
+func (f *serviceForwarder) drop(pod string, conn httpstream.Connection) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.conns[pod] == conn {
+		delete(f.conns, pod)
+	}
+	_ = conn.Close()
+}
+
+func (f *serviceForwarder) closeAll() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for pod, conn := range f.conns {
+		_ = conn.Close()
+		delete(f.conns, pod)
+	}
+}
+
+func (f *serviceForwarder) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	counter := pfstats.Lookup(f.localPort, _const.PortForwardTCP)
+	counter.Opened()
+	defer counter.Closed()
+	conn = counter.Wrap(conn)
+
+	f.lock.Lock()
+	attempts := len(f.endpoints)
+	f.lock.Unlock()
+	if attempts == 0 {
+		attempts = 1
+	}
+
+	// fail over to the next endpoint if streams can not be created
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		e, streamConn, requestID, err := f.pick()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		errorStream, dataStream, err := createForwardStreams(streamConn, e.Port, requestID)
+		if err != nil {
+			f.drop(e.Pod, streamConn)
+			lastErr = errors.Wrap(err, fmt.Sprintf("Failed to forward to %s", e))
+			continue
+		}
+		if !copyForwardStreams(conn, errorStream, dataStream, f.errOut) {
+			counter.Error()
+		}
+		return
+	}
+	counter.Error()
+	if lastErr != nil && f.errOut != nil {
+		_, _ = fmt.Fprintln(f.errOut, lastErr.Error())
+	}
+}
+
+func createForwardStreams(conn httpstream.Connection, port, requestID int) (httpstream.Stream, httpstream.Stream, error) {
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	// we're not writing to this stream
+	_ = errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.RemoveStreams(errorStream)
+		return nil, nil, err
+	}
+	return errorStream, dataStream, nil
+}
+
+// copyForwardStreams returns false if the remote port reports an error
+func copyForwardStreams(conn net.Conn, errorStream, dataStream httpstream.Stream, errOut io.Writer) bool {
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		if err == nil && len(message) > 0 {
+			err = errors.New(string(message))
+		}
+		errorChan <- err
+	}()
+
+	remoteDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(conn, dataStream)
+		close(remoteDone)
+	}()
+	localDone := make(chan struct{})
+	go func() {
+		defer dataStream.Close()
+		_, _ = io.Copy(dataStream, conn)
+		close(localDone)
+	}()
+
+	select {
+	case <-remoteDone:
+	case <-localDone:
+		<-remoteDone
+	}
+	if err := <-errorChan; err != nil {
+		if errOut != nil {
+			_, _ = fmt.Fprintln(errOut, err.Error())
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+)
+
+type fakeStreamConn struct {
+	httpstream.Connection
+	closed bool
+}
+
+func (c *fakeStreamConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func podAddress(pod string) corev1.EndpointAddress {
+	return corev1.EndpointAddress{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: pod}}
+}
+
+func TestEqualEndpoints(t *testing.T) {
+	a := []ServiceEndpoint{{Pod: "a", Port: 80}, {Pod: "b", Port: 80}}
+	if !equalEndpoints(a, []ServiceEndpoint{{Pod: "a", Port: 80}, {Pod: "b", Port: 80}}) {
+		t.Fatal("same endpoints should be equal")
+	}
+	if equalEndpoints(a, []ServiceEndpoint{{Pod: "a", Port: 80}}) {
+		t.Fatal("endpoints of different length should not be equal")
+	}
+	if equalEndpoints(a, []ServiceEndpoint{{Pod: "a", Port: 80}, {Pod: "b", Port: 81}}) {
+		t.Fatal("endpoints of different port should not be equal")
+	}
+	if !equalEndpoints(nil, []ServiceEndpoint{}) {
+		t.Fatal("nil and empty endpoints should be equal")
+	}
+}
+
+func TestServiceForwarderUpdate(t *testing.T) {
+	var changes [][]ServiceEndpoint
+	stale := &fakeStreamConn{}
+	kept := &fakeStreamConn{}
+	f := &serviceForwarder{
+		service:  "svc",
+		port:     corev1.ServicePort{Name: "http", Port: 80},
+		conns:    map[string]httpstream.Connection{"gone": stale, "b": kept},
+		onChange: func(e []ServiceEndpoint) { changes = append(changes, e) },
+	}
+	endpoints := &corev1.Endpoints{
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses:         []corev1.EndpointAddress{podAddress("b"), podAddress("a")},
+				NotReadyAddresses: []corev1.EndpointAddress{podAddress("c")},
+				Ports:             []corev1.EndpointPort{{Name: "grpc", Port: 9090}, {Name: "http", Port: 8080}},
+			},
+			// subset without the named port is skipped
+			{
+				Addresses: []corev1.EndpointAddress{podAddress("d")},
+				Ports:     []corev1.EndpointPort{{Name: "grpc", Port: 9090}, {Name: "metrics", Port: 9100}},
+			},
+			// address not backed by a pod is skipped
+			{
+				Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: 8080}},
+			},
+		},
+	}
+	f.update(endpoints)
+
+	expected := []ServiceEndpoint{{Pod: "a", Port: 8080}, {Pod: "b", Port: 8080}}
+	if !equalEndpoints(f.endpoints, expected) {
+		t.Fatalf("expected endpoints %v, got %v", expected, f.endpoints)
+	}
+	if !stale.closed || f.conns["gone"] != nil {
+		t.Fatal("connection of pod not ready should be closed")
+	}
+	if kept.closed || f.conns["b"] != kept {
+		t.Fatal("connection of ready pod should be kept")
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+
+	f.update(endpoints)
+	if len(changes) != 1 {
+		t.Fatal("onChange should not be called if endpoints are not changed")
+	}
+
+	f.update(&corev1.Endpoints{})
+	if len(f.endpoints) != 0 || len(changes) != 2 {
+		t.Fatal("endpoints should be cleared if the service has none")
+	}
+}
+
+func TestServiceForwarderPick(t *testing.T) {
+	f := &serviceForwarder{service: "svc", conns: map[string]httpstream.Connection{}}
+	if _, _, _, err := f.pick(); err == nil {
+		t.Fatal("pick should fail without ready endpoints")
+	}
+
+	connA, connB := &fakeStreamConn{}, &fakeStreamConn{}
+	f.endpoints = []ServiceEndpoint{{Pod: "a", Port: 80}, {Pod: "b", Port: 80}}
+	f.conns = map[string]httpstream.Connection{"a": connA, "b": connB}
+
+	pods := make([]string, 0)
+	lastID := 0
+	for i := 0; i < 4; i++ {
+		e, conn, id, err := f.pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn != f.conns[e.Pod] {
+			t.Fatalf("connection of %s should be reused", e.Pod)
+		}
+		if id <= lastID {
+			t.Fatalf("request id should increase, got %d after %d", id, lastID)
+		}
+		lastID = id
+		pods = append(pods, e.Pod)
+	}
+	if pods[0] != "a" || pods[1] != "b" || pods[2] != "a" || pods[3] != "b" {
+		t.Fatalf("endpoints should be picked by round robin, got %v", pods)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"nocalhost/internal/nhctl/udpforward"
+)
+
+// ForwardUDPByPod forwards udp localPort to remotePort of pod, datagrams are framed and tunnelled by
+// the exec api to a relay installed in the first container of pod, it blocks until stopChan closed
+// or the tunnel broken
+func (c *ClientGoUtils) ForwardUDPByPod(pod string, localPort, remotePort int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams) error {
+	p, err := c.GetPod(pod)
+	if err != nil {
+		return err
+	}
+	container := p.Spec.Containers[0].Name
+	if name := p.Annotations["kubectl.kubernetes.io/default-container"]; name != "" {
+		container = name
+	}
+	exec := func(cmd []string, stdin io.Reader, stdout io.Writer) error {
+		return c.ExecWithStream(pod, container, cmd, stdin, stdout, g.ErrOut)
+	}
+	if err = udpforward.EnsureRelay(exec); err != nil {
+		return err
+	}
+
+	conn, err := net.ListenPacket("udp", fmt.Sprintf("0.0.0.0:%d", localPort))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if g.Out != nil {
+		_, _ = fmt.Fprintf(g.Out, "Forwarding from 0.0.0.0:%d -> %d/udp\n", localPort, remotePort)
+	}
+	if readyChan != nil {
+		close(readyChan)
+	}
+	err = udpforward.Forward(ctx, conn, udpforward.Dial(exec, remotePort))
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = errors.New(fmt.Sprintf("udp relay of port %d exited", remotePort))
+	}
+	return errors.Wrap(err, "")
+}